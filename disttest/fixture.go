@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -18,9 +19,11 @@ import (
 
 	"distributed_build/pkg/api"
 	"distributed_build/pkg/artifact"
+	"distributed_build/pkg/auth"
 	"distributed_build/pkg/client"
 	"distributed_build/pkg/dist"
 	"distributed_build/pkg/filecache"
+	"distributed_build/pkg/mtls"
 	"distributed_build/pkg/worker"
 
 	"gitlab.com/slon/shad-go/tools/testtool"
@@ -40,6 +43,26 @@ type env struct {
 	WorkerCache []*artifact.Cache
 
 	HTTP *http.Server
+
+	workerCancel []context.CancelFunc
+	workerKilled []*atomic.Bool
+}
+
+// KillWorker останавливает i-го воркера посреди теста: его цикл heartbeat-ов завершается,
+// а HTTP запросы к нему получают 503.
+func (e *env) KillWorker(i int) {
+	e.workerKilled[i].Store(true)
+	e.workerCancel[i]()
+}
+
+func killable(killed *atomic.Bool, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if killed.Load() {
+			http.Error(w, "worker is killed", http.StatusServiceUnavailable)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
 }
 
 const (
@@ -48,6 +71,9 @@ const (
 
 type Config struct {
 	WorkerCount int
+
+	// TLS запускает все компоненты с mTLS. Сертификаты выпускает локальный CA.
+	TLS bool
 }
 
 func newEnv(t *testing.T, config *Config) (e *env) {
@@ -104,7 +130,15 @@ func newEnv(t *testing.T, config *Config) (e *env) {
 	port, err := testtool.GetFreePort()
 	require.NoError(t, err)
 	addr := "127.0.0.1:" + port
-	coordinatorEndpoint := "http://" + addr + "/coordinator"
+
+	scheme := "http://"
+	var ca *mtls.CA
+	if config.TLS {
+		scheme = "https://"
+		ca, err = mtls.NewCA()
+		require.NoError(t, err)
+	}
+	coordinatorEndpoint := scheme + addr + "/coordinator"
 
 	var cancelRootContext func()
 	env.Ctx, cancelRootContext = context.WithCancel(context.Background())
@@ -115,6 +149,13 @@ func newEnv(t *testing.T, config *Config) (e *env) {
 		coordinatorEndpoint,
 		filepath.Join(absCWD, "testdata", t.Name()))
 
+	if ca != nil {
+		var clientTLS *mtls.Config
+		clientTLS, err = ca.Issue(auth.Identity{Subject: "client", Role: auth.RoleClient})
+		require.NoError(t, err)
+		env.Client.SetTLS(clientTLS)
+	}
+
 	coordinatorCache, err := filecache.New(filepath.Join(env.RootDir, "coordinator", "filecache"))
 	require.NoError(t, err)
 
@@ -140,7 +181,7 @@ func newEnv(t *testing.T, config *Config) (e *env) {
 		require.NoError(t, err)
 
 		workerPrefix := fmt.Sprintf("/worker/%d", i)
-		workerID := api.WorkerID("http://" + addr + workerPrefix)
+		workerID := api.WorkerID(scheme + addr + workerPrefix)
 
 		w := worker.New(
 			workerID,
@@ -150,10 +191,20 @@ func newEnv(t *testing.T, config *Config) (e *env) {
 			artifacts,
 		)
 
+		if ca != nil {
+			var workerTLS *mtls.Config
+			workerTLS, err = ca.Issue(auth.Identity{Subject: workerID.String(), Role: auth.RoleWorker})
+			require.NoError(t, err)
+			w.SetTLS(workerTLS)
+		}
+
 		env.Workers = append(env.Workers, w)
 		env.WorkerCache = append(env.WorkerCache, artifacts)
 
-		router.Handle(workerPrefix+"/", http.StripPrefix(workerPrefix, w))
+		killed := &atomic.Bool{}
+		env.workerKilled = append(env.workerKilled, killed)
+
+		router.Handle(workerPrefix+"/", killable(killed, http.StripPrefix(workerPrefix, w)))
 	}
 
 	env.HTTP = &http.Server{
@@ -161,11 +212,23 @@ func newEnv(t *testing.T, config *Config) (e *env) {
 		Handler: router,
 	}
 
+	if ca != nil {
+		var serverTLS *mtls.Config
+		serverTLS, err = ca.Issue(auth.Identity{Subject: "coordinator", Role: auth.RoleCoordinator}, "127.0.0.1")
+		require.NoError(t, err)
+		env.HTTP.TLSConfig = serverTLS.ServerTLS()
+	}
+
 	lsn, err := net.Listen("tcp", env.HTTP.Addr)
 	require.NoError(t, err)
 
 	go func() {
-		err := env.HTTP.Serve(lsn)
+		var err error
+		if env.HTTP.TLSConfig != nil {
+			err = env.HTTP.ServeTLS(lsn, "", "")
+		} else {
+			err = env.HTTP.Serve(lsn)
+		}
 		if err != http.ErrServerClosed {
 			env.Logger.Fatal("http server stopped", zap.Error(err))
 		}
@@ -177,8 +240,11 @@ func newEnv(t *testing.T, config *Config) (e *env) {
 	})
 
 	for _, w := range env.Workers {
+		workerCtx, cancel := context.WithCancel(env.Ctx)
+		env.workerCancel = append(env.workerCancel, cancel)
+
 		go func(w *worker.Worker) {
-			err := w.Run(env.Ctx)
+			err := w.Run(workerCtx)
 			if errors.Is(err, context.Canceled) {
 				return
 			}
@@ -36,6 +36,16 @@ func TestSingleCommand(t *testing.T) {
 	assert.Equal(t, &JobResult{Stdout: "OK\n", Code: new(int)}, recorder.Jobs[build.ID{'a'}])
 }
 
+func TestSingleCommandTLS(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 1, TLS: true})
+
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, echoGraph, recorder))
+
+	assert.Len(t, recorder.Jobs, 1)
+	assert.Equal(t, &JobResult{Stdout: "OK\n", Code: new(int)}, recorder.Jobs[build.ID{'a'}])
+}
+
 func TestJobCaching(t *testing.T) {
 	env := newEnv(t, singleWorkerConfig)
 
@@ -5,6 +5,7 @@ import (
 	"os"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -62,3 +63,30 @@ func TestArtifactTransferBetweenWorkers(t *testing.T) {
 		defer unlock()
 	}
 }
+
+func TestWorkerLoss(t *testing.T) {
+	env := newEnv(t, threeWorkerConfig)
+
+	var graph build.Graph
+	for i := 0; i < 6; i++ {
+		graph.Jobs = append(graph.Jobs, build.Job{
+			ID:   build.ID{'a', byte(i)},
+			Name: "sleep",
+			Cmds: []build.Cmd{
+				{Exec: []string{"sleep", "1"}},
+				{Exec: []string{"echo", "OK"}},
+			},
+		})
+	}
+
+	// Убиваем воркера, пока на нём бегут джобы. WorkerTimeout координатора - 1s.
+	time.AfterFunc(500*time.Millisecond, func() { env.KillWorker(0) })
+
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, graph, recorder))
+
+	require.Len(t, recorder.Jobs, len(graph.Jobs))
+	for _, job := range graph.Jobs {
+		assert.Equal(t, &JobResult{Stdout: "OK\n", Code: new(int)}, recorder.Jobs[job.ID])
+	}
+}
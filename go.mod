@@ -3,19 +3,23 @@ module distributed_build
 go 1.22.2
 
 require (
+	connectrpc.com/connect v1.18.1
 	github.com/golang/mock v1.6.0
 	github.com/jonboulle/clockwork v0.5.0
 	github.com/stretchr/testify v1.10.0
 	gitlab.com/slon/shad-go v0.0.0-20231003165454-50b27acb6315
 	go.uber.org/goleak v1.3.0
 	go.uber.org/zap v1.27.0
+	golang.org/x/net v0.33.0
 	golang.org/x/sync v0.10.0
 	golang.org/x/sys v0.28.0
+	google.golang.org/protobuf v1.36.6
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	go.uber.org/multierr v1.10.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrWorkerNotFound возвращается админским API, если координатор ничего не знает о воркере.
+var ErrWorkerNotFound = errors.New("worker not found")
+
+// WorkerInfo описывает состояние воркера на координаторе.
+type WorkerInfo struct {
+	ID WorkerID `json:"id"`
+
+	// Alive - воркер присылает heartbeat-ы.
+	Alive bool `json:"alive"`
+
+	// RecentJobs - число последних завершённых на воркере джобов, по которым считается доля
+	// инфраструктурных ошибок, RecentInfraFailures - число таких ошибок среди них.
+	RecentJobs          int `json:"recent_jobs"`
+	RecentInfraFailures int `json:"recent_infra_failures"`
+
+	// Draining - воркер выводится из кластера и не берёт новые джобы.
+	Draining bool `json:"draining,omitempty"`
+
+	// Quarantine задан, если координатор не назначает воркеру новые джобы.
+	Quarantine *Quarantine `json:"quarantine,omitempty"`
+}
+
+// Quarantine описывает карантин воркера.
+type Quarantine struct {
+	Reason string    `json:"reason"`
+	Since  time.Time `json:"since"`
+
+	// Manual - карантин включён через админский API и снимается только через него.
+	// Автоматический карантин снимается сам в момент Until.
+	Manual bool      `json:"manual"`
+	Until  time.Time `json:"until,omitempty"`
+}
+
+type QuarantineRequest struct {
+	WorkerID WorkerID `json:"worker_id"`
+	Reason   string   `json:"reason,omitempty"`
+
+	// Release снимает карантин с воркера, вместо того чтобы его включить.
+	Release bool `json:"release,omitempty"`
+}
+
+// AdminService - админский API координатора для дежурных.
+type AdminService interface {
+	// ListWorkers возвращает все воркеры, известные координатору.
+	ListWorkers(ctx context.Context) ([]WorkerInfo, error)
+
+	// QuarantineWorker включает или снимает карантин воркера. Снять карантин с воркера, который
+	// не в карантине, нельзя: возвращается ErrWorkerNotFound.
+	QuarantineWorker(ctx context.Context, req *QuarantineRequest) error
+}
@@ -0,0 +1,105 @@
+//go:build !solution
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"distributed_build/pkg/auth"
+)
+
+// AdminClient ходит в админский API координатора.
+type AdminClient struct {
+	logger   *zap.Logger
+	endpoint string
+	client   *http.Client
+
+	credentials auth.Credentials
+}
+
+// NewAdminClient создаёт клиента админского API. Префикс транспорта в endpoint игнорируется:
+// админский API доступен только в JSON.
+func NewAdminClient(l *zap.Logger, endpoint string) *AdminClient {
+	return &AdminClient{logger: l, endpoint: HTTPEndpoint(endpoint), client: http.DefaultClient}
+}
+
+// SetHTTPClient задаёт HTTP клиент, например с настройками mTLS. Вызывается до первого запроса.
+func (c *AdminClient) SetHTTPClient(client *http.Client) {
+	c.client = client
+}
+
+// SetCredentials задаёт токен, который клиент посылает с каждым запросом. Вызывается до первого запроса.
+func (c *AdminClient) SetCredentials(creds auth.Credentials) {
+	c.credentials = creds
+}
+
+func (c *AdminClient) ListWorkers(ctx context.Context) ([]WorkerInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+"/admin/workers", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var workers []WorkerInfo
+	if err := json.NewDecoder(resp.Body).Decode(&workers); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return workers, nil
+}
+
+func (c *AdminClient) QuarantineWorker(ctx context.Context, request *QuarantineRequest) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/admin/quarantine", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// do выполняет запрос и превращает ответ с ошибкой в error. ErrWorkerNotFound сохраняется.
+func (c *AdminClient) do(req *http.Request) (*http.Response, error) {
+	if err := auth.Authorize(req, c.credentials); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+
+		errorData, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read error response: %w", err)
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%w: %s", ErrWorkerNotFound, string(errorData))
+		}
+		return nil, fmt.Errorf("service error: %s", string(errorData))
+	}
+	return resp, nil
+}
@@ -0,0 +1,102 @@
+//go:build !solution
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"distributed_build/pkg/auth"
+)
+
+// AdminHandler отдаёт админский API координатора. В отличие от BuildHandler, он доступен только
+// в JSON поверх HTTP.
+type AdminHandler struct {
+	logger  *zap.Logger
+	service AdminService
+	auth    auth.Authenticator
+}
+
+func NewAdminHandler(l *zap.Logger, s AdminService) *AdminHandler {
+	return &AdminHandler{logger: l, service: s}
+}
+
+// RequireAuth включает проверку токенов: все вызовы разрешены только auth.RoleAdmin.
+// Вызывается до Register.
+func (h *AdminHandler) RequireAuth(a auth.Authenticator) {
+	h.auth = a
+}
+
+func (h *AdminHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/admin/workers", func(w http.ResponseWriter, r *http.Request) {
+		r, ok := auth.Check(h.logger, h.auth, w, r, auth.RoleAdmin)
+		if !ok {
+			return
+		}
+
+		workers, err := h.service.ListWorkers(r.Context())
+		if err != nil {
+			errorMessage := "error listing workers " + err.Error()
+			h.logger.Error(errorMessage)
+			http.Error(w, errorMessage, http.StatusInternalServerError)
+			return
+		}
+
+		respData, err := json.Marshal(workers)
+		if err != nil {
+			errorMessage := "error generating response " + err.Error()
+			h.logger.Error(errorMessage)
+			http.Error(w, errorMessage, http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if _, err = w.Write(respData); err != nil {
+			h.logger.Error("unable to write response", zap.Error(err))
+		}
+	})
+	mux.HandleFunc("/admin/quarantine", func(w http.ResponseWriter, r *http.Request) {
+		r, ok := auth.Check(h.logger, h.auth, w, r, auth.RoleAdmin)
+		if !ok {
+			return
+		}
+
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			errorMessage := "unable to read request body " + err.Error()
+			h.logger.Error(errorMessage)
+			http.Error(w, errorMessage, http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		var request QuarantineRequest
+		if err = json.Unmarshal(data, &request); err != nil {
+			errorMessage := "invalid request format " + err.Error()
+			h.logger.Error(errorMessage)
+			http.Error(w, errorMessage, http.StatusBadRequest)
+			return
+		}
+
+		if err = h.service.QuarantineWorker(r.Context(), &request); err != nil {
+			errorMessage := "service error: unable to quarantine worker " + err.Error()
+			h.logger.Error(errorMessage)
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrWorkerNotFound) {
+				status = http.StatusNotFound
+			}
+			http.Error(w, errorMessage, status)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
@@ -0,0 +1,85 @@
+package api_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"distributed_build/pkg/api"
+	mock "distributed_build/pkg/api/mock"
+	"distributed_build/pkg/auth"
+)
+
+//go:generate mockgen -package mock -destination mock/admin.go . AdminService
+
+func newAdminEnv(t *testing.T, a auth.Authenticator) (*mock.MockAdminService, *api.AdminClient) {
+	service := mock.NewMockAdminService(gomock.NewController(t))
+	log := zaptest.NewLogger(t)
+
+	handler := api.NewAdminHandler(log, service)
+	if a != nil {
+		handler.RequireAuth(a)
+	}
+	mux := http.NewServeMux()
+	handler.Register(mux)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return service, api.NewAdminClient(log, "connect+"+server.URL)
+}
+
+func TestAdminWorkers(t *testing.T) {
+	service, client := newAdminEnv(t, nil)
+	ctx := context.Background()
+
+	since := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	workers := []api.WorkerInfo{
+		{ID: "worker0", Alive: true, RecentJobs: 10, RecentInfraFailures: 5, Quarantine: &api.Quarantine{
+			Reason: "5 of last 10 jobs failed with infrastructure errors",
+			Since:  since,
+			Until:  since.Add(time.Minute),
+		}},
+		{ID: "worker1", Alive: true},
+	}
+	quarantine := &api.QuarantineRequest{WorkerID: "worker1", Reason: "broken toolchain"}
+	release := &api.QuarantineRequest{WorkerID: "worker2", Release: true}
+
+	service.EXPECT().ListWorkers(gomock.Any()).Return(workers, nil)
+	service.EXPECT().QuarantineWorker(gomock.Any(), gomock.Eq(quarantine)).Return(nil)
+	service.EXPECT().QuarantineWorker(gomock.Any(), gomock.Eq(release)).
+		Return(fmt.Errorf("%w: worker2 is not quarantined", api.ErrWorkerNotFound))
+
+	got, err := client.ListWorkers(ctx)
+	require.NoError(t, err)
+	require.Equal(t, workers, got)
+
+	require.NoError(t, client.QuarantineWorker(ctx, quarantine))
+	require.ErrorIs(t, client.QuarantineWorker(ctx, release), api.ErrWorkerNotFound)
+}
+
+func TestAdminAuth(t *testing.T) {
+	tokens := auth.NewStaticTokens()
+	tokens.Add("client-token", auth.Identity{Subject: "alice", Role: auth.RoleClient})
+	tokens.Add("admin-token", auth.Identity{Subject: "bob", Role: auth.RoleAdmin})
+
+	service, client := newAdminEnv(t, tokens)
+	ctx := context.Background()
+
+	service.EXPECT().ListWorkers(gomock.Any()).Return(nil, nil)
+
+	client.SetCredentials(auth.Token("client-token"))
+	_, err := client.ListWorkers(ctx)
+	require.Error(t, err, "clients must not manage workers")
+
+	client.SetCredentials(auth.Token("admin-token"))
+	_, err = client.ListWorkers(ctx)
+	require.NoError(t, err)
+}
@@ -0,0 +1,2179 @@
+// Protobuf schema of the coordinator API.
+//
+// Messages mirror the JSON types from pkg/api one to one. Job, file and build IDs are
+// 20-byte sha1 hashes encoded as bytes. Map keys can not be bytes, so IDs used as map keys
+// are hex-encoded, the same way as in JSON. Regenerate Go code with `go generate ./pkg/api`.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: distbuild/api/v1/api.proto
+
+package apipb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Cmd struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Exec             []string               `protobuf:"bytes,1,rep,name=exec,proto3" json:"exec,omitempty"`
+	Environ          []string               `protobuf:"bytes,2,rep,name=environ,proto3" json:"environ,omitempty"`
+	WorkingDirectory string                 `protobuf:"bytes,3,opt,name=working_directory,json=workingDirectory,proto3" json:"working_directory,omitempty"`
+	CatTemplate      string                 `protobuf:"bytes,4,opt,name=cat_template,json=catTemplate,proto3" json:"cat_template,omitempty"`
+	CatOutput        string                 `protobuf:"bytes,5,opt,name=cat_output,json=catOutput,proto3" json:"cat_output,omitempty"`
+	PassEnv          []string               `protobuf:"bytes,6,rep,name=pass_env,json=passEnv,proto3" json:"pass_env,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *Cmd) Reset() {
+	*x = Cmd{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Cmd) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Cmd) ProtoMessage() {}
+
+func (x *Cmd) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Cmd.ProtoReflect.Descriptor instead.
+func (*Cmd) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Cmd) GetExec() []string {
+	if x != nil {
+		return x.Exec
+	}
+	return nil
+}
+
+func (x *Cmd) GetEnviron() []string {
+	if x != nil {
+		return x.Environ
+	}
+	return nil
+}
+
+func (x *Cmd) GetWorkingDirectory() string {
+	if x != nil {
+		return x.WorkingDirectory
+	}
+	return ""
+}
+
+func (x *Cmd) GetCatTemplate() string {
+	if x != nil {
+		return x.CatTemplate
+	}
+	return ""
+}
+
+func (x *Cmd) GetCatOutput() string {
+	if x != nil {
+		return x.CatOutput
+	}
+	return ""
+}
+
+func (x *Cmd) GetPassEnv() []string {
+	if x != nil {
+		return x.PassEnv
+	}
+	return nil
+}
+
+type Job struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            []byte                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Inputs        []string               `protobuf:"bytes,3,rep,name=inputs,proto3" json:"inputs,omitempty"`
+	Deps          [][]byte               `protobuf:"bytes,4,rep,name=deps,proto3" json:"deps,omitempty"`
+	Cmds          []*Cmd                 `protobuf:"bytes,5,rep,name=cmds,proto3" json:"cmds,omitempty"`
+	Retryable     bool                   `protobuf:"varint,6,opt,name=retryable,proto3" json:"retryable,omitempty"`
+	Resources     *Resources             `protobuf:"bytes,7,opt,name=resources,proto3" json:"resources,omitempty"`
+	Outputs       []string               `protobuf:"bytes,8,rep,name=outputs,proto3" json:"outputs,omitempty"`
+	StrictOutputs bool                   `protobuf:"varint,9,opt,name=strict_outputs,json=strictOutputs,proto3" json:"strict_outputs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Job) Reset() {
+	*x = Job{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Job) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Job) ProtoMessage() {}
+
+func (x *Job) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Job.ProtoReflect.Descriptor instead.
+func (*Job) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Job) GetId() []byte {
+	if x != nil {
+		return x.Id
+	}
+	return nil
+}
+
+func (x *Job) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Job) GetInputs() []string {
+	if x != nil {
+		return x.Inputs
+	}
+	return nil
+}
+
+func (x *Job) GetDeps() [][]byte {
+	if x != nil {
+		return x.Deps
+	}
+	return nil
+}
+
+func (x *Job) GetCmds() []*Cmd {
+	if x != nil {
+		return x.Cmds
+	}
+	return nil
+}
+
+func (x *Job) GetRetryable() bool {
+	if x != nil {
+		return x.Retryable
+	}
+	return false
+}
+
+func (x *Job) GetResources() *Resources {
+	if x != nil {
+		return x.Resources
+	}
+	return nil
+}
+
+func (x *Job) GetOutputs() []string {
+	if x != nil {
+		return x.Outputs
+	}
+	return nil
+}
+
+func (x *Job) GetStrictOutputs() bool {
+	if x != nil {
+		return x.StrictOutputs
+	}
+	return false
+}
+
+type Resources struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cpu           float64                `protobuf:"fixed64,1,opt,name=cpu,proto3" json:"cpu,omitempty"`
+	Memory        int64                  `protobuf:"varint,2,opt,name=memory,proto3" json:"memory,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Resources) Reset() {
+	*x = Resources{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Resources) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Resources) ProtoMessage() {}
+
+func (x *Resources) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Resources.ProtoReflect.Descriptor instead.
+func (*Resources) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Resources) GetCpu() float64 {
+	if x != nil {
+		return x.Cpu
+	}
+	return 0
+}
+
+func (x *Resources) GetMemory() int64 {
+	if x != nil {
+		return x.Memory
+	}
+	return 0
+}
+
+type Graph struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SourceFiles   map[string]string      `protobuf:"bytes,1,rep,name=source_files,json=sourceFiles,proto3" json:"source_files,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Jobs          []*Job                 `protobuf:"bytes,2,rep,name=jobs,proto3" json:"jobs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Graph) Reset() {
+	*x = Graph{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Graph) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Graph) ProtoMessage() {}
+
+func (x *Graph) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Graph.ProtoReflect.Descriptor instead.
+func (*Graph) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Graph) GetSourceFiles() map[string]string {
+	if x != nil {
+		return x.SourceFiles
+	}
+	return nil
+}
+
+func (x *Graph) GetJobs() []*Job {
+	if x != nil {
+		return x.Jobs
+	}
+	return nil
+}
+
+type JobResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            []byte                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Stdout        []byte                 `protobuf:"bytes,2,opt,name=stdout,proto3" json:"stdout,omitempty"`
+	Stderr        []byte                 `protobuf:"bytes,3,opt,name=stderr,proto3" json:"stderr,omitempty"`
+	ExitCode      int32                  `protobuf:"varint,4,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	Error         *string                `protobuf:"bytes,5,opt,name=error,proto3,oneof" json:"error,omitempty"`
+	Failure       string                 `protobuf:"bytes,6,opt,name=failure,proto3" json:"failure,omitempty"`
+	Usage         *ResourceUsage         `protobuf:"bytes,7,opt,name=usage,proto3" json:"usage,omitempty"`
+	Log           *OutputLog             `protobuf:"bytes,8,opt,name=log,proto3" json:"log,omitempty"`
+	Outputs       []*OutputFile          `protobuf:"bytes,9,rep,name=outputs,proto3" json:"outputs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobResult) Reset() {
+	*x = JobResult{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobResult) ProtoMessage() {}
+
+func (x *JobResult) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobResult.ProtoReflect.Descriptor instead.
+func (*JobResult) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *JobResult) GetId() []byte {
+	if x != nil {
+		return x.Id
+	}
+	return nil
+}
+
+func (x *JobResult) GetStdout() []byte {
+	if x != nil {
+		return x.Stdout
+	}
+	return nil
+}
+
+func (x *JobResult) GetStderr() []byte {
+	if x != nil {
+		return x.Stderr
+	}
+	return nil
+}
+
+func (x *JobResult) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
+	}
+	return 0
+}
+
+func (x *JobResult) GetError() string {
+	if x != nil && x.Error != nil {
+		return *x.Error
+	}
+	return ""
+}
+
+func (x *JobResult) GetFailure() string {
+	if x != nil {
+		return x.Failure
+	}
+	return ""
+}
+
+func (x *JobResult) GetUsage() *ResourceUsage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+func (x *JobResult) GetLog() *OutputLog {
+	if x != nil {
+		return x.Log
+	}
+	return nil
+}
+
+func (x *JobResult) GetOutputs() []*OutputFile {
+	if x != nil {
+		return x.Outputs
+	}
+	return nil
+}
+
+type OutputFile struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Size          int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	Digest        string                 `protobuf:"bytes,3,opt,name=digest,proto3" json:"digest,omitempty"`
+	Symlink       string                 `protobuf:"bytes,4,opt,name=symlink,proto3" json:"symlink,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OutputFile) Reset() {
+	*x = OutputFile{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OutputFile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OutputFile) ProtoMessage() {}
+
+func (x *OutputFile) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OutputFile.ProtoReflect.Descriptor instead.
+func (*OutputFile) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *OutputFile) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *OutputFile) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *OutputFile) GetDigest() string {
+	if x != nil {
+		return x.Digest
+	}
+	return ""
+}
+
+func (x *OutputFile) GetSymlink() string {
+	if x != nil {
+		return x.Symlink
+	}
+	return ""
+}
+
+type OutputLog struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	WorkerId      string                 `protobuf:"bytes,1,opt,name=worker_id,json=workerId,proto3" json:"worker_id,omitempty"`
+	ArtifactId    []byte                 `protobuf:"bytes,2,opt,name=artifact_id,json=artifactId,proto3" json:"artifact_id,omitempty"`
+	StdoutSize    int64                  `protobuf:"varint,3,opt,name=stdout_size,json=stdoutSize,proto3" json:"stdout_size,omitempty"`
+	StderrSize    int64                  `protobuf:"varint,4,opt,name=stderr_size,json=stderrSize,proto3" json:"stderr_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OutputLog) Reset() {
+	*x = OutputLog{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OutputLog) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OutputLog) ProtoMessage() {}
+
+func (x *OutputLog) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OutputLog.ProtoReflect.Descriptor instead.
+func (*OutputLog) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *OutputLog) GetWorkerId() string {
+	if x != nil {
+		return x.WorkerId
+	}
+	return ""
+}
+
+func (x *OutputLog) GetArtifactId() []byte {
+	if x != nil {
+		return x.ArtifactId
+	}
+	return nil
+}
+
+func (x *OutputLog) GetStdoutSize() int64 {
+	if x != nil {
+		return x.StdoutSize
+	}
+	return 0
+}
+
+func (x *OutputLog) GetStderrSize() int64 {
+	if x != nil {
+		return x.StderrSize
+	}
+	return 0
+}
+
+type ResourceUsage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PeakMemory    int64                  `protobuf:"varint,1,opt,name=peak_memory,json=peakMemory,proto3" json:"peak_memory,omitempty"`
+	CpuTime       *durationpb.Duration   `protobuf:"bytes,2,opt,name=cpu_time,json=cpuTime,proto3" json:"cpu_time,omitempty"`
+	ReadBytes     int64                  `protobuf:"varint,3,opt,name=read_bytes,json=readBytes,proto3" json:"read_bytes,omitempty"`
+	WriteBytes    int64                  `protobuf:"varint,4,opt,name=write_bytes,json=writeBytes,proto3" json:"write_bytes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResourceUsage) Reset() {
+	*x = ResourceUsage{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResourceUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResourceUsage) ProtoMessage() {}
+
+func (x *ResourceUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResourceUsage.ProtoReflect.Descriptor instead.
+func (*ResourceUsage) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ResourceUsage) GetPeakMemory() int64 {
+	if x != nil {
+		return x.PeakMemory
+	}
+	return 0
+}
+
+func (x *ResourceUsage) GetCpuTime() *durationpb.Duration {
+	if x != nil {
+		return x.CpuTime
+	}
+	return nil
+}
+
+func (x *ResourceUsage) GetReadBytes() int64 {
+	if x != nil {
+		return x.ReadBytes
+	}
+	return 0
+}
+
+func (x *ResourceUsage) GetWriteBytes() int64 {
+	if x != nil {
+		return x.WriteBytes
+	}
+	return 0
+}
+
+type JobOutput struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            []byte                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Stdout        []byte                 `protobuf:"bytes,2,opt,name=stdout,proto3" json:"stdout,omitempty"`
+	Stderr        []byte                 `protobuf:"bytes,3,opt,name=stderr,proto3" json:"stderr,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobOutput) Reset() {
+	*x = JobOutput{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobOutput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobOutput) ProtoMessage() {}
+
+func (x *JobOutput) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobOutput.ProtoReflect.Descriptor instead.
+func (*JobOutput) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *JobOutput) GetId() []byte {
+	if x != nil {
+		return x.Id
+	}
+	return nil
+}
+
+func (x *JobOutput) GetStdout() []byte {
+	if x != nil {
+		return x.Stdout
+	}
+	return nil
+}
+
+func (x *JobOutput) GetStderr() []byte {
+	if x != nil {
+		return x.Stderr
+	}
+	return nil
+}
+
+type JobSpec struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SourceFiles   map[string]string      `protobuf:"bytes,1,rep,name=source_files,json=sourceFiles,proto3" json:"source_files,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Artifacts     map[string]string      `protobuf:"bytes,2,rep,name=artifacts,proto3" json:"artifacts,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Job           *Job                   `protobuf:"bytes,3,opt,name=job,proto3" json:"job,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobSpec) Reset() {
+	*x = JobSpec{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobSpec) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobSpec) ProtoMessage() {}
+
+func (x *JobSpec) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobSpec.ProtoReflect.Descriptor instead.
+func (*JobSpec) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *JobSpec) GetSourceFiles() map[string]string {
+	if x != nil {
+		return x.SourceFiles
+	}
+	return nil
+}
+
+func (x *JobSpec) GetArtifacts() map[string]string {
+	if x != nil {
+		return x.Artifacts
+	}
+	return nil
+}
+
+func (x *JobSpec) GetJob() *Job {
+	if x != nil {
+		return x.Job
+	}
+	return nil
+}
+
+type HeartbeatRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	WorkerId       string                 `protobuf:"bytes,1,opt,name=worker_id,json=workerId,proto3" json:"worker_id,omitempty"`
+	RunningJobs    [][]byte               `protobuf:"bytes,2,rep,name=running_jobs,json=runningJobs,proto3" json:"running_jobs,omitempty"`
+	FreeSlots      int32                  `protobuf:"varint,3,opt,name=free_slots,json=freeSlots,proto3" json:"free_slots,omitempty"`
+	FinishedJobs   []*JobResult           `protobuf:"bytes,4,rep,name=finished_jobs,json=finishedJobs,proto3" json:"finished_jobs,omitempty"`
+	AddedArtifacts [][]byte               `protobuf:"bytes,5,rep,name=added_artifacts,json=addedArtifacts,proto3" json:"added_artifacts,omitempty"`
+	JobOutput      []*JobOutput           `protobuf:"bytes,6,rep,name=job_output,json=jobOutput,proto3" json:"job_output,omitempty"`
+	PollTimeout    *durationpb.Duration   `protobuf:"bytes,7,opt,name=poll_timeout,json=pollTimeout,proto3" json:"poll_timeout,omitempty"`
+	Draining       bool                   `protobuf:"varint,8,opt,name=draining,proto3" json:"draining,omitempty"`
+	Leaving        bool                   `protobuf:"varint,9,opt,name=leaving,proto3" json:"leaving,omitempty"`
+	Beat           uint64                 `protobuf:"varint,10,opt,name=beat,proto3" json:"beat,omitempty"`
+	Received       uint64                 `protobuf:"varint,11,opt,name=received,proto3" json:"received,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *HeartbeatRequest) Reset() {
+	*x = HeartbeatRequest{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeartbeatRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatRequest) ProtoMessage() {}
+
+func (x *HeartbeatRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatRequest.ProtoReflect.Descriptor instead.
+func (*HeartbeatRequest) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *HeartbeatRequest) GetWorkerId() string {
+	if x != nil {
+		return x.WorkerId
+	}
+	return ""
+}
+
+func (x *HeartbeatRequest) GetRunningJobs() [][]byte {
+	if x != nil {
+		return x.RunningJobs
+	}
+	return nil
+}
+
+func (x *HeartbeatRequest) GetFreeSlots() int32 {
+	if x != nil {
+		return x.FreeSlots
+	}
+	return 0
+}
+
+func (x *HeartbeatRequest) GetFinishedJobs() []*JobResult {
+	if x != nil {
+		return x.FinishedJobs
+	}
+	return nil
+}
+
+func (x *HeartbeatRequest) GetAddedArtifacts() [][]byte {
+	if x != nil {
+		return x.AddedArtifacts
+	}
+	return nil
+}
+
+func (x *HeartbeatRequest) GetJobOutput() []*JobOutput {
+	if x != nil {
+		return x.JobOutput
+	}
+	return nil
+}
+
+func (x *HeartbeatRequest) GetPollTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.PollTimeout
+	}
+	return nil
+}
+
+func (x *HeartbeatRequest) GetDraining() bool {
+	if x != nil {
+		return x.Draining
+	}
+	return false
+}
+
+func (x *HeartbeatRequest) GetLeaving() bool {
+	if x != nil {
+		return x.Leaving
+	}
+	return false
+}
+
+func (x *HeartbeatRequest) GetBeat() uint64 {
+	if x != nil {
+		return x.Beat
+	}
+	return 0
+}
+
+func (x *HeartbeatRequest) GetReceived() uint64 {
+	if x != nil {
+		return x.Received
+	}
+	return 0
+}
+
+type HeartbeatResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobsToRun     map[string]*JobSpec    `protobuf:"bytes,1,rep,name=jobs_to_run,json=jobsToRun,proto3" json:"jobs_to_run,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	JobsToCancel  [][]byte               `protobuf:"bytes,2,rep,name=jobs_to_cancel,json=jobsToCancel,proto3" json:"jobs_to_cancel,omitempty"`
+	Drained       bool                   `protobuf:"varint,3,opt,name=drained,proto3" json:"drained,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HeartbeatResponse) Reset() {
+	*x = HeartbeatResponse{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeartbeatResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatResponse) ProtoMessage() {}
+
+func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatResponse.ProtoReflect.Descriptor instead.
+func (*HeartbeatResponse) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *HeartbeatResponse) GetJobsToRun() map[string]*JobSpec {
+	if x != nil {
+		return x.JobsToRun
+	}
+	return nil
+}
+
+func (x *HeartbeatResponse) GetJobsToCancel() [][]byte {
+	if x != nil {
+		return x.JobsToCancel
+	}
+	return nil
+}
+
+func (x *HeartbeatResponse) GetDrained() bool {
+	if x != nil {
+		return x.Drained
+	}
+	return false
+}
+
+type BuildRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Graph         *Graph                 `protobuf:"bytes,1,opt,name=graph,proto3" json:"graph,omitempty"`
+	Priority      int32                  `protobuf:"varint,2,opt,name=priority,proto3" json:"priority,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BuildRequest) Reset() {
+	*x = BuildRequest{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BuildRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BuildRequest) ProtoMessage() {}
+
+func (x *BuildRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BuildRequest.ProtoReflect.Descriptor instead.
+func (*BuildRequest) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *BuildRequest) GetGraph() *Graph {
+	if x != nil {
+		return x.Graph
+	}
+	return nil
+}
+
+func (x *BuildRequest) GetPriority() int32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+type BuildStarted struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            []byte                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	MissingFiles  [][]byte               `protobuf:"bytes,2,rep,name=missing_files,json=missingFiles,proto3" json:"missing_files,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BuildStarted) Reset() {
+	*x = BuildStarted{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BuildStarted) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BuildStarted) ProtoMessage() {}
+
+func (x *BuildStarted) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BuildStarted.ProtoReflect.Descriptor instead.
+func (*BuildStarted) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *BuildStarted) GetId() []byte {
+	if x != nil {
+		return x.Id
+	}
+	return nil
+}
+
+func (x *BuildStarted) GetMissingFiles() [][]byte {
+	if x != nil {
+		return x.MissingFiles
+	}
+	return nil
+}
+
+type BuildFailed struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Error         string                 `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BuildFailed) Reset() {
+	*x = BuildFailed{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BuildFailed) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BuildFailed) ProtoMessage() {}
+
+func (x *BuildFailed) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BuildFailed.ProtoReflect.Descriptor instead.
+func (*BuildFailed) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *BuildFailed) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type BuildFinished struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BuildFinished) Reset() {
+	*x = BuildFinished{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BuildFinished) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BuildFinished) ProtoMessage() {}
+
+func (x *BuildFinished) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BuildFinished.ProtoReflect.Descriptor instead.
+func (*BuildFinished) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{15}
+}
+
+type JobRetry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            []byte                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Attempt       int32                  `protobuf:"varint,2,opt,name=attempt,proto3" json:"attempt,omitempty"`
+	Worker        string                 `protobuf:"bytes,3,opt,name=worker,proto3" json:"worker,omitempty"`
+	Failure       string                 `protobuf:"bytes,4,opt,name=failure,proto3" json:"failure,omitempty"`
+	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobRetry) Reset() {
+	*x = JobRetry{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobRetry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobRetry) ProtoMessage() {}
+
+func (x *JobRetry) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobRetry.ProtoReflect.Descriptor instead.
+func (*JobRetry) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *JobRetry) GetId() []byte {
+	if x != nil {
+		return x.Id
+	}
+	return nil
+}
+
+func (x *JobRetry) GetAttempt() int32 {
+	if x != nil {
+		return x.Attempt
+	}
+	return 0
+}
+
+func (x *JobRetry) GetWorker() string {
+	if x != nil {
+		return x.Worker
+	}
+	return ""
+}
+
+func (x *JobRetry) GetFailure() string {
+	if x != nil {
+		return x.Failure
+	}
+	return ""
+}
+
+func (x *JobRetry) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type StatusUpdate struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Seq           uint64                 `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	JobOutput     *JobOutput             `protobuf:"bytes,2,opt,name=job_output,json=jobOutput,proto3" json:"job_output,omitempty"`
+	JobFinished   *JobResult             `protobuf:"bytes,3,opt,name=job_finished,json=jobFinished,proto3" json:"job_finished,omitempty"`
+	BuildFailed   *BuildFailed           `protobuf:"bytes,4,opt,name=build_failed,json=buildFailed,proto3" json:"build_failed,omitempty"`
+	BuildFinished *BuildFinished         `protobuf:"bytes,5,opt,name=build_finished,json=buildFinished,proto3" json:"build_finished,omitempty"`
+	JobRetried    *JobRetry              `protobuf:"bytes,6,opt,name=job_retried,json=jobRetried,proto3" json:"job_retried,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatusUpdate) Reset() {
+	*x = StatusUpdate{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusUpdate) ProtoMessage() {}
+
+func (x *StatusUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusUpdate.ProtoReflect.Descriptor instead.
+func (*StatusUpdate) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *StatusUpdate) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *StatusUpdate) GetJobOutput() *JobOutput {
+	if x != nil {
+		return x.JobOutput
+	}
+	return nil
+}
+
+func (x *StatusUpdate) GetJobFinished() *JobResult {
+	if x != nil {
+		return x.JobFinished
+	}
+	return nil
+}
+
+func (x *StatusUpdate) GetBuildFailed() *BuildFailed {
+	if x != nil {
+		return x.BuildFailed
+	}
+	return nil
+}
+
+func (x *StatusUpdate) GetBuildFinished() *BuildFinished {
+	if x != nil {
+		return x.BuildFinished
+	}
+	return nil
+}
+
+func (x *StatusUpdate) GetJobRetried() *JobRetry {
+	if x != nil {
+		return x.JobRetried
+	}
+	return nil
+}
+
+// StartBuildResponse is a single message of the StartBuild stream. The first message is
+// always started, all the following ones are updates.
+type StartBuildResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*StartBuildResponse_Started
+	//	*StartBuildResponse_Update
+	Event         isStartBuildResponse_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartBuildResponse) Reset() {
+	*x = StartBuildResponse{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartBuildResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartBuildResponse) ProtoMessage() {}
+
+func (x *StartBuildResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartBuildResponse.ProtoReflect.Descriptor instead.
+func (*StartBuildResponse) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *StartBuildResponse) GetEvent() isStartBuildResponse_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *StartBuildResponse) GetStarted() *BuildStarted {
+	if x != nil {
+		if x, ok := x.Event.(*StartBuildResponse_Started); ok {
+			return x.Started
+		}
+	}
+	return nil
+}
+
+func (x *StartBuildResponse) GetUpdate() *StatusUpdate {
+	if x != nil {
+		if x, ok := x.Event.(*StartBuildResponse_Update); ok {
+			return x.Update
+		}
+	}
+	return nil
+}
+
+type isStartBuildResponse_Event interface {
+	isStartBuildResponse_Event()
+}
+
+type StartBuildResponse_Started struct {
+	Started *BuildStarted `protobuf:"bytes,1,opt,name=started,proto3,oneof"`
+}
+
+type StartBuildResponse_Update struct {
+	Update *StatusUpdate `protobuf:"bytes,2,opt,name=update,proto3,oneof"`
+}
+
+func (*StartBuildResponse_Started) isStartBuildResponse_Event() {}
+
+func (*StartBuildResponse_Update) isStartBuildResponse_Event() {}
+
+type UploadDone struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadDone) Reset() {
+	*x = UploadDone{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadDone) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadDone) ProtoMessage() {}
+
+func (x *UploadDone) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadDone.ProtoReflect.Descriptor instead.
+func (*UploadDone) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{19}
+}
+
+type Cancel struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reason        string                 `protobuf:"bytes,1,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Cancel) Reset() {
+	*x = Cancel{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Cancel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Cancel) ProtoMessage() {}
+
+func (x *Cancel) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Cancel.ProtoReflect.Descriptor instead.
+func (*Cancel) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *Cancel) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type SignalRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BuildId       []byte                 `protobuf:"bytes,1,opt,name=build_id,json=buildId,proto3" json:"build_id,omitempty"`
+	UploadDone    *UploadDone            `protobuf:"bytes,2,opt,name=upload_done,json=uploadDone,proto3" json:"upload_done,omitempty"`
+	Cancel        *Cancel                `protobuf:"bytes,3,opt,name=cancel,proto3" json:"cancel,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SignalRequest) Reset() {
+	*x = SignalRequest{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SignalRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SignalRequest) ProtoMessage() {}
+
+func (x *SignalRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SignalRequest.ProtoReflect.Descriptor instead.
+func (*SignalRequest) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *SignalRequest) GetBuildId() []byte {
+	if x != nil {
+		return x.BuildId
+	}
+	return nil
+}
+
+func (x *SignalRequest) GetUploadDone() *UploadDone {
+	if x != nil {
+		return x.UploadDone
+	}
+	return nil
+}
+
+func (x *SignalRequest) GetCancel() *Cancel {
+	if x != nil {
+		return x.Cancel
+	}
+	return nil
+}
+
+type SignalResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SignalResponse) Reset() {
+	*x = SignalResponse{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SignalResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SignalResponse) ProtoMessage() {}
+
+func (x *SignalResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SignalResponse.ProtoReflect.Descriptor instead.
+func (*SignalResponse) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{22}
+}
+
+type WatchBuildRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BuildId       []byte                 `protobuf:"bytes,1,opt,name=build_id,json=buildId,proto3" json:"build_id,omitempty"`
+	FromSeq       uint64                 `protobuf:"varint,2,opt,name=from_seq,json=fromSeq,proto3" json:"from_seq,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchBuildRequest) Reset() {
+	*x = WatchBuildRequest{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchBuildRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchBuildRequest) ProtoMessage() {}
+
+func (x *WatchBuildRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchBuildRequest.ProtoReflect.Descriptor instead.
+func (*WatchBuildRequest) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *WatchBuildRequest) GetBuildId() []byte {
+	if x != nil {
+		return x.BuildId
+	}
+	return nil
+}
+
+func (x *WatchBuildRequest) GetFromSeq() uint64 {
+	if x != nil {
+		return x.FromSeq
+	}
+	return 0
+}
+
+type JobInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            []byte                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	State         string                 `protobuf:"bytes,3,opt,name=state,proto3" json:"state,omitempty"`
+	Worker        string                 `protobuf:"bytes,4,opt,name=worker,proto3" json:"worker,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobInfo) Reset() {
+	*x = JobInfo{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobInfo) ProtoMessage() {}
+
+func (x *JobInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobInfo.ProtoReflect.Descriptor instead.
+func (*JobInfo) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *JobInfo) GetId() []byte {
+	if x != nil {
+		return x.Id
+	}
+	return nil
+}
+
+func (x *JobInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *JobInfo) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *JobInfo) GetWorker() string {
+	if x != nil {
+		return x.Worker
+	}
+	return ""
+}
+
+type BuildInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            []byte                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	State         string                 `protobuf:"bytes,2,opt,name=state,proto3" json:"state,omitempty"`
+	Submitted     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=submitted,proto3" json:"submitted,omitempty"`
+	JobCounts     map[string]int32       `protobuf:"bytes,4,rep,name=job_counts,json=jobCounts,proto3" json:"job_counts,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	Jobs          []*JobInfo             `protobuf:"bytes,5,rep,name=jobs,proto3" json:"jobs,omitempty"`
+	Tenant        string                 `protobuf:"bytes,6,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BuildInfo) Reset() {
+	*x = BuildInfo{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BuildInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BuildInfo) ProtoMessage() {}
+
+func (x *BuildInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BuildInfo.ProtoReflect.Descriptor instead.
+func (*BuildInfo) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *BuildInfo) GetId() []byte {
+	if x != nil {
+		return x.Id
+	}
+	return nil
+}
+
+func (x *BuildInfo) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *BuildInfo) GetSubmitted() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Submitted
+	}
+	return nil
+}
+
+func (x *BuildInfo) GetJobCounts() map[string]int32 {
+	if x != nil {
+		return x.JobCounts
+	}
+	return nil
+}
+
+func (x *BuildInfo) GetJobs() []*JobInfo {
+	if x != nil {
+		return x.Jobs
+	}
+	return nil
+}
+
+func (x *BuildInfo) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+type ListBuildsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBuildsRequest) Reset() {
+	*x = ListBuildsRequest{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBuildsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBuildsRequest) ProtoMessage() {}
+
+func (x *ListBuildsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListBuildsRequest.ProtoReflect.Descriptor instead.
+func (*ListBuildsRequest) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{26}
+}
+
+type ListBuildsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Builds        []*BuildInfo           `protobuf:"bytes,1,rep,name=builds,proto3" json:"builds,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBuildsResponse) Reset() {
+	*x = ListBuildsResponse{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBuildsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBuildsResponse) ProtoMessage() {}
+
+func (x *ListBuildsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListBuildsResponse.ProtoReflect.Descriptor instead.
+func (*ListBuildsResponse) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *ListBuildsResponse) GetBuilds() []*BuildInfo {
+	if x != nil {
+		return x.Builds
+	}
+	return nil
+}
+
+type GetBuildRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BuildId       []byte                 `protobuf:"bytes,1,opt,name=build_id,json=buildId,proto3" json:"build_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBuildRequest) Reset() {
+	*x = GetBuildRequest{}
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBuildRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBuildRequest) ProtoMessage() {}
+
+func (x *GetBuildRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_distbuild_api_v1_api_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBuildRequest.ProtoReflect.Descriptor instead.
+func (*GetBuildRequest) Descriptor() ([]byte, []int) {
+	return file_distbuild_api_v1_api_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *GetBuildRequest) GetBuildId() []byte {
+	if x != nil {
+		return x.BuildId
+	}
+	return nil
+}
+
+var File_distbuild_api_v1_api_proto protoreflect.FileDescriptor
+
+const file_distbuild_api_v1_api_proto_rawDesc = "" +
+	"\n" +
+	"\x1adistbuild/api/v1/api.proto\x12\x10distbuild.api.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xbd\x01\n" +
+	"\x03Cmd\x12\x12\n" +
+	"\x04exec\x18\x01 \x03(\tR\x04exec\x12\x18\n" +
+	"\aenviron\x18\x02 \x03(\tR\aenviron\x12+\n" +
+	"\x11working_directory\x18\x03 \x01(\tR\x10workingDirectory\x12!\n" +
+	"\fcat_template\x18\x04 \x01(\tR\vcatTemplate\x12\x1d\n" +
+	"\n" +
+	"cat_output\x18\x05 \x01(\tR\tcatOutput\x12\x19\n" +
+	"\bpass_env\x18\x06 \x03(\tR\apassEnv\"\x9a\x02\n" +
+	"\x03Job\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\fR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x16\n" +
+	"\x06inputs\x18\x03 \x03(\tR\x06inputs\x12\x12\n" +
+	"\x04deps\x18\x04 \x03(\fR\x04deps\x12)\n" +
+	"\x04cmds\x18\x05 \x03(\v2\x15.distbuild.api.v1.CmdR\x04cmds\x12\x1c\n" +
+	"\tretryable\x18\x06 \x01(\bR\tretryable\x129\n" +
+	"\tresources\x18\a \x01(\v2\x1b.distbuild.api.v1.ResourcesR\tresources\x12\x18\n" +
+	"\aoutputs\x18\b \x03(\tR\aoutputs\x12%\n" +
+	"\x0estrict_outputs\x18\t \x01(\bR\rstrictOutputs\"5\n" +
+	"\tResources\x12\x10\n" +
+	"\x03cpu\x18\x01 \x01(\x01R\x03cpu\x12\x16\n" +
+	"\x06memory\x18\x02 \x01(\x03R\x06memory\"\xbf\x01\n" +
+	"\x05Graph\x12K\n" +
+	"\fsource_files\x18\x01 \x03(\v2(.distbuild.api.v1.Graph.SourceFilesEntryR\vsourceFiles\x12)\n" +
+	"\x04jobs\x18\x02 \x03(\v2\x15.distbuild.api.v1.JobR\x04jobs\x1a>\n" +
+	"\x10SourceFilesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xc5\x02\n" +
+	"\tJobResult\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\fR\x02id\x12\x16\n" +
+	"\x06stdout\x18\x02 \x01(\fR\x06stdout\x12\x16\n" +
+	"\x06stderr\x18\x03 \x01(\fR\x06stderr\x12\x1b\n" +
+	"\texit_code\x18\x04 \x01(\x05R\bexitCode\x12\x19\n" +
+	"\x05error\x18\x05 \x01(\tH\x00R\x05error\x88\x01\x01\x12\x18\n" +
+	"\afailure\x18\x06 \x01(\tR\afailure\x125\n" +
+	"\x05usage\x18\a \x01(\v2\x1f.distbuild.api.v1.ResourceUsageR\x05usage\x12-\n" +
+	"\x03log\x18\b \x01(\v2\x1b.distbuild.api.v1.OutputLogR\x03log\x126\n" +
+	"\aoutputs\x18\t \x03(\v2\x1c.distbuild.api.v1.OutputFileR\aoutputsB\b\n" +
+	"\x06_error\"f\n" +
+	"\n" +
+	"OutputFile\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\x12\x16\n" +
+	"\x06digest\x18\x03 \x01(\tR\x06digest\x12\x18\n" +
+	"\asymlink\x18\x04 \x01(\tR\asymlink\"\x8b\x01\n" +
+	"\tOutputLog\x12\x1b\n" +
+	"\tworker_id\x18\x01 \x01(\tR\bworkerId\x12\x1f\n" +
+	"\vartifact_id\x18\x02 \x01(\fR\n" +
+	"artifactId\x12\x1f\n" +
+	"\vstdout_size\x18\x03 \x01(\x03R\n" +
+	"stdoutSize\x12\x1f\n" +
+	"\vstderr_size\x18\x04 \x01(\x03R\n" +
+	"stderrSize\"\xa6\x01\n" +
+	"\rResourceUsage\x12\x1f\n" +
+	"\vpeak_memory\x18\x01 \x01(\x03R\n" +
+	"peakMemory\x124\n" +
+	"\bcpu_time\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\acpuTime\x12\x1d\n" +
+	"\n" +
+	"read_bytes\x18\x03 \x01(\x03R\treadBytes\x12\x1f\n" +
+	"\vwrite_bytes\x18\x04 \x01(\x03R\n" +
+	"writeBytes\"K\n" +
+	"\tJobOutput\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\fR\x02id\x12\x16\n" +
+	"\x06stdout\x18\x02 \x01(\fR\x06stdout\x12\x16\n" +
+	"\x06stderr\x18\x03 \x01(\fR\x06stderr\"\xc7\x02\n" +
+	"\aJobSpec\x12M\n" +
+	"\fsource_files\x18\x01 \x03(\v2*.distbuild.api.v1.JobSpec.SourceFilesEntryR\vsourceFiles\x12F\n" +
+	"\tartifacts\x18\x02 \x03(\v2(.distbuild.api.v1.JobSpec.ArtifactsEntryR\tartifacts\x12'\n" +
+	"\x03job\x18\x03 \x01(\v2\x15.distbuild.api.v1.JobR\x03job\x1a>\n" +
+	"\x10SourceFilesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a<\n" +
+	"\x0eArtifactsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xbc\x03\n" +
+	"\x10HeartbeatRequest\x12\x1b\n" +
+	"\tworker_id\x18\x01 \x01(\tR\bworkerId\x12!\n" +
+	"\frunning_jobs\x18\x02 \x03(\fR\vrunningJobs\x12\x1d\n" +
+	"\n" +
+	"free_slots\x18\x03 \x01(\x05R\tfreeSlots\x12@\n" +
+	"\rfinished_jobs\x18\x04 \x03(\v2\x1b.distbuild.api.v1.JobResultR\ffinishedJobs\x12'\n" +
+	"\x0fadded_artifacts\x18\x05 \x03(\fR\x0eaddedArtifacts\x12:\n" +
+	"\n" +
+	"job_output\x18\x06 \x03(\v2\x1b.distbuild.api.v1.JobOutputR\tjobOutput\x12<\n" +
+	"\fpoll_timeout\x18\a \x01(\v2\x19.google.protobuf.DurationR\vpollTimeout\x12\x1a\n" +
+	"\bdraining\x18\b \x01(\bR\bdraining\x12\x18\n" +
+	"\aleaving\x18\t \x01(\bR\aleaving\x12\x12\n" +
+	"\x04beat\x18\n" +
+	" \x01(\x04R\x04beat\x12\x1a\n" +
+	"\breceived\x18\v \x01(\x04R\breceived\"\x80\x02\n" +
+	"\x11HeartbeatResponse\x12R\n" +
+	"\vjobs_to_run\x18\x01 \x03(\v22.distbuild.api.v1.HeartbeatResponse.JobsToRunEntryR\tjobsToRun\x12$\n" +
+	"\x0ejobs_to_cancel\x18\x02 \x03(\fR\fjobsToCancel\x12\x18\n" +
+	"\adrained\x18\x03 \x01(\bR\adrained\x1aW\n" +
+	"\x0eJobsToRunEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12/\n" +
+	"\x05value\x18\x02 \x01(\v2\x19.distbuild.api.v1.JobSpecR\x05value:\x028\x01\"Y\n" +
+	"\fBuildRequest\x12-\n" +
+	"\x05graph\x18\x01 \x01(\v2\x17.distbuild.api.v1.GraphR\x05graph\x12\x1a\n" +
+	"\bpriority\x18\x02 \x01(\x05R\bpriority\"C\n" +
+	"\fBuildStarted\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\fR\x02id\x12#\n" +
+	"\rmissing_files\x18\x02 \x03(\fR\fmissingFiles\"#\n" +
+	"\vBuildFailed\x12\x14\n" +
+	"\x05error\x18\x01 \x01(\tR\x05error\"\x0f\n" +
+	"\rBuildFinished\"|\n" +
+	"\bJobRetry\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\fR\x02id\x12\x18\n" +
+	"\aattempt\x18\x02 \x01(\x05R\aattempt\x12\x16\n" +
+	"\x06worker\x18\x03 \x01(\tR\x06worker\x12\x18\n" +
+	"\afailure\x18\x04 \x01(\tR\afailure\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\"\xe3\x02\n" +
+	"\fStatusUpdate\x12\x10\n" +
+	"\x03seq\x18\x01 \x01(\x04R\x03seq\x12:\n" +
+	"\n" +
+	"job_output\x18\x02 \x01(\v2\x1b.distbuild.api.v1.JobOutputR\tjobOutput\x12>\n" +
+	"\fjob_finished\x18\x03 \x01(\v2\x1b.distbuild.api.v1.JobResultR\vjobFinished\x12@\n" +
+	"\fbuild_failed\x18\x04 \x01(\v2\x1d.distbuild.api.v1.BuildFailedR\vbuildFailed\x12F\n" +
+	"\x0ebuild_finished\x18\x05 \x01(\v2\x1f.distbuild.api.v1.BuildFinishedR\rbuildFinished\x12;\n" +
+	"\vjob_retried\x18\x06 \x01(\v2\x1a.distbuild.api.v1.JobRetryR\n" +
+	"jobRetried\"\x93\x01\n" +
+	"\x12StartBuildResponse\x12:\n" +
+	"\astarted\x18\x01 \x01(\v2\x1e.distbuild.api.v1.BuildStartedH\x00R\astarted\x128\n" +
+	"\x06update\x18\x02 \x01(\v2\x1e.distbuild.api.v1.StatusUpdateH\x00R\x06updateB\a\n" +
+	"\x05event\"\f\n" +
+	"\n" +
+	"UploadDone\" \n" +
+	"\x06Cancel\x12\x16\n" +
+	"\x06reason\x18\x01 \x01(\tR\x06reason\"\x9b\x01\n" +
+	"\rSignalRequest\x12\x19\n" +
+	"\bbuild_id\x18\x01 \x01(\fR\abuildId\x12=\n" +
+	"\vupload_done\x18\x02 \x01(\v2\x1c.distbuild.api.v1.UploadDoneR\n" +
+	"uploadDone\x120\n" +
+	"\x06cancel\x18\x03 \x01(\v2\x18.distbuild.api.v1.CancelR\x06cancel\"\x10\n" +
+	"\x0eSignalResponse\"I\n" +
+	"\x11WatchBuildRequest\x12\x19\n" +
+	"\bbuild_id\x18\x01 \x01(\fR\abuildId\x12\x19\n" +
+	"\bfrom_seq\x18\x02 \x01(\x04R\afromSeq\"[\n" +
+	"\aJobInfo\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\fR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
+	"\x05state\x18\x03 \x01(\tR\x05state\x12\x16\n" +
+	"\x06worker\x18\x04 \x01(\tR\x06worker\"\xbb\x02\n" +
+	"\tBuildInfo\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\fR\x02id\x12\x14\n" +
+	"\x05state\x18\x02 \x01(\tR\x05state\x128\n" +
+	"\tsubmitted\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\tsubmitted\x12I\n" +
+	"\n" +
+	"job_counts\x18\x04 \x03(\v2*.distbuild.api.v1.BuildInfo.JobCountsEntryR\tjobCounts\x12-\n" +
+	"\x04jobs\x18\x05 \x03(\v2\x19.distbuild.api.v1.JobInfoR\x04jobs\x12\x16\n" +
+	"\x06tenant\x18\x06 \x01(\tR\x06tenant\x1a<\n" +
+	"\x0eJobCountsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\"\x13\n" +
+	"\x11ListBuildsRequest\"I\n" +
+	"\x12ListBuildsResponse\x123\n" +
+	"\x06builds\x18\x01 \x03(\v2\x1b.distbuild.api.v1.BuildInfoR\x06builds\",\n" +
+	"\x0fGetBuildRequest\x12\x19\n" +
+	"\bbuild_id\x18\x01 \x01(\fR\abuildId2\xb0\x03\n" +
+	"\fBuildService\x12T\n" +
+	"\n" +
+	"StartBuild\x12\x1e.distbuild.api.v1.BuildRequest\x1a$.distbuild.api.v1.StartBuildResponse0\x01\x12P\n" +
+	"\vSignalBuild\x12\x1f.distbuild.api.v1.SignalRequest\x1a .distbuild.api.v1.SignalResponse\x12S\n" +
+	"\n" +
+	"WatchBuild\x12#.distbuild.api.v1.WatchBuildRequest\x1a\x1e.distbuild.api.v1.StatusUpdate0\x01\x12W\n" +
+	"\n" +
+	"ListBuilds\x12#.distbuild.api.v1.ListBuildsRequest\x1a$.distbuild.api.v1.ListBuildsResponse\x12J\n" +
+	"\bGetBuild\x12!.distbuild.api.v1.GetBuildRequest\x1a\x1b.distbuild.api.v1.BuildInfo2h\n" +
+	"\x10HeartbeatService\x12T\n" +
+	"\tHeartbeat\x12\".distbuild.api.v1.HeartbeatRequest\x1a#.distbuild.api.v1.HeartbeatResponseB!Z\x1fdistributed_build/pkg/api/apipbb\x06proto3"
+
+var (
+	file_distbuild_api_v1_api_proto_rawDescOnce sync.Once
+	file_distbuild_api_v1_api_proto_rawDescData []byte
+)
+
+func file_distbuild_api_v1_api_proto_rawDescGZIP() []byte {
+	file_distbuild_api_v1_api_proto_rawDescOnce.Do(func() {
+		file_distbuild_api_v1_api_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_distbuild_api_v1_api_proto_rawDesc), len(file_distbuild_api_v1_api_proto_rawDesc)))
+	})
+	return file_distbuild_api_v1_api_proto_rawDescData
+}
+
+var file_distbuild_api_v1_api_proto_msgTypes = make([]protoimpl.MessageInfo, 34)
+var file_distbuild_api_v1_api_proto_goTypes = []any{
+	(*Cmd)(nil),                   // 0: distbuild.api.v1.Cmd
+	(*Job)(nil),                   // 1: distbuild.api.v1.Job
+	(*Resources)(nil),             // 2: distbuild.api.v1.Resources
+	(*Graph)(nil),                 // 3: distbuild.api.v1.Graph
+	(*JobResult)(nil),             // 4: distbuild.api.v1.JobResult
+	(*OutputFile)(nil),            // 5: distbuild.api.v1.OutputFile
+	(*OutputLog)(nil),             // 6: distbuild.api.v1.OutputLog
+	(*ResourceUsage)(nil),         // 7: distbuild.api.v1.ResourceUsage
+	(*JobOutput)(nil),             // 8: distbuild.api.v1.JobOutput
+	(*JobSpec)(nil),               // 9: distbuild.api.v1.JobSpec
+	(*HeartbeatRequest)(nil),      // 10: distbuild.api.v1.HeartbeatRequest
+	(*HeartbeatResponse)(nil),     // 11: distbuild.api.v1.HeartbeatResponse
+	(*BuildRequest)(nil),          // 12: distbuild.api.v1.BuildRequest
+	(*BuildStarted)(nil),          // 13: distbuild.api.v1.BuildStarted
+	(*BuildFailed)(nil),           // 14: distbuild.api.v1.BuildFailed
+	(*BuildFinished)(nil),         // 15: distbuild.api.v1.BuildFinished
+	(*JobRetry)(nil),              // 16: distbuild.api.v1.JobRetry
+	(*StatusUpdate)(nil),          // 17: distbuild.api.v1.StatusUpdate
+	(*StartBuildResponse)(nil),    // 18: distbuild.api.v1.StartBuildResponse
+	(*UploadDone)(nil),            // 19: distbuild.api.v1.UploadDone
+	(*Cancel)(nil),                // 20: distbuild.api.v1.Cancel
+	(*SignalRequest)(nil),         // 21: distbuild.api.v1.SignalRequest
+	(*SignalResponse)(nil),        // 22: distbuild.api.v1.SignalResponse
+	(*WatchBuildRequest)(nil),     // 23: distbuild.api.v1.WatchBuildRequest
+	(*JobInfo)(nil),               // 24: distbuild.api.v1.JobInfo
+	(*BuildInfo)(nil),             // 25: distbuild.api.v1.BuildInfo
+	(*ListBuildsRequest)(nil),     // 26: distbuild.api.v1.ListBuildsRequest
+	(*ListBuildsResponse)(nil),    // 27: distbuild.api.v1.ListBuildsResponse
+	(*GetBuildRequest)(nil),       // 28: distbuild.api.v1.GetBuildRequest
+	nil,                           // 29: distbuild.api.v1.Graph.SourceFilesEntry
+	nil,                           // 30: distbuild.api.v1.JobSpec.SourceFilesEntry
+	nil,                           // 31: distbuild.api.v1.JobSpec.ArtifactsEntry
+	nil,                           // 32: distbuild.api.v1.HeartbeatResponse.JobsToRunEntry
+	nil,                           // 33: distbuild.api.v1.BuildInfo.JobCountsEntry
+	(*durationpb.Duration)(nil),   // 34: google.protobuf.Duration
+	(*timestamppb.Timestamp)(nil), // 35: google.protobuf.Timestamp
+}
+var file_distbuild_api_v1_api_proto_depIdxs = []int32{
+	0,  // 0: distbuild.api.v1.Job.cmds:type_name -> distbuild.api.v1.Cmd
+	2,  // 1: distbuild.api.v1.Job.resources:type_name -> distbuild.api.v1.Resources
+	29, // 2: distbuild.api.v1.Graph.source_files:type_name -> distbuild.api.v1.Graph.SourceFilesEntry
+	1,  // 3: distbuild.api.v1.Graph.jobs:type_name -> distbuild.api.v1.Job
+	7,  // 4: distbuild.api.v1.JobResult.usage:type_name -> distbuild.api.v1.ResourceUsage
+	6,  // 5: distbuild.api.v1.JobResult.log:type_name -> distbuild.api.v1.OutputLog
+	5,  // 6: distbuild.api.v1.JobResult.outputs:type_name -> distbuild.api.v1.OutputFile
+	34, // 7: distbuild.api.v1.ResourceUsage.cpu_time:type_name -> google.protobuf.Duration
+	30, // 8: distbuild.api.v1.JobSpec.source_files:type_name -> distbuild.api.v1.JobSpec.SourceFilesEntry
+	31, // 9: distbuild.api.v1.JobSpec.artifacts:type_name -> distbuild.api.v1.JobSpec.ArtifactsEntry
+	1,  // 10: distbuild.api.v1.JobSpec.job:type_name -> distbuild.api.v1.Job
+	4,  // 11: distbuild.api.v1.HeartbeatRequest.finished_jobs:type_name -> distbuild.api.v1.JobResult
+	8,  // 12: distbuild.api.v1.HeartbeatRequest.job_output:type_name -> distbuild.api.v1.JobOutput
+	34, // 13: distbuild.api.v1.HeartbeatRequest.poll_timeout:type_name -> google.protobuf.Duration
+	32, // 14: distbuild.api.v1.HeartbeatResponse.jobs_to_run:type_name -> distbuild.api.v1.HeartbeatResponse.JobsToRunEntry
+	3,  // 15: distbuild.api.v1.BuildRequest.graph:type_name -> distbuild.api.v1.Graph
+	8,  // 16: distbuild.api.v1.StatusUpdate.job_output:type_name -> distbuild.api.v1.JobOutput
+	4,  // 17: distbuild.api.v1.StatusUpdate.job_finished:type_name -> distbuild.api.v1.JobResult
+	14, // 18: distbuild.api.v1.StatusUpdate.build_failed:type_name -> distbuild.api.v1.BuildFailed
+	15, // 19: distbuild.api.v1.StatusUpdate.build_finished:type_name -> distbuild.api.v1.BuildFinished
+	16, // 20: distbuild.api.v1.StatusUpdate.job_retried:type_name -> distbuild.api.v1.JobRetry
+	13, // 21: distbuild.api.v1.StartBuildResponse.started:type_name -> distbuild.api.v1.BuildStarted
+	17, // 22: distbuild.api.v1.StartBuildResponse.update:type_name -> distbuild.api.v1.StatusUpdate
+	19, // 23: distbuild.api.v1.SignalRequest.upload_done:type_name -> distbuild.api.v1.UploadDone
+	20, // 24: distbuild.api.v1.SignalRequest.cancel:type_name -> distbuild.api.v1.Cancel
+	35, // 25: distbuild.api.v1.BuildInfo.submitted:type_name -> google.protobuf.Timestamp
+	33, // 26: distbuild.api.v1.BuildInfo.job_counts:type_name -> distbuild.api.v1.BuildInfo.JobCountsEntry
+	24, // 27: distbuild.api.v1.BuildInfo.jobs:type_name -> distbuild.api.v1.JobInfo
+	25, // 28: distbuild.api.v1.ListBuildsResponse.builds:type_name -> distbuild.api.v1.BuildInfo
+	9,  // 29: distbuild.api.v1.HeartbeatResponse.JobsToRunEntry.value:type_name -> distbuild.api.v1.JobSpec
+	12, // 30: distbuild.api.v1.BuildService.StartBuild:input_type -> distbuild.api.v1.BuildRequest
+	21, // 31: distbuild.api.v1.BuildService.SignalBuild:input_type -> distbuild.api.v1.SignalRequest
+	23, // 32: distbuild.api.v1.BuildService.WatchBuild:input_type -> distbuild.api.v1.WatchBuildRequest
+	26, // 33: distbuild.api.v1.BuildService.ListBuilds:input_type -> distbuild.api.v1.ListBuildsRequest
+	28, // 34: distbuild.api.v1.BuildService.GetBuild:input_type -> distbuild.api.v1.GetBuildRequest
+	10, // 35: distbuild.api.v1.HeartbeatService.Heartbeat:input_type -> distbuild.api.v1.HeartbeatRequest
+	18, // 36: distbuild.api.v1.BuildService.StartBuild:output_type -> distbuild.api.v1.StartBuildResponse
+	22, // 37: distbuild.api.v1.BuildService.SignalBuild:output_type -> distbuild.api.v1.SignalResponse
+	17, // 38: distbuild.api.v1.BuildService.WatchBuild:output_type -> distbuild.api.v1.StatusUpdate
+	27, // 39: distbuild.api.v1.BuildService.ListBuilds:output_type -> distbuild.api.v1.ListBuildsResponse
+	25, // 40: distbuild.api.v1.BuildService.GetBuild:output_type -> distbuild.api.v1.BuildInfo
+	11, // 41: distbuild.api.v1.HeartbeatService.Heartbeat:output_type -> distbuild.api.v1.HeartbeatResponse
+	36, // [36:42] is the sub-list for method output_type
+	30, // [30:36] is the sub-list for method input_type
+	30, // [30:30] is the sub-list for extension type_name
+	30, // [30:30] is the sub-list for extension extendee
+	0,  // [0:30] is the sub-list for field type_name
+}
+
+func init() { file_distbuild_api_v1_api_proto_init() }
+func file_distbuild_api_v1_api_proto_init() {
+	if File_distbuild_api_v1_api_proto != nil {
+		return
+	}
+	file_distbuild_api_v1_api_proto_msgTypes[4].OneofWrappers = []any{}
+	file_distbuild_api_v1_api_proto_msgTypes[18].OneofWrappers = []any{
+		(*StartBuildResponse_Started)(nil),
+		(*StartBuildResponse_Update)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_distbuild_api_v1_api_proto_rawDesc), len(file_distbuild_api_v1_api_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   34,
+			NumExtensions: 0,
+			NumServices:   2,
+		},
+		GoTypes:           file_distbuild_api_v1_api_proto_goTypes,
+		DependencyIndexes: file_distbuild_api_v1_api_proto_depIdxs,
+		MessageInfos:      file_distbuild_api_v1_api_proto_msgTypes,
+	}.Build()
+	File_distbuild_api_v1_api_proto = out.File
+	file_distbuild_api_v1_api_proto_goTypes = nil
+	file_distbuild_api_v1_api_proto_depIdxs = nil
+}
@@ -0,0 +1,302 @@
+// Protobuf schema of the coordinator API.
+//
+// Messages mirror the JSON types from pkg/api one to one. Job, file and build IDs are
+// 20-byte sha1 hashes encoded as bytes. Map keys can not be bytes, so IDs used as map keys
+// are hex-encoded, the same way as in JSON. Regenerate Go code with `go generate ./pkg/api`.
+
+// Code generated by protoc-gen-connect-go. DO NOT EDIT.
+//
+// Source: distbuild/api/v1/api.proto
+
+package apipbconnect
+
+import (
+	connect "connectrpc.com/connect"
+	context "context"
+	apipb "distributed_build/pkg/api/apipb"
+	errors "errors"
+	http "net/http"
+	strings "strings"
+)
+
+// This is a compile-time assertion to ensure that this generated file and the connect package are
+// compatible. If you get a compiler error that this constant is not defined, this code was
+// generated with a version of connect newer than the one compiled into your binary. You can fix the
+// problem by either regenerating this code with an older version of connect or updating the connect
+// version compiled into your binary.
+const _ = connect.IsAtLeastVersion1_13_0
+
+const (
+	// BuildServiceName is the fully-qualified name of the BuildService service.
+	BuildServiceName = "distbuild.api.v1.BuildService"
+	// HeartbeatServiceName is the fully-qualified name of the HeartbeatService service.
+	HeartbeatServiceName = "distbuild.api.v1.HeartbeatService"
+)
+
+// These constants are the fully-qualified names of the RPCs defined in this package. They're
+// exposed at runtime as Spec.Procedure and as the final two segments of the HTTP route.
+//
+// Note that these are different from the fully-qualified method names used by
+// google.golang.org/protobuf/reflect/protoreflect. To convert from these constants to
+// reflection-formatted method names, remove the leading slash and convert the remaining slash to a
+// period.
+const (
+	// BuildServiceStartBuildProcedure is the fully-qualified name of the BuildService's StartBuild RPC.
+	BuildServiceStartBuildProcedure = "/distbuild.api.v1.BuildService/StartBuild"
+	// BuildServiceSignalBuildProcedure is the fully-qualified name of the BuildService's SignalBuild
+	// RPC.
+	BuildServiceSignalBuildProcedure = "/distbuild.api.v1.BuildService/SignalBuild"
+	// BuildServiceWatchBuildProcedure is the fully-qualified name of the BuildService's WatchBuild RPC.
+	BuildServiceWatchBuildProcedure = "/distbuild.api.v1.BuildService/WatchBuild"
+	// BuildServiceListBuildsProcedure is the fully-qualified name of the BuildService's ListBuilds RPC.
+	BuildServiceListBuildsProcedure = "/distbuild.api.v1.BuildService/ListBuilds"
+	// BuildServiceGetBuildProcedure is the fully-qualified name of the BuildService's GetBuild RPC.
+	BuildServiceGetBuildProcedure = "/distbuild.api.v1.BuildService/GetBuild"
+	// HeartbeatServiceHeartbeatProcedure is the fully-qualified name of the HeartbeatService's
+	// Heartbeat RPC.
+	HeartbeatServiceHeartbeatProcedure = "/distbuild.api.v1.HeartbeatService/Heartbeat"
+)
+
+// BuildServiceClient is a client for the distbuild.api.v1.BuildService service.
+type BuildServiceClient interface {
+	StartBuild(context.Context, *connect.Request[apipb.BuildRequest]) (*connect.ServerStreamForClient[apipb.StartBuildResponse], error)
+	SignalBuild(context.Context, *connect.Request[apipb.SignalRequest]) (*connect.Response[apipb.SignalResponse], error)
+	WatchBuild(context.Context, *connect.Request[apipb.WatchBuildRequest]) (*connect.ServerStreamForClient[apipb.StatusUpdate], error)
+	ListBuilds(context.Context, *connect.Request[apipb.ListBuildsRequest]) (*connect.Response[apipb.ListBuildsResponse], error)
+	GetBuild(context.Context, *connect.Request[apipb.GetBuildRequest]) (*connect.Response[apipb.BuildInfo], error)
+}
+
+// NewBuildServiceClient constructs a client for the distbuild.api.v1.BuildService service. By
+// default, it uses the Connect protocol with the binary Protobuf Codec, asks for gzipped responses,
+// and sends uncompressed requests. To use the gRPC or gRPC-Web protocols, supply the
+// connect.WithGRPC() or connect.WithGRPCWeb() options.
+//
+// The URL supplied here should be the base URL for the Connect or gRPC server (for example,
+// http://api.acme.com or https://acme.com/grpc).
+func NewBuildServiceClient(httpClient connect.HTTPClient, baseURL string, opts ...connect.ClientOption) BuildServiceClient {
+	baseURL = strings.TrimRight(baseURL, "/")
+	buildServiceMethods := apipb.File_distbuild_api_v1_api_proto.Services().ByName("BuildService").Methods()
+	return &buildServiceClient{
+		startBuild: connect.NewClient[apipb.BuildRequest, apipb.StartBuildResponse](
+			httpClient,
+			baseURL+BuildServiceStartBuildProcedure,
+			connect.WithSchema(buildServiceMethods.ByName("StartBuild")),
+			connect.WithClientOptions(opts...),
+		),
+		signalBuild: connect.NewClient[apipb.SignalRequest, apipb.SignalResponse](
+			httpClient,
+			baseURL+BuildServiceSignalBuildProcedure,
+			connect.WithSchema(buildServiceMethods.ByName("SignalBuild")),
+			connect.WithClientOptions(opts...),
+		),
+		watchBuild: connect.NewClient[apipb.WatchBuildRequest, apipb.StatusUpdate](
+			httpClient,
+			baseURL+BuildServiceWatchBuildProcedure,
+			connect.WithSchema(buildServiceMethods.ByName("WatchBuild")),
+			connect.WithClientOptions(opts...),
+		),
+		listBuilds: connect.NewClient[apipb.ListBuildsRequest, apipb.ListBuildsResponse](
+			httpClient,
+			baseURL+BuildServiceListBuildsProcedure,
+			connect.WithSchema(buildServiceMethods.ByName("ListBuilds")),
+			connect.WithClientOptions(opts...),
+		),
+		getBuild: connect.NewClient[apipb.GetBuildRequest, apipb.BuildInfo](
+			httpClient,
+			baseURL+BuildServiceGetBuildProcedure,
+			connect.WithSchema(buildServiceMethods.ByName("GetBuild")),
+			connect.WithClientOptions(opts...),
+		),
+	}
+}
+
+// buildServiceClient implements BuildServiceClient.
+type buildServiceClient struct {
+	startBuild  *connect.Client[apipb.BuildRequest, apipb.StartBuildResponse]
+	signalBuild *connect.Client[apipb.SignalRequest, apipb.SignalResponse]
+	watchBuild  *connect.Client[apipb.WatchBuildRequest, apipb.StatusUpdate]
+	listBuilds  *connect.Client[apipb.ListBuildsRequest, apipb.ListBuildsResponse]
+	getBuild    *connect.Client[apipb.GetBuildRequest, apipb.BuildInfo]
+}
+
+// StartBuild calls distbuild.api.v1.BuildService.StartBuild.
+func (c *buildServiceClient) StartBuild(ctx context.Context, req *connect.Request[apipb.BuildRequest]) (*connect.ServerStreamForClient[apipb.StartBuildResponse], error) {
+	return c.startBuild.CallServerStream(ctx, req)
+}
+
+// SignalBuild calls distbuild.api.v1.BuildService.SignalBuild.
+func (c *buildServiceClient) SignalBuild(ctx context.Context, req *connect.Request[apipb.SignalRequest]) (*connect.Response[apipb.SignalResponse], error) {
+	return c.signalBuild.CallUnary(ctx, req)
+}
+
+// WatchBuild calls distbuild.api.v1.BuildService.WatchBuild.
+func (c *buildServiceClient) WatchBuild(ctx context.Context, req *connect.Request[apipb.WatchBuildRequest]) (*connect.ServerStreamForClient[apipb.StatusUpdate], error) {
+	return c.watchBuild.CallServerStream(ctx, req)
+}
+
+// ListBuilds calls distbuild.api.v1.BuildService.ListBuilds.
+func (c *buildServiceClient) ListBuilds(ctx context.Context, req *connect.Request[apipb.ListBuildsRequest]) (*connect.Response[apipb.ListBuildsResponse], error) {
+	return c.listBuilds.CallUnary(ctx, req)
+}
+
+// GetBuild calls distbuild.api.v1.BuildService.GetBuild.
+func (c *buildServiceClient) GetBuild(ctx context.Context, req *connect.Request[apipb.GetBuildRequest]) (*connect.Response[apipb.BuildInfo], error) {
+	return c.getBuild.CallUnary(ctx, req)
+}
+
+// BuildServiceHandler is an implementation of the distbuild.api.v1.BuildService service.
+type BuildServiceHandler interface {
+	StartBuild(context.Context, *connect.Request[apipb.BuildRequest], *connect.ServerStream[apipb.StartBuildResponse]) error
+	SignalBuild(context.Context, *connect.Request[apipb.SignalRequest]) (*connect.Response[apipb.SignalResponse], error)
+	WatchBuild(context.Context, *connect.Request[apipb.WatchBuildRequest], *connect.ServerStream[apipb.StatusUpdate]) error
+	ListBuilds(context.Context, *connect.Request[apipb.ListBuildsRequest]) (*connect.Response[apipb.ListBuildsResponse], error)
+	GetBuild(context.Context, *connect.Request[apipb.GetBuildRequest]) (*connect.Response[apipb.BuildInfo], error)
+}
+
+// NewBuildServiceHandler builds an HTTP handler from the service implementation. It returns the
+// path on which to mount the handler and the handler itself.
+//
+// By default, handlers support the Connect, gRPC, and gRPC-Web protocols with the binary Protobuf
+// and JSON codecs. They also support gzip compression.
+func NewBuildServiceHandler(svc BuildServiceHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	buildServiceMethods := apipb.File_distbuild_api_v1_api_proto.Services().ByName("BuildService").Methods()
+	buildServiceStartBuildHandler := connect.NewServerStreamHandler(
+		BuildServiceStartBuildProcedure,
+		svc.StartBuild,
+		connect.WithSchema(buildServiceMethods.ByName("StartBuild")),
+		connect.WithHandlerOptions(opts...),
+	)
+	buildServiceSignalBuildHandler := connect.NewUnaryHandler(
+		BuildServiceSignalBuildProcedure,
+		svc.SignalBuild,
+		connect.WithSchema(buildServiceMethods.ByName("SignalBuild")),
+		connect.WithHandlerOptions(opts...),
+	)
+	buildServiceWatchBuildHandler := connect.NewServerStreamHandler(
+		BuildServiceWatchBuildProcedure,
+		svc.WatchBuild,
+		connect.WithSchema(buildServiceMethods.ByName("WatchBuild")),
+		connect.WithHandlerOptions(opts...),
+	)
+	buildServiceListBuildsHandler := connect.NewUnaryHandler(
+		BuildServiceListBuildsProcedure,
+		svc.ListBuilds,
+		connect.WithSchema(buildServiceMethods.ByName("ListBuilds")),
+		connect.WithHandlerOptions(opts...),
+	)
+	buildServiceGetBuildHandler := connect.NewUnaryHandler(
+		BuildServiceGetBuildProcedure,
+		svc.GetBuild,
+		connect.WithSchema(buildServiceMethods.ByName("GetBuild")),
+		connect.WithHandlerOptions(opts...),
+	)
+	return "/distbuild.api.v1.BuildService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case BuildServiceStartBuildProcedure:
+			buildServiceStartBuildHandler.ServeHTTP(w, r)
+		case BuildServiceSignalBuildProcedure:
+			buildServiceSignalBuildHandler.ServeHTTP(w, r)
+		case BuildServiceWatchBuildProcedure:
+			buildServiceWatchBuildHandler.ServeHTTP(w, r)
+		case BuildServiceListBuildsProcedure:
+			buildServiceListBuildsHandler.ServeHTTP(w, r)
+		case BuildServiceGetBuildProcedure:
+			buildServiceGetBuildHandler.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// UnimplementedBuildServiceHandler returns CodeUnimplemented from all methods.
+type UnimplementedBuildServiceHandler struct{}
+
+func (UnimplementedBuildServiceHandler) StartBuild(context.Context, *connect.Request[apipb.BuildRequest], *connect.ServerStream[apipb.StartBuildResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("distbuild.api.v1.BuildService.StartBuild is not implemented"))
+}
+
+func (UnimplementedBuildServiceHandler) SignalBuild(context.Context, *connect.Request[apipb.SignalRequest]) (*connect.Response[apipb.SignalResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("distbuild.api.v1.BuildService.SignalBuild is not implemented"))
+}
+
+func (UnimplementedBuildServiceHandler) WatchBuild(context.Context, *connect.Request[apipb.WatchBuildRequest], *connect.ServerStream[apipb.StatusUpdate]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("distbuild.api.v1.BuildService.WatchBuild is not implemented"))
+}
+
+func (UnimplementedBuildServiceHandler) ListBuilds(context.Context, *connect.Request[apipb.ListBuildsRequest]) (*connect.Response[apipb.ListBuildsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("distbuild.api.v1.BuildService.ListBuilds is not implemented"))
+}
+
+func (UnimplementedBuildServiceHandler) GetBuild(context.Context, *connect.Request[apipb.GetBuildRequest]) (*connect.Response[apipb.BuildInfo], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("distbuild.api.v1.BuildService.GetBuild is not implemented"))
+}
+
+// HeartbeatServiceClient is a client for the distbuild.api.v1.HeartbeatService service.
+type HeartbeatServiceClient interface {
+	Heartbeat(context.Context, *connect.Request[apipb.HeartbeatRequest]) (*connect.Response[apipb.HeartbeatResponse], error)
+}
+
+// NewHeartbeatServiceClient constructs a client for the distbuild.api.v1.HeartbeatService service.
+// By default, it uses the Connect protocol with the binary Protobuf Codec, asks for gzipped
+// responses, and sends uncompressed requests. To use the gRPC or gRPC-Web protocols, supply the
+// connect.WithGRPC() or connect.WithGRPCWeb() options.
+//
+// The URL supplied here should be the base URL for the Connect or gRPC server (for example,
+// http://api.acme.com or https://acme.com/grpc).
+func NewHeartbeatServiceClient(httpClient connect.HTTPClient, baseURL string, opts ...connect.ClientOption) HeartbeatServiceClient {
+	baseURL = strings.TrimRight(baseURL, "/")
+	heartbeatServiceMethods := apipb.File_distbuild_api_v1_api_proto.Services().ByName("HeartbeatService").Methods()
+	return &heartbeatServiceClient{
+		heartbeat: connect.NewClient[apipb.HeartbeatRequest, apipb.HeartbeatResponse](
+			httpClient,
+			baseURL+HeartbeatServiceHeartbeatProcedure,
+			connect.WithSchema(heartbeatServiceMethods.ByName("Heartbeat")),
+			connect.WithClientOptions(opts...),
+		),
+	}
+}
+
+// heartbeatServiceClient implements HeartbeatServiceClient.
+type heartbeatServiceClient struct {
+	heartbeat *connect.Client[apipb.HeartbeatRequest, apipb.HeartbeatResponse]
+}
+
+// Heartbeat calls distbuild.api.v1.HeartbeatService.Heartbeat.
+func (c *heartbeatServiceClient) Heartbeat(ctx context.Context, req *connect.Request[apipb.HeartbeatRequest]) (*connect.Response[apipb.HeartbeatResponse], error) {
+	return c.heartbeat.CallUnary(ctx, req)
+}
+
+// HeartbeatServiceHandler is an implementation of the distbuild.api.v1.HeartbeatService service.
+type HeartbeatServiceHandler interface {
+	Heartbeat(context.Context, *connect.Request[apipb.HeartbeatRequest]) (*connect.Response[apipb.HeartbeatResponse], error)
+}
+
+// NewHeartbeatServiceHandler builds an HTTP handler from the service implementation. It returns the
+// path on which to mount the handler and the handler itself.
+//
+// By default, handlers support the Connect, gRPC, and gRPC-Web protocols with the binary Protobuf
+// and JSON codecs. They also support gzip compression.
+func NewHeartbeatServiceHandler(svc HeartbeatServiceHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	heartbeatServiceMethods := apipb.File_distbuild_api_v1_api_proto.Services().ByName("HeartbeatService").Methods()
+	heartbeatServiceHeartbeatHandler := connect.NewUnaryHandler(
+		HeartbeatServiceHeartbeatProcedure,
+		svc.Heartbeat,
+		connect.WithSchema(heartbeatServiceMethods.ByName("Heartbeat")),
+		connect.WithHandlerOptions(opts...),
+	)
+	return "/distbuild.api.v1.HeartbeatService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case HeartbeatServiceHeartbeatProcedure:
+			heartbeatServiceHeartbeatHandler.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// UnimplementedHeartbeatServiceHandler returns CodeUnimplemented from all methods.
+type UnimplementedHeartbeatServiceHandler struct{}
+
+func (UnimplementedHeartbeatServiceHandler) Heartbeat(context.Context, *connect.Request[apipb.HeartbeatRequest]) (*connect.Response[apipb.HeartbeatResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("distbuild.api.v1.HeartbeatService.Heartbeat is not implemented"))
+}
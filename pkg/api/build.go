@@ -8,6 +8,10 @@ import (
 
 type BuildRequest struct {
 	Graph build.Graph `json:"graph"`
+
+	// Priority задаёт приоритет сборки. Джобы сборок с большим приоритетом запускаются раньше
+	// джобов других сборок того же владельца.
+	Priority int `json:"priority,omitempty"`
 }
 
 type BuildStarted struct {
@@ -16,11 +20,31 @@ type BuildStarted struct {
 }
 
 type StatusUpdate struct {
+	// Seq задаёт порядковый номер обновления внутри сборки, начиная с 1.
+	//
+	// По Seq клиент переподключается к потоку через /build/status и отбрасывает повторы.
+	Seq uint64 `json:"seq"`
+
+	JobOutput     *JobOutput     `json:"job_output"`
+	JobRetried    *JobRetry      `json:"job_retried,omitempty"`
 	JobFinished   *JobResult     `json:"job_finished"`
 	BuildFailed   *BuildFailed   `json:"build_failed"`
 	BuildFinished *BuildFinished `json:"build_finished"`
 }
 
+// JobRetry сообщает, что запуск джоба завершился ошибкой и джоб будет перезапущен.
+type JobRetry struct {
+	ID build.ID `json:"job_id"`
+
+	// Attempt задаёт номер следующего запуска джоба, начиная с 2.
+	Attempt int `json:"attempt"`
+
+	// Worker, Failure и Error описывают неудавшийся запуск.
+	Worker  WorkerID    `json:"worker"`
+	Failure FailureKind `json:"failure"`
+	Error   string      `json:"error"`
+}
+
 type BuildFailed struct {
 	Error string `json:"error"`
 }
@@ -30,8 +54,14 @@ type BuildFinished struct {
 
 type UploadDone struct{}
 
+// Cancel отменяет сборку. Сборка завершается с BuildFailed, в котором передаётся Reason.
+type Cancel struct {
+	Reason string `json:"reason"`
+}
+
 type SignalRequest struct {
 	UploadDone *UploadDone `json:"upload_done"`
+	Cancel     *Cancel     `json:"cancel"`
 }
 
 type SignalResponse struct {
@@ -45,6 +75,16 @@ type StatusWriter interface {
 type Service interface {
 	StartBuild(ctx context.Context, request *BuildRequest, w StatusWriter) error
 	SignalBuild(ctx context.Context, buildID build.ID, signal *SignalRequest) (*SignalResponse, error)
+
+	// WatchBuild пишет в w обновления уже запущенной сборки, начиная с обновления с номером fromSeq,
+	// и возвращается после завершения сборки.
+	WatchBuild(ctx context.Context, buildID build.ID, fromSeq uint64, w StatusWriter) error
+
+	// ListBuilds возвращает все сборки, известные координатору, без списка джобов.
+	ListBuilds(ctx context.Context) ([]BuildInfo, error)
+
+	// GetBuild возвращает подробное состояние сборки или ErrBuildNotFound.
+	GetBuild(ctx context.Context, buildID build.ID) (*BuildInfo, error)
 }
 
 type StatusReader interface {
@@ -6,12 +6,18 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sync/atomic"
+	"time"
 
+	"connectrpc.com/connect"
 	"go.uber.org/zap"
 
+	"distributed_build/pkg/api/apipb/apipbconnect"
+	"distributed_build/pkg/auth"
 	"distributed_build/pkg/build"
 )
 
@@ -19,13 +25,68 @@ type BuildClient struct {
 	logger   *zap.Logger
 	endpoint string
 	client   *http.Client
+	protocol atomic.Pointer[Protocol]
+
+	// rpc задан, если endpoint начинается с ConnectScheme или GRPCScheme.
+	rpc apipbconnect.BuildServiceClient
+
+	credentials auth.Credentials
 }
 
+// NewBuildClient создаёт клиента координатора.
+//
+// Транспорт выбирается по префиксу endpoint-а: "connect+http://..." - Connect, "grpc+http://..." - gRPC,
+// "http://..." - JSON API.
 func NewBuildClient(l *zap.Logger, endpoint string) *BuildClient {
-	return &BuildClient{logger: l, endpoint: endpoint, client: http.DefaultClient}
+	c := &BuildClient{logger: l, endpoint: endpoint, client: http.DefaultClient}
+	c.initRPC()
+	return c
+}
+
+func (c *BuildClient) initRPC() {
+	if httpClient, baseURL, opts, ok := rpcEndpoint(c.endpoint, c.client); ok {
+		opts = append(opts, connect.WithInterceptors(
+			&authInterceptor{credentials: func() auth.Credentials { return c.credentials }},
+			&protocolInterceptor{negotiated: c.protocol.Store}))
+		c.rpc = apipbconnect.NewBuildServiceClient(httpClient, baseURL, opts...)
+	}
+}
+
+// SetHTTPClient задаёт HTTP клиент, например с настройками mTLS. Вызывается до первого запроса.
+func (c *BuildClient) SetHTTPClient(client *http.Client) {
+	c.client = client
+	c.initRPC()
+}
+
+// Protocol возвращает протокол, согласованный с координатором при последнем запросе.
+func (c *BuildClient) Protocol() *Protocol {
+	return c.protocol.Load()
+}
+
+// SetCredentials задаёт токен, который клиент посылает с каждым запросом. Вызывается до первого запроса.
+func (c *BuildClient) SetCredentials(creds auth.Credentials) {
+	c.credentials = creds
+}
+
+func (c *BuildClient) do(req *http.Request) (*http.Response, error) {
+	if err := auth.Authorize(req, c.credentials); err != nil {
+		return nil, err
+	}
+
+	resp, peer, err := doNegotiated(c.client, req)
+	if err != nil {
+		return nil, err
+	}
+
+	c.protocol.Store(peer)
+	return resp, nil
 }
 
 func (c *BuildClient) StartBuild(ctx context.Context, request *BuildRequest) (*BuildStarted, StatusReader, error) {
+	if c.rpc != nil {
+		return c.rpcStartBuild(ctx, request)
+	}
+
 	reqJSON, err := json.Marshal(request)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to marshal request: %w", err)
@@ -35,7 +96,7 @@ func (c *BuildClient) StartBuild(ctx context.Context, request *BuildRequest) (*B
 		return nil, nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, nil, fmt.Errorf("request failed: %w", err)
 	}
@@ -45,17 +106,79 @@ func (c *BuildClient) StartBuild(ctx context.Context, request *BuildRequest) (*B
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to read error response: %w", err)
 		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return nil, nil, fmt.Errorf("%w: %s", ErrQuotaExceeded, string(errorData))
+		}
 		return nil, nil, fmt.Errorf("service error: %s", string(errorData))
 	}
+	// Один декодер на весь поток: json.Decoder буферизует данные, идущие после BuildStarted.
+	dec := json.NewDecoder(resp.Body)
 	var buildStarted BuildStarted
-	if err := json.NewDecoder(resp.Body).Decode(&buildStarted); err != nil {
+	if err := dec.Decode(&buildStarted); err != nil {
+		resp.Body.Close()
 		return nil, nil, fmt.Errorf("failed to decode build started response: %w", err)
 	}
-	return &buildStarted, NewStatusReader(resp.Body), nil
+	sr := &statusReader{
+		ctx:     ctx,
+		client:  c,
+		buildID: buildStarted.ID,
+		stream:  &jsonStatusStream{r: resp.Body, dec: dec},
+	}
+	return &buildStarted, sr, nil
+}
+
+// WatchBuild подключается к потоку обновлений уже запущенной сборки, начиная с обновления fromSeq.
+func (c *BuildClient) WatchBuild(ctx context.Context, buildID build.ID, fromSeq uint64) (StatusReader, error) {
+	stream, err := c.watch(ctx, buildID, fromSeq)
+	if err != nil {
+		return nil, err
+	}
 
+	sr := &statusReader{
+		ctx:     ctx,
+		client:  c,
+		buildID: buildID,
+		stream:  stream,
+	}
+	if fromSeq > 0 {
+		sr.lastSeq = fromSeq - 1
+	}
+	return sr, nil
+}
+
+func (c *BuildClient) watch(ctx context.Context, buildID build.ID, fromSeq uint64) (statusStream, error) {
+	if c.rpc != nil {
+		return c.rpcWatch(ctx, buildID, fromSeq)
+	}
+
+	url := fmt.Sprintf("%s/build/status?build_id=%s&from_seq=%d", c.endpoint, buildID.String(), fromSeq)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create status request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("status request failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+
+		errorData, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read error response: %w", err)
+		}
+		return nil, fmt.Errorf("service error: %s", string(errorData))
+	}
+	return &jsonStatusStream{r: resp.Body, dec: json.NewDecoder(resp.Body)}, nil
 }
 
 func (c *BuildClient) SignalBuild(ctx context.Context, buildID build.ID, signal *SignalRequest) (*SignalResponse, error) {
+	if c.rpc != nil {
+		return c.rpcSignalBuild(ctx, buildID, signal)
+	}
+
 	signalJSON, err := json.Marshal(signal)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal signal request: %w", err)
@@ -68,7 +191,7 @@ func (c *BuildClient) SignalBuild(ctx context.Context, buildID build.ID, signal
 
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("signal request failed: %w", err)
 	}
@@ -90,25 +213,194 @@ func (c *BuildClient) SignalBuild(ctx context.Context, buildID build.ID, signal
 	return &signalResponse, nil
 }
 
+func (c *BuildClient) ListBuilds(ctx context.Context) ([]BuildInfo, error) {
+	if c.rpc != nil {
+		return c.rpcListBuilds(ctx)
+	}
+
+	var builds []BuildInfo
+	if _, err := c.get(ctx, c.endpoint+"/builds", &builds); err != nil {
+		return nil, err
+	}
+	return builds, nil
+}
+
+func (c *BuildClient) GetBuild(ctx context.Context, buildID build.ID) (*BuildInfo, error) {
+	if c.rpc != nil {
+		return c.rpcGetBuild(ctx, buildID)
+	}
+
+	var info BuildInfo
+	status, err := c.get(ctx, c.endpoint+"/build?id="+buildID.String(), &info)
+	if status == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %w", ErrBuildNotFound, err)
+	} else if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (c *BuildClient) get(ctx context.Context, url string, rsp any) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errorData, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return resp.StatusCode, fmt.Errorf("failed to read error response: %w", err)
+		}
+		return resp.StatusCode, fmt.Errorf("service error: %s", string(errorData))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(rsp); err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
+
+const (
+	// maxReattachAttempts ограничивает число попыток переподключиться к потоку подряд, без новых обновлений.
+	maxReattachAttempts = 5
+	reattachDelay       = 100 * time.Millisecond
+
+	cancelTimeout = 5 * time.Second
+)
+
+// statusStream - поток обновлений статуса одного подключения к координатору.
+type statusStream interface {
+	// next возвращает io.EOF, если координатор закрыл поток.
+	next() (*StatusUpdate, error)
+	Close() error
+}
+
+type jsonStatusStream struct {
+	r   io.ReadCloser
+	dec *json.Decoder
+}
+
+func (s *jsonStatusStream) next() (*StatusUpdate, error) {
+	var update StatusUpdate
+	if err := s.dec.Decode(&update); err != nil {
+		return nil, err
+	}
+	return &update, nil
+}
+
+func (s *jsonStatusStream) Close() error {
+	return s.r.Close()
+}
+
 type statusReader struct {
-	r io.ReadCloser
+	stream statusStream
+
+	// Поля ниже заполнены, только если поток получен из BuildClient. Без них statusReader
+	// не умеет переподключаться.
+	ctx      context.Context
+	client   *BuildClient
+	buildID  build.ID
+	lastSeq  uint64
+	attempts int
+
+	// finished - получено ли BuildFinished или BuildFailed. До этого конец потока - обрыв соединения.
+	finished bool
 }
 
 func NewStatusReader(reader io.ReadCloser) StatusReader {
-	return &statusReader{r: reader}
+	return &statusReader{stream: &jsonStatusStream{r: reader, dec: json.NewDecoder(reader)}}
 }
+
+// Next возвращает следующее обновление статуса.
+//
+// Если соединение оборвалось, Next переподключается к сборке через /build/status (или WatchBuild) и продолжает
+// с обновления, следующего за последним полученным. Поток, закрытый до BuildFinished или BuildFailed, тоже
+// считается оборванным. Если оборвалось из-за отмены контекста, Next отменяет сборку на координаторе.
+// После BuildFinished или BuildFailed Next не переподключается и возвращает ошибку потока как есть.
 func (sr *statusReader) Next() (*StatusUpdate, error) {
-	var update StatusUpdate
-	if err := json.NewDecoder(sr.r).Decode(&update); err != nil {
+	for {
+		update, err := sr.stream.next()
+		if err == nil {
+			if update.Seq != 0 {
+				if update.Seq <= sr.lastSeq {
+					continue
+				}
+				sr.lastSeq = update.Seq
+			}
+			sr.attempts = 0
+			if update.BuildFinished != nil || update.BuildFailed != nil {
+				sr.finished = true
+			}
+			return update, nil
+		}
+
 		if err == io.EOF {
-			return nil, io.EOF
+			if sr.finished || sr.client == nil {
+				return nil, io.EOF
+			}
+			err = errors.New("status stream closed before build finished")
+		}
+		if sr.finished || sr.client == nil {
+			// После BuildFinished или BuildFailed переподключаться незачем: сборка завершилась.
+			return nil, fmt.Errorf("failed to decode status update: %w", err)
+		}
+		if sr.ctx.Err() != nil {
+			sr.cancelBuild()
+			return nil, fmt.Errorf("failed to decode status update: %w", err)
+		}
+		if reattachErr := sr.reattach(); reattachErr != nil {
+			return nil, fmt.Errorf("failed to decode status update: %w (reattach failed: %v)", err, reattachErr)
 		}
-		return nil, fmt.Errorf("failed to decode status update: %w", err)
 	}
-	return &update, nil
+}
+
+func (sr *statusReader) reattach() error {
+	for {
+		sr.attempts++
+		if sr.attempts > maxReattachAttempts {
+			return fmt.Errorf("gave up after %d attempts", maxReattachAttempts)
+		}
+
+		select {
+		case <-sr.ctx.Done():
+			return sr.ctx.Err()
+		case <-time.After(reattachDelay * time.Duration(sr.attempts)):
+		}
+
+		sr.client.logger.Warn("status stream broken, reattaching",
+			zap.String("build_id", sr.buildID.String()),
+			zap.Uint64("from_seq", sr.lastSeq+1),
+			zap.Int("attempt", sr.attempts))
+
+		stream, err := sr.client.watch(sr.ctx, sr.buildID, sr.lastSeq+1)
+		if err != nil {
+			sr.client.logger.Error("reattach failed", zap.Error(err))
+			continue
+		}
+
+		sr.stream.Close()
+		sr.stream = stream
+		return nil
+	}
+}
+
+func (sr *statusReader) cancelBuild() {
+	ctx, cancel := context.WithTimeout(context.Background(), cancelTimeout)
+	defer cancel()
+
+	signal := &SignalRequest{Cancel: &Cancel{Reason: "cancelled by client"}}
+	if _, err := sr.client.SignalBuild(ctx, sr.buildID, signal); err != nil {
+		sr.client.logger.Error("unable to cancel build", zap.String("build_id", sr.buildID.String()), zap.Error(err))
+	}
 }
 
 func (sr *statusReader) Close() error {
-	sr.r.Close()
+	sr.stream.Close()
 	return nil
 }
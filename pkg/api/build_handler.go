@@ -3,10 +3,13 @@
 package api
 
 import (
+	"distributed_build/pkg/auth"
 	"distributed_build/pkg/build"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
+	"strconv"
 
 	"go.uber.org/zap"
 )
@@ -14,14 +17,50 @@ import (
 type BuildHandler struct {
 	logger  *zap.Logger
 	service Service
+	auth    auth.Authenticator
 }
 
 func NewBuildService(l *zap.Logger, s Service) *BuildHandler {
 	return &BuildHandler{logger: l, service: s}
 }
 
+// RequireAuth включает проверку токенов: все вызовы разрешены только auth.RoleClient.
+// Вызывается до Register.
+func (h *BuildHandler) RequireAuth(a auth.Authenticator) {
+	h.auth = a
+}
+
+// accept проверяет токен и договаривается о протоколе с отправителем запроса.
+func (h *BuildHandler) accept(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	r, ok := auth.Check(h.logger, h.auth, w, r, auth.RoleClient)
+	if !ok {
+		return nil, false
+	}
+	return negotiateRequest(h.logger, w, r)
+}
+
+// Register регистрирует в mux JSON API и тот же сервис поверх Connect и gRPC (см. api.proto).
 func (h *BuildHandler) Register(mux *http.ServeMux) {
+	(&rpcBuildHandler{logger: h.logger, service: h.service, auth: h.auth}).register(mux)
+
 	mux.HandleFunc("/build", func(w http.ResponseWriter, r *http.Request) {
+		r, ok := h.accept(w, r)
+		if !ok {
+			return
+		}
+
+		if r.Method == http.MethodGet {
+			h.getBuild(w, r)
+			return
+		}
+
+		if err := CheckClientProtocol(PeerProtocol(r.Context())); err != nil {
+			errorMessage := "unable to negotiate protocol: " + err.Error()
+			h.logger.Error(errorMessage)
+			http.Error(w, errorMessage, http.StatusBadRequest)
+			return
+		}
+
 		data, err := io.ReadAll(r.Body)
 		if err != nil {
 			errorMessage := "unable to read request body " + err.Error()
@@ -47,7 +86,11 @@ func (h *BuildHandler) Register(mux *http.ServeMux) {
 			errorMessage := "service error: unable to start build " + err.Error()
 			h.logger.Error(errorMessage)
 			if !writer.started {
-				http.Error(w, errorMessage, http.StatusInternalServerError)
+				status := http.StatusInternalServerError
+				if errors.Is(err, ErrQuotaExceeded) {
+					status = http.StatusTooManyRequests
+				}
+				http.Error(w, errorMessage, status)
 				return
 			}
 			err = writer.Updated(&StatusUpdate{BuildFailed: &BuildFailed{Error: errorMessage}})
@@ -57,7 +100,71 @@ func (h *BuildHandler) Register(mux *http.ServeMux) {
 			return
 		}
 	})
+	mux.HandleFunc("/builds", func(w http.ResponseWriter, r *http.Request) {
+		r, ok := h.accept(w, r)
+		if !ok {
+			return
+		}
+
+		builds, err := h.service.ListBuilds(r.Context())
+		if err != nil {
+			errorMessage := "error listing builds " + err.Error()
+			h.logger.Error(errorMessage)
+			http.Error(w, errorMessage, http.StatusInternalServerError)
+			return
+		}
+		h.writeJSON(w, builds)
+	})
+	mux.HandleFunc("/build/status", func(w http.ResponseWriter, r *http.Request) {
+		r, ok := h.accept(w, r)
+		if !ok {
+			return
+		}
+
+		var buildID build.ID
+		err := buildID.UnmarshalText([]byte(r.URL.Query().Get("build_id")))
+		if err != nil {
+			errorMessage := "unable to read status buildID: " + err.Error()
+			h.logger.Error(errorMessage)
+			http.Error(w, errorMessage, http.StatusBadRequest)
+			return
+		}
+
+		var fromSeq uint64
+		if fromSeqData := r.URL.Query().Get("from_seq"); fromSeqData != "" {
+			fromSeq, err = strconv.ParseUint(fromSeqData, 10, 64)
+			if err != nil {
+				errorMessage := "unable to read status from_seq: " + err.Error()
+				h.logger.Error(errorMessage)
+				http.Error(w, errorMessage, http.StatusBadRequest)
+				return
+			}
+		}
+
+		h.logger.Info("client reattached to build", zap.String("build_id", buildID.String()), zap.Uint64("from_seq", fromSeq))
+
+		ctrl := http.NewResponseController(w)
+		writer := NewResponseWriter(w, ctrl)
+		err = h.service.WatchBuild(r.Context(), buildID, fromSeq, writer)
+		if err != nil {
+			errorMessage := "service error: unable to watch build " + err.Error()
+			h.logger.Error(errorMessage)
+			if !writer.started {
+				http.Error(w, errorMessage, http.StatusInternalServerError)
+				return
+			}
+			err = writer.Updated(&StatusUpdate{BuildFailed: &BuildFailed{Error: errorMessage}})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+		}
+	})
 	mux.HandleFunc("/signal", func(w http.ResponseWriter, r *http.Request) {
+		r, ok := h.accept(w, r)
+		if !ok {
+			return
+		}
+
 		buildIDData := r.URL.Query().Get("build_id")
 		var buildID build.ID
 		err := buildID.UnmarshalText([]byte(buildIDData))
@@ -110,6 +217,48 @@ func (h *BuildHandler) Register(mux *http.ServeMux) {
 	})
 }
 
+func (h *BuildHandler) getBuild(w http.ResponseWriter, r *http.Request) {
+	var buildID build.ID
+	err := buildID.UnmarshalText([]byte(r.URL.Query().Get("id")))
+	if err != nil {
+		errorMessage := "unable to read buildID: " + err.Error()
+		h.logger.Error(errorMessage)
+		http.Error(w, errorMessage, http.StatusBadRequest)
+		return
+	}
+
+	info, err := h.service.GetBuild(r.Context(), buildID)
+	if err != nil {
+		errorMessage := "error getting build " + err.Error()
+		h.logger.Error(errorMessage)
+
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrBuildNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, errorMessage, status)
+		return
+	}
+	h.writeJSON(w, info)
+}
+
+func (h *BuildHandler) writeJSON(w http.ResponseWriter, v any) {
+	respData, err := json.Marshal(v)
+	if err != nil {
+		errorMessage := "error generating response " + err.Error()
+		h.logger.Error(errorMessage)
+		http.Error(w, errorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = w.Write(respData); err != nil {
+		h.logger.Error("unable to write response", zap.Error(err))
+	}
+}
+
 type ResponseWriter struct {
 	w       http.ResponseWriter
 	ctrl    *http.ResponseController
@@ -135,6 +284,8 @@ func (rw *ResponseWriter) Started(rsp *BuildStarted) error {
 }
 
 func (rw *ResponseWriter) Updated(update *StatusUpdate) error {
+	rw.started = true
+
 	data, err := json.Marshal(*update)
 	if err != nil {
 		return err
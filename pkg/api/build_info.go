@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"distributed_build/pkg/auth"
+	"distributed_build/pkg/build"
+)
+
+// ErrBuildNotFound возвращается сервисом, если сборки с таким ID нет на координаторе.
+var ErrBuildNotFound = errors.New("build not found")
+
+// ErrQuotaExceeded возвращается из StartBuild, если владелец сборки превысил свою квоту на координаторе.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// TenantFromContext возвращает владельца запроса, от имени которого запускается сборка.
+//
+// Владелец определяется по токену или сертификату клиента (см. auth.FromContext). Если проверка
+// выключена, все сборки принадлежат владельцу по умолчанию - пустой строке.
+func TenantFromContext(ctx context.Context) string {
+	if id := auth.FromContext(ctx); id != nil {
+		return id.Subject
+	}
+	return ""
+}
+
+type BuildState string
+
+const (
+	// BuildStateUploading - координатор ждёт, пока клиент зальёт недостающие файлы.
+	BuildStateUploading BuildState = "uploading"
+	BuildStateRunning   BuildState = "running"
+	BuildStateFinished  BuildState = "finished"
+	BuildStateFailed    BuildState = "failed"
+	BuildStateCancelled BuildState = "cancelled"
+)
+
+type JobState string
+
+const (
+	// JobStatePending - джоб ждёт своих зависимостей или свободного воркера.
+	JobStatePending JobState = "pending"
+	JobStateRunning JobState = "running"
+	// JobStateCached - результат джоба взят из кеша, джоб не запускался.
+	JobStateCached    JobState = "cached"
+	JobStateSucceeded JobState = "succeeded"
+	JobStateFailed    JobState = "failed"
+)
+
+// BuildInfo описывает состояние сборки на координаторе.
+type BuildInfo struct {
+	ID        build.ID   `json:"id"`
+	State     BuildState `json:"state"`
+	Submitted time.Time  `json:"submitted"`
+
+	// Tenant задаёт владельца сборки, см. TenantFromContext.
+	Tenant string `json:"tenant,omitempty"`
+
+	// JobCounts задаёт число джобов сборки в каждом состоянии.
+	JobCounts map[JobState]int `json:"job_counts"`
+
+	// Jobs перечисляет джобы сборки. Заполняется только в ответе на GET /build?id=.
+	Jobs []JobInfo `json:"jobs,omitempty"`
+}
+
+type JobInfo struct {
+	ID    build.ID `json:"id"`
+	Name  string   `json:"name"`
+	State JobState `json:"state"`
+
+	// Worker задаёт воркера, на котором джоб запущен или был выполнен.
+	Worker WorkerID `json:"worker,omitempty"`
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
@@ -161,3 +162,231 @@ func TestBuildResultsStreaming(t *testing.T) {
 	defer r.Close()
 	require.Equal(t, started, rsp)
 }
+
+func TestBuildJobOutputStreaming(t *testing.T) {
+	env, stop := newEnv(t)
+	defer stop()
+
+	ctx := context.Background()
+
+	jobID := build.ID{01}
+	updates := []*api.StatusUpdate{
+		{JobOutput: &api.JobOutput{ID: jobID, Stdout: []byte("foo")}},
+		{JobOutput: &api.JobOutput{ID: jobID, Stderr: []byte("bar")}},
+		{JobFinished: &api.JobResult{ID: jobID, Stdout: []byte("foo"), Stderr: []byte("bar")}},
+		{BuildFinished: &api.BuildFinished{}},
+	}
+
+	env.mock.EXPECT().StartBuild(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, req *api.BuildRequest, w api.StatusWriter) error {
+			if err := w.Started(&api.BuildStarted{ID: build.ID{02}}); err != nil {
+				return err
+			}
+
+			for _, u := range updates {
+				if err := w.Updated(u); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+	_, r, err := env.client.StartBuild(ctx, &api.BuildRequest{})
+	require.NoError(t, err)
+	defer r.Close()
+
+	for _, expected := range updates {
+		u, err := r.Next()
+		require.NoError(t, err)
+		require.Equal(t, expected, u)
+	}
+
+	_, err = r.Next()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestBuildCancelSignal(t *testing.T) {
+	env, stop := newEnv(t)
+	defer stop()
+
+	buildID := build.ID{01}
+	req := &api.SignalRequest{Cancel: &api.Cancel{Reason: "interrupted"}}
+
+	env.mock.EXPECT().SignalBuild(gomock.Any(), buildID, req).Return(&api.SignalResponse{}, nil)
+
+	_, err := env.client.SignalBuild(context.Background(), buildID, req)
+	require.NoError(t, err)
+}
+
+func TestBuildStatusReattach(t *testing.T) {
+	env, stop := newEnv(t)
+	defer stop()
+
+	ctx := context.Background()
+
+	buildID := build.ID{02}
+	update := func(seq uint64) *api.StatusUpdate {
+		return &api.StatusUpdate{Seq: seq, JobFinished: &api.JobResult{ID: build.ID{byte(seq)}}}
+	}
+	finished := &api.StatusUpdate{Seq: 4, BuildFinished: &api.BuildFinished{}}
+
+	gomock.InOrder(
+		env.mock.EXPECT().StartBuild(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, req *api.BuildRequest, w api.StatusWriter) error {
+				if err := w.Started(&api.BuildStarted{ID: buildID}); err != nil {
+					return err
+				}
+				if err := w.Updated(update(1)); err != nil {
+					return err
+				}
+				if err := w.Updated(update(2)); err != nil {
+					return err
+				}
+
+				// Drop the connection in the middle of the stream.
+				panic(http.ErrAbortHandler)
+			}),
+		env.mock.EXPECT().WatchBuild(gomock.Any(), buildID, uint64(3), gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ build.ID, _ uint64, w api.StatusWriter) error {
+				// Duplicates must be dropped by the client.
+				for _, u := range []*api.StatusUpdate{update(2), update(3), finished} {
+					if err := w.Updated(u); err != nil {
+						return err
+					}
+				}
+				return nil
+			}),
+	)
+
+	_, r, err := env.client.StartBuild(ctx, &api.BuildRequest{})
+	require.NoError(t, err)
+	defer r.Close()
+
+	for _, expected := range []*api.StatusUpdate{update(1), update(2), update(3), finished} {
+		u, err := r.Next()
+		require.NoError(t, err)
+		require.Equal(t, expected, u)
+	}
+
+	_, err = r.Next()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestBuildStatusReattachOnEOF(t *testing.T) {
+	env, stop := newEnv(t)
+	defer stop()
+
+	buildID := build.ID{02}
+	update := &api.StatusUpdate{Seq: 1, JobFinished: &api.JobResult{ID: build.ID{01}}}
+	finished := &api.StatusUpdate{Seq: 2, BuildFinished: &api.BuildFinished{}}
+
+	gomock.InOrder(
+		env.mock.EXPECT().StartBuild(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, req *api.BuildRequest, w api.StatusWriter) error {
+				if err := w.Started(&api.BuildStarted{ID: buildID}); err != nil {
+					return err
+				}
+
+				// The stream ends cleanly, but the build is still running.
+				return w.Updated(update)
+			}),
+		env.mock.EXPECT().WatchBuild(gomock.Any(), buildID, uint64(2), gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ build.ID, _ uint64, w api.StatusWriter) error {
+				return w.Updated(finished)
+			}),
+	)
+
+	_, r, err := env.client.StartBuild(context.Background(), &api.BuildRequest{})
+	require.NoError(t, err)
+	defer r.Close()
+
+	for _, expected := range []*api.StatusUpdate{update, finished} {
+		u, err := r.Next()
+		require.NoError(t, err)
+		require.Equal(t, expected, u)
+	}
+
+	_, err = r.Next()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestBuildStatusNoReattachAfterFinish(t *testing.T) {
+	env, stop := newEnv(t)
+	defer stop()
+
+	finished := &api.StatusUpdate{Seq: 1, BuildFinished: &api.BuildFinished{}}
+
+	// WatchBuild is not expected: the build is over, there is nothing to reattach to.
+	env.mock.EXPECT().StartBuild(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, req *api.BuildRequest, w api.StatusWriter) error {
+			if err := w.Started(&api.BuildStarted{ID: build.ID{02}}); err != nil {
+				return err
+			}
+			if err := w.Updated(finished); err != nil {
+				return err
+			}
+
+			// Drop the connection after the final update.
+			panic(http.ErrAbortHandler)
+		})
+
+	_, r, err := env.client.StartBuild(context.Background(), &api.BuildRequest{})
+	require.NoError(t, err)
+	defer r.Close()
+
+	u, err := r.Next()
+	require.NoError(t, err)
+	require.Equal(t, finished, u)
+
+	_, err = r.Next()
+	require.Error(t, err)
+}
+
+func TestBuildStatusWatchError(t *testing.T) {
+	env, stop := newEnv(t)
+	defer stop()
+
+	buildID := build.ID{02}
+	env.mock.EXPECT().WatchBuild(gomock.Any(), buildID, uint64(1), gomock.Any()).Return(fmt.Errorf("build not found"))
+
+	_, err := env.client.WatchBuild(context.Background(), buildID, 1)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "build not found")
+}
+
+func TestBuildInspection(t *testing.T) {
+	env, stop := newEnv(t)
+	defer stop()
+
+	ctx := context.Background()
+
+	buildID := build.ID{02}
+	submitted := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	info := &api.BuildInfo{
+		ID:        buildID,
+		State:     api.BuildStateRunning,
+		Submitted: submitted,
+		JobCounts: map[api.JobState]int{api.JobStateRunning: 1, api.JobStatePending: 1},
+		Jobs: []api.JobInfo{
+			{ID: build.ID{'a'}, Name: "compile", State: api.JobStateRunning, Worker: "worker0"},
+			{ID: build.ID{'b'}, Name: "link", State: api.JobStatePending},
+		},
+	}
+	summary := *info
+	summary.Jobs = nil
+
+	env.mock.EXPECT().ListBuilds(gomock.Any()).Return([]api.BuildInfo{summary}, nil)
+	env.mock.EXPECT().GetBuild(gomock.Any(), buildID).Return(info, nil)
+	env.mock.EXPECT().GetBuild(gomock.Any(), build.ID{03}).Return(nil, api.ErrBuildNotFound)
+
+	builds, err := env.client.ListBuilds(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []api.BuildInfo{summary}, builds)
+
+	rsp, err := env.client.GetBuild(ctx, buildID)
+	require.NoError(t, err)
+	require.Equal(t, info, rsp)
+
+	_, err = env.client.GetBuild(ctx, build.ID{03})
+	require.ErrorIs(t, err, api.ErrBuildNotFound)
+}
@@ -2,6 +2,8 @@ package api
 
 import (
 	"context"
+	"crypto/sha1"
+	"time"
 
 	"distributed_build/pkg/build"
 )
@@ -18,6 +20,94 @@ type JobResult struct {
 	//
 	// Если Error == nil, значит джоб завершился успешно.
 	Error *string `json:"error"`
+
+	// Failure говорит, кто виноват в ошибке Error. Пустое значение у старых воркеров означает FailureJob.
+	Failure FailureKind `json:"failure,omitempty"`
+
+	// Usage описывает ресурсы, которые потратил джоб. Nil, если воркер не умеет их считать.
+	Usage *ResourceUsage `json:"usage,omitempty"`
+
+	// Log описывает полный вывод джоба, если Stdout или Stderr не поместились в лимит воркера и были обрезаны.
+	// Nil, если вывод пришёл целиком.
+	Log *OutputLog `json:"log,omitempty"`
+
+	// Outputs - манифест файлов, которые джоб оставил в выходной директории. Заполняется и для джоба,
+	// не прошедшего проверку build.Job.Outputs.
+	Outputs []OutputFile `json:"outputs,omitempty"`
+}
+
+// OutputFile описывает один файл из выходной директории джоба.
+type OutputFile struct {
+	// Path - путь относительно выходной директории, разделённый "/".
+	Path string `json:"path"`
+	// Size - размер файла в байтах.
+	Size int64 `json:"size"`
+	// Digest - sha256 содержимого в hex. Пустой для символических ссылок.
+	Digest string `json:"digest,omitempty"`
+	// Symlink - куда указывает символическая ссылка. Пустой для обычных файлов.
+	Symlink string `json:"symlink,omitempty"`
+}
+
+// OutputLog описывает лог-артефакт с полным выводом джоба.
+//
+// Обрезанный Stdout или Stderr содержит начало и конец вывода, между которыми стоит строка с пометкой
+// об обрезке. Полный вывод лежит в кеше воркера Worker в артефакте ArtifactID в файлах LogStdout и LogStderr,
+// его можно скачать по /artifact?id=, см. artifact.Fetch.
+type OutputLog struct {
+	Worker     WorkerID `json:"worker_id"`
+	ArtifactID build.ID `json:"artifact_id"`
+
+	// StdoutSize и StderrSize - полный размер вывода в байтах.
+	StdoutSize int64 `json:"stdout_size"`
+	StderrSize int64 `json:"stderr_size"`
+}
+
+// Файлы лог-артефакта.
+const (
+	LogStdout = "stdout"
+	LogStderr = "stderr"
+)
+
+// LogArtifactID возвращает ID лог-артефакта джоба jobID. Он отличается от ID артефакта с выходом джоба,
+// чтобы лог не попадал в зависимости других джобов.
+func LogArtifactID(jobID build.ID) build.ID {
+	return sha1.Sum(append([]byte("log:"), jobID[:]...))
+}
+
+// ResourceUsage описывает ресурсы, потраченные джобом.
+type ResourceUsage struct {
+	// PeakMemory - максимальный объём памяти джоба в байтах. Ноль, если ядро его не сообщает.
+	PeakMemory int64 `json:"peak_memory"`
+
+	// CPUTime - процессорное время джоба в user и system режимах.
+	CPUTime time.Duration `json:"cpu_time"`
+
+	// ReadBytes и WriteBytes - объём дискового ввода-вывода джоба в байтах.
+	ReadBytes  int64 `json:"read_bytes"`
+	WriteBytes int64 `json:"write_bytes"`
+}
+
+// FailureKind классифицирует ошибку джоба.
+type FailureKind string
+
+const (
+	// FailureJob - ошибка самого джоба, например ненулевой ExitCode. Перезапуск даст тот же результат,
+	// если только джоб не помечен build.Job.Retryable.
+	FailureJob FailureKind = "job"
+	// FailureInfra - джоб не удалось выполнить из-за воркера: не скачались исходники или артефакты,
+	// кончилось место на диске, воркер умер. Такой джоб имеет смысл перезапустить на другом воркере.
+	FailureInfra FailureKind = "infra"
+	// FailureOOM - джоб убит за превышение лимита памяти build.Job.Resources.Memory. С тем же лимитом
+	// перезапуск упадёт снова, поэтому такой джоб не перезапускается, даже если он Retryable.
+	FailureOOM FailureKind = "oom"
+)
+
+// JobOutput описывает очередной кусок вывода джоба, который ещё выполняется.
+type JobOutput struct {
+	ID build.ID `json:"job_id"`
+
+	Stdout []byte `json:"stdout"`
+	Stderr []byte `json:"stderr"`
 }
 
 type WorkerID string
@@ -45,8 +135,40 @@ type HeartbeatRequest struct {
 	// на этой итерации цикла.
 	FinishedJob []JobResult `json:"finished_jobs"`
 
+	// JobOutput передаёт координатору вывод бегущих джобов, появившийся на этой итерации цикла.
+	//
+	// Полный вывод джоба всё равно передаётся в FinishedJob после его завершения.
+	JobOutput []JobOutput `json:"job_output"`
+
 	// AddedArtifacts говорит, какие артефакты появились в кеше на этой итерации цикла.
 	AddedArtifacts []build.ID `json:"added_artifacts"`
+
+	// PollTimeout разрешает координатору держать запрос, пока для воркера не появятся джобы
+	// или отмены, но не дольше PollTimeout. Ноль означает ответить сразу.
+	//
+	// Поле учитывается, только если координатор поддерживает FeatureLongPoll. Старый координатор
+	// отвечает сразу, и воркер просто продолжает опрашивать его в цикле.
+	PollTimeout time.Duration `json:"poll_timeout"`
+
+	// Draining сообщает, что воркер уходит из кластера: новых джобов он не берёт, а FreeSlots всегда ноль.
+	// Воркер доделывает бегущие джобы и раздаёт артефакты, пока координатор не ответит Drained.
+	//
+	// Поле учитывается, только если координатор поддерживает FeatureDrain.
+	Draining bool `json:"draining,omitempty"`
+
+	// Leaving - последний heartbeat воркера. Координатор забывает воркера и его кеш.
+	Leaving bool `json:"leaving,omitempty"`
+
+	// Beat - номер heartbeat-а. Воркер нумерует heartbeat-ы с 1 после каждого запуска.
+	//
+	// Received - наибольший номер, до которого воркер дождался ответов на все свои heartbeat-ы, успешных
+	// или оборвавшихся. Джобы из этих ответов уже перечислены в RunningJobs или FinishedJob. Пока висит
+	// long-poll, воркер шлёт другие heartbeat-ы, и без этих полей координатор не отличит джоб, который
+	// ещё едет к воркеру, от потерянного.
+	//
+	// Ноль в Beat означает, что воркер heartbeat-ы не нумерует.
+	Beat     uint64 `json:"beat,omitempty"`
+	Received uint64 `json:"received,omitempty"`
 }
 
 // JobSpec описывает джоб, который нужно запустить.
@@ -62,6 +184,13 @@ type JobSpec struct {
 
 type HeartbeatResponse struct {
 	JobsToRun map[build.ID]JobSpec `json:"jobs_to_run"`
+
+	// JobsToCancel перечисляет бегущие на воркере джобы, которые нужно убить, потому что их сборку отменили.
+	JobsToCancel []build.ID `json:"jobs_to_cancel"`
+
+	// Drained отвечает воркеру с Draining, что на нём не бежит ни одного джоба, а его артефакты
+	// не нужны ни одному джобу в очереди. Воркер может уйти.
+	Drained bool `json:"drained,omitempty"`
 }
 
 type HeartbeatService interface {
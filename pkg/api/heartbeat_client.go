@@ -9,20 +9,73 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sync/atomic"
 
+	"connectrpc.com/connect"
 	"go.uber.org/zap"
+
+	"distributed_build/pkg/api/apipb/apipbconnect"
+	"distributed_build/pkg/auth"
 )
 
 type HeartbeatClient struct {
-	logger   *zap.Logger
-	endpoint string
-	client   *http.Client
+	logger      *zap.Logger
+	endpoint    string
+	coordinator string
+	client      *http.Client
+	protocol    atomic.Pointer[Protocol]
+
+	// rpc задан, если endpoint начинается с ConnectScheme или GRPCScheme.
+	rpc apipbconnect.HeartbeatServiceClient
+
+	credentials auth.Credentials
 }
 
+// NewHeartbeatClient создаёт клиента координатора. Транспорт выбирается так же, как в NewBuildClient.
 func NewHeartbeatClient(l *zap.Logger, endpoint string) *HeartbeatClient {
-	return &HeartbeatClient{logger: l, endpoint: endpoint + "/heartbeat", client: http.DefaultClient}
+	c := &HeartbeatClient{logger: l, endpoint: endpoint + "/heartbeat", coordinator: endpoint, client: http.DefaultClient}
+	c.initRPC()
+	return c
+}
+
+func (c *HeartbeatClient) initRPC() {
+	if httpClient, baseURL, opts, ok := rpcEndpoint(c.coordinator, c.client); ok {
+		opts = append(opts, connect.WithInterceptors(
+			&authInterceptor{credentials: func() auth.Credentials { return c.credentials }},
+			&protocolInterceptor{negotiated: c.negotiated}))
+		c.rpc = apipbconnect.NewHeartbeatServiceClient(httpClient, baseURL, opts...)
+	}
+}
+
+// SetHTTPClient задаёт HTTP клиент, например с настройками mTLS. Вызывается до первого запроса.
+func (c *HeartbeatClient) SetHTTPClient(client *http.Client) {
+	c.client = client
+	c.initRPC()
+}
+
+// Protocol возвращает протокол, согласованный с координатором, или nil до первого heartbeat-а.
+func (c *HeartbeatClient) Protocol() *Protocol {
+	return c.protocol.Load()
 }
+
+// SetCredentials задаёт токен воркера, который посылается с каждым heartbeat-ом. Вызывается до первого запроса.
+func (c *HeartbeatClient) SetCredentials(creds auth.Credentials) {
+	c.credentials = creds
+}
+
+func (c *HeartbeatClient) negotiated(peer *Protocol) {
+	if c.protocol.Swap(peer) == nil {
+		c.logger.Info("negotiated protocol with coordinator",
+			zap.Int("version", peer.Version),
+			zap.Any("features", peer.Features))
+	}
+}
+
 func (c *HeartbeatClient) Heartbeat(ctx context.Context, req *HeartbeatRequest) (*HeartbeatResponse, error) {
+	if c.rpc != nil {
+		return c.rpcHeartbeat(ctx, req)
+	}
+
 	reqJSON, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
@@ -34,13 +87,18 @@ func (c *HeartbeatClient) Heartbeat(ctx context.Context, req *HeartbeatRequest)
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	request.Header.Set("Content-Type", "application/json")
+	if err := auth.Authorize(request, c.credentials); err != nil {
+		return nil, err
+	}
 
-	resp, err := c.client.Do(request)
+	resp, peer, err := doNegotiated(c.client, request)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
+	c.negotiated(peer)
+
 	// Check for non-200 status codes
 	if resp.StatusCode != http.StatusOK {
 		errorData, err := io.ReadAll(resp.Body)
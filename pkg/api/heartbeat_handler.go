@@ -3,24 +3,55 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 
 	"go.uber.org/zap"
+
+	"distributed_build/pkg/auth"
 )
 
 type HeartbeatHandler struct {
 	logger  *zap.Logger
 	service HeartbeatService
+	auth    auth.Authenticator
 }
 
 func NewHeartbeatHandler(l *zap.Logger, s HeartbeatService) *HeartbeatHandler {
 	return &HeartbeatHandler{logger: l, service: s}
 }
 
+// RequireAuth включает проверку токенов: heartbeat разрешён только auth.RoleWorker,
+// и WorkerID в запросе должен совпадать с владельцем токена. Вызывается до Register.
+func (h *HeartbeatHandler) RequireAuth(a auth.Authenticator) {
+	h.auth = a
+}
+
+// checkWorkerID не даёт воркеру представиться чужим WorkerID.
+func checkWorkerID(ctx context.Context, workerID WorkerID) error {
+	if id := auth.FromContext(ctx); id != nil && id.Subject != string(workerID) {
+		return fmt.Errorf("%w: %s can not send heartbeats of worker %q", auth.ErrForbidden, id, workerID)
+	}
+	return nil
+}
+
+// Register регистрирует в mux /heartbeat и тот же сервис поверх Connect и gRPC (см. api.proto).
 func (h *HeartbeatHandler) Register(mux *http.ServeMux) {
+	(&rpcHeartbeatHandler{logger: h.logger, service: h.service, auth: h.auth}).register(mux)
+
 	mux.HandleFunc("/heartbeat", func(w http.ResponseWriter, r *http.Request) {
+		r, ok := auth.Check(h.logger, h.auth, w, r, auth.RoleWorker)
+		if !ok {
+			return
+		}
+		r, ok = negotiateRequest(h.logger, w, r)
+		if !ok {
+			return
+		}
+
 		// Read the request body
 		data, err := io.ReadAll(r.Body)
 		if err != nil {
@@ -40,6 +71,13 @@ func (h *HeartbeatHandler) Register(mux *http.ServeMux) {
 			return
 		}
 
+		if err := checkWorkerID(r.Context(), request.WorkerID); err != nil {
+			errorMessage := "unable to authenticate request: " + err.Error()
+			h.logger.Error(errorMessage)
+			http.Error(w, errorMessage, http.StatusForbidden)
+			return
+		}
+
 		// Call the service's Heartbeat method
 		resp, err := h.service.Heartbeat(r.Context(), &request)
 		if err != nil {
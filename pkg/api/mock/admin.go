@@ -0,0 +1,65 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: distributed_build/pkg/api (interfaces: AdminService)
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	api "distributed_build/pkg/api"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockAdminService is a mock of AdminService interface.
+type MockAdminService struct {
+	ctrl     *gomock.Controller
+	recorder *MockAdminServiceMockRecorder
+}
+
+// MockAdminServiceMockRecorder is the mock recorder for MockAdminService.
+type MockAdminServiceMockRecorder struct {
+	mock *MockAdminService
+}
+
+// NewMockAdminService creates a new mock instance.
+func NewMockAdminService(ctrl *gomock.Controller) *MockAdminService {
+	mock := &MockAdminService{ctrl: ctrl}
+	mock.recorder = &MockAdminServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAdminService) EXPECT() *MockAdminServiceMockRecorder {
+	return m.recorder
+}
+
+// ListWorkers mocks base method.
+func (m *MockAdminService) ListWorkers(arg0 context.Context) ([]api.WorkerInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWorkers", arg0)
+	ret0, _ := ret[0].([]api.WorkerInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWorkers indicates an expected call of ListWorkers.
+func (mr *MockAdminServiceMockRecorder) ListWorkers(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWorkers", reflect.TypeOf((*MockAdminService)(nil).ListWorkers), arg0)
+}
+
+// QuarantineWorker mocks base method.
+func (m *MockAdminService) QuarantineWorker(arg0 context.Context, arg1 *api.QuarantineRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QuarantineWorker", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// QuarantineWorker indicates an expected call of QuarantineWorker.
+func (mr *MockAdminServiceMockRecorder) QuarantineWorker(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QuarantineWorker", reflect.TypeOf((*MockAdminService)(nil).QuarantineWorker), arg0, arg1)
+}
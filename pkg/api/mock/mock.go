@@ -63,3 +63,47 @@ func (mr *MockServiceMockRecorder) StartBuild(arg0, arg1, arg2 interface{}) *gom
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartBuild", reflect.TypeOf((*MockService)(nil).StartBuild), arg0, arg1, arg2)
 }
+
+// WatchBuild mocks base method
+func (m *MockService) WatchBuild(arg0 context.Context, arg1 build.ID, arg2 uint64, arg3 api.StatusWriter) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WatchBuild", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WatchBuild indicates an expected call of WatchBuild
+func (mr *MockServiceMockRecorder) WatchBuild(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchBuild", reflect.TypeOf((*MockService)(nil).WatchBuild), arg0, arg1, arg2, arg3)
+}
+
+// ListBuilds mocks base method
+func (m *MockService) ListBuilds(arg0 context.Context) ([]api.BuildInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListBuilds", arg0)
+	ret0, _ := ret[0].([]api.BuildInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListBuilds indicates an expected call of ListBuilds
+func (mr *MockServiceMockRecorder) ListBuilds(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListBuilds", reflect.TypeOf((*MockService)(nil).ListBuilds), arg0)
+}
+
+// GetBuild mocks base method
+func (m *MockService) GetBuild(arg0 context.Context, arg1 build.ID) (*api.BuildInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBuild", arg0, arg1)
+	ret0, _ := ret[0].(*api.BuildInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBuild indicates an expected call of GetBuild
+func (mr *MockServiceMockRecorder) GetBuild(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBuild", reflect.TypeOf((*MockService)(nil).GetBuild), arg0, arg1)
+}
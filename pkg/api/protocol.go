@@ -0,0 +1,234 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"distributed_build/pkg/build"
+)
+
+const (
+	// ProtocolVersion задаёт версию протокола этой сборки кода.
+	//
+	// Версию нужно увеличивать при изменениях, которые старая сторона не может молча проигнорировать,
+	// в том числе когда меняется смысл уже существующих полей:
+	//
+	//   - 1 - стороны, которые не сообщают свою версию;
+	//   - 2 - заголовки версии и возможностей;
+	//   - 3 - команды джобов выполняются в окружении, которое строит воркер, а не в окружении воркера.
+	ProtocolVersion = 3
+
+	// MinProtocolVersion задаёт минимальную версию другой стороны, с которой мы ещё готовы работать.
+	// Воркеров поддерживаются все версии, чтобы их можно было обновлять постепенно: отличия старых
+	// воркеров закрыты через Feature и PeerProtocol. Поднимать MinProtocolVersion нужно, когда поддержку
+	// старой версии убирают.
+	MinProtocolVersion = 1
+
+	// MinClientProtocolVersion задаёт минимальную версию клиента, которому координатор разрешает начинать сборки.
+	// С версии 3 команды джобов выполняются в окружении из build.Cmd, а не в окружении воркера, поэтому граф
+	// клиента младшей версии молча собрался бы не так, как клиент рассчитывал.
+	MinClientProtocolVersion = HermeticProtocolVersion
+
+	// HermeticProtocolVersion - первая версия воркеров, которые выполняют команды в окружении из build.Cmd.
+	// Результаты воркеров младших версий зависят от их окружения, поэтому их нельзя переиспользовать в других сборках.
+	HermeticProtocolVersion = 3
+
+	// legacyProtocolVersion - версия сторон, которые ещё не умеют сообщать свою версию.
+	legacyProtocolVersion = 1
+)
+
+const (
+	protocolVersionHeader  = "X-Distbuild-Protocol"
+	protocolFeaturesHeader = "X-Distbuild-Features"
+)
+
+// Feature описывает необязательную возможность протокола. Новые поля сообщений, которые
+// старая сторона может проигнорировать, должны использоваться только если обе стороны
+// поддерживают соответствующую Feature.
+type Feature string
+
+const (
+	// FeatureJobOutput - HeartbeatRequest.JobOutput и StatusUpdate.JobOutput.
+	FeatureJobOutput Feature = "job_output"
+	// FeatureCancel - сигнал Cancel и HeartbeatResponse.JobsToCancel.
+	FeatureCancel Feature = "cancel"
+	// FeatureReattach - StatusUpdate.Seq и /build/status.
+	FeatureReattach Feature = "reattach"
+	// FeatureLongPoll - HeartbeatRequest.PollTimeout, HeartbeatRequest.Beat и HeartbeatRequest.Received.
+	FeatureLongPoll Feature = "long_poll"
+	// FeatureRetry - JobResult.Failure и StatusUpdate.JobRetried.
+	FeatureRetry Feature = "retry"
+	// FeatureDrain - HeartbeatRequest.Draining, HeartbeatRequest.Leaving и HeartbeatResponse.Drained.
+	FeatureDrain Feature = "drain"
+	// FeatureResources - build.Job.Resources, JobResult.Usage и FailureOOM.
+	FeatureResources Feature = "resources"
+	// FeaturePassEnv - build.Cmd.PassEnv.
+	FeaturePassEnv Feature = "pass_env"
+	// FeatureOutputLog - JobResult.Log.
+	FeatureOutputLog Feature = "output_log"
+	// FeatureOutputs - build.Job.Outputs, build.Job.StrictOutputs и JobResult.Outputs.
+	FeatureOutputs Feature = "outputs"
+)
+
+// JobFeatures возвращает возможности, без которых воркер молча выполнит джоб не так, как он описан:
+// без лимитов, без проверки выходов или без переменных из PassEnv. Такие джобы отдаются только воркерам
+// со всеми этими возможностями.
+func JobFeatures(job *build.Job) []Feature {
+	var features []Feature
+	if job.Resources != (build.Resources{}) {
+		features = append(features, FeatureResources)
+	}
+	if len(job.Outputs) != 0 || job.StrictOutputs {
+		features = append(features, FeatureOutputs)
+	}
+	for _, cmd := range job.Cmds {
+		if len(cmd.PassEnv) != 0 {
+			features = append(features, FeaturePassEnv)
+			break
+		}
+	}
+	return features
+}
+
+// SupportedFeatures перечисляет возможности, которые поддерживает эта сборка кода.
+var SupportedFeatures = []Feature{
+	FeatureJobOutput,
+	FeatureCancel,
+	FeatureReattach,
+	FeatureLongPoll,
+	FeatureRetry,
+	FeatureDrain,
+	FeatureResources,
+	FeaturePassEnv,
+	FeatureOutputLog,
+	FeatureOutputs,
+}
+
+var ErrIncompatibleProtocol = errors.New("incompatible protocol version")
+
+// Protocol описывает версию протокола и набор возможностей.
+type Protocol struct {
+	Version  int
+	Features []Feature
+}
+
+func LocalProtocol() *Protocol {
+	return &Protocol{Version: ProtocolVersion, Features: SupportedFeatures}
+}
+
+func (p *Protocol) Has(f Feature) bool {
+	return p != nil && slices.Contains(p.Features, f)
+}
+
+// Negotiate проверяет, что с другой стороной можно работать, и возвращает общую версию
+// протокола и общий набор возможностей.
+func Negotiate(peer *Protocol) (*Protocol, error) {
+	if peer.Version < MinProtocolVersion {
+		return nil, fmt.Errorf("%w: peer speaks version %d, minimum supported version is %d",
+			ErrIncompatibleProtocol, peer.Version, MinProtocolVersion)
+	}
+
+	negotiated := &Protocol{Version: min(peer.Version, ProtocolVersion)}
+	for _, f := range SupportedFeatures {
+		if slices.Contains(peer.Features, f) {
+			negotiated.Features = append(negotiated.Features, f)
+		}
+	}
+	return negotiated, nil
+}
+
+// CheckClientProtocol возвращает ошибку, обёрнутую в ErrIncompatibleProtocol, если клиенту с протоколом peer
+// нельзя начинать сборки, см. MinClientProtocolVersion.
+func CheckClientProtocol(peer *Protocol) error {
+	if peer.Version < MinClientProtocolVersion {
+		return fmt.Errorf("%w: client speaks version %d, builds require version %d",
+			ErrIncompatibleProtocol, peer.Version, MinClientProtocolVersion)
+	}
+	return nil
+}
+
+func setProtocolHeaders(h http.Header) {
+	h.Set(protocolVersionHeader, strconv.Itoa(ProtocolVersion))
+
+	features := make([]string, 0, len(SupportedFeatures))
+	for _, f := range SupportedFeatures {
+		features = append(features, string(f))
+	}
+	h.Set(protocolFeaturesHeader, strings.Join(features, ","))
+}
+
+// negotiateHeaders читает версию другой стороны из заголовков и договаривается с ней.
+// Сторона без заголовков считается старой, не поддерживающей ни одной Feature.
+func negotiateHeaders(h http.Header) (*Protocol, error) {
+	peer := &Protocol{Version: legacyProtocolVersion}
+
+	if version := h.Get(protocolVersionHeader); version != "" {
+		var err error
+		if peer.Version, err = strconv.Atoi(version); err != nil {
+			return nil, fmt.Errorf("%w: invalid version %q", ErrIncompatibleProtocol, version)
+		}
+	}
+
+	if features := h.Get(protocolFeaturesHeader); features != "" {
+		for _, f := range strings.Split(features, ",") {
+			peer.Features = append(peer.Features, Feature(strings.TrimSpace(f)))
+		}
+	}
+
+	return Negotiate(peer)
+}
+
+type protocolKey struct{}
+
+// WithPeerProtocol сохраняет в контексте протокол, согласованный с другой стороной.
+func WithPeerProtocol(ctx context.Context, p *Protocol) context.Context {
+	return context.WithValue(ctx, protocolKey{}, p)
+}
+
+// PeerProtocol возвращает протокол, согласованный с воркером или клиентом, от которого пришёл запрос.
+//
+// Хендлеры кладут его в контекст запроса перед вызовом Service и HeartbeatService. Сервис должен
+// пользоваться новыми возможностями, только если PeerProtocol(ctx).Has(feature).
+func PeerProtocol(ctx context.Context) *Protocol {
+	p, _ := ctx.Value(protocolKey{}).(*Protocol)
+	return p
+}
+
+// negotiateRequest договаривается о протоколе с отправителем запроса и кладёт результат
+// в контекст запроса. Если договориться не удалось, отвечает ошибкой и возвращает false.
+func negotiateRequest(l *zap.Logger, w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	setProtocolHeaders(w.Header())
+
+	peer, err := negotiateHeaders(r.Header)
+	if err != nil {
+		errorMessage := "unable to negotiate protocol: " + err.Error()
+		l.Error(errorMessage)
+		http.Error(w, errorMessage, http.StatusBadRequest)
+		return nil, false
+	}
+	return r.WithContext(WithPeerProtocol(r.Context(), peer)), true
+}
+
+// doNegotiated выполняет запрос, сообщая серверу нашу версию протокола, и проверяет версию сервера.
+func doNegotiated(client *http.Client, req *http.Request) (*http.Response, *Protocol, error) {
+	setProtocolHeaders(req.Header)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	peer, err := negotiateHeaders(resp.Header)
+	if err != nil {
+		resp.Body.Close()
+		return nil, nil, err
+	}
+	return resp, peer, nil
+}
@@ -0,0 +1,140 @@
+package api_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/api/mock"
+	"distributed_build/pkg/build"
+)
+
+func TestNegotiate(t *testing.T) {
+	p, err := api.Negotiate(api.LocalProtocol())
+	require.NoError(t, err)
+	require.Equal(t, api.LocalProtocol(), p)
+
+	p, err = api.Negotiate(&api.Protocol{Version: 1})
+	require.NoError(t, err)
+	require.Equal(t, &api.Protocol{Version: 1}, p)
+	require.False(t, p.Has(api.FeatureCancel))
+
+	// Воркер версии 2 выполняет команды в своём окружении: по согласованной версии это видно.
+	p, err = api.Negotiate(&api.Protocol{Version: 2, Features: []api.Feature{api.FeatureCancel}})
+	require.NoError(t, err)
+	require.Equal(t, &api.Protocol{Version: 2, Features: []api.Feature{api.FeatureCancel}}, p)
+
+	p, err = api.Negotiate(&api.Protocol{Version: api.ProtocolVersion + 1, Features: []api.Feature{"teleport", api.FeatureCancel}})
+	require.NoError(t, err)
+	require.Equal(t, &api.Protocol{Version: api.ProtocolVersion, Features: []api.Feature{api.FeatureCancel}}, p)
+
+	_, err = api.Negotiate(&api.Protocol{Version: 0})
+	require.ErrorIs(t, err, api.ErrIncompatibleProtocol)
+}
+
+func TestJobFeatures(t *testing.T) {
+	require.Empty(t, api.JobFeatures(&build.Job{Cmds: []build.Cmd{{Exec: []string{"true"}}}}))
+
+	job := &build.Job{
+		Cmds:      []build.Cmd{{Exec: []string{"go", "test"}, PassEnv: []string{"HTTP_PROXY"}}},
+		Resources: build.Resources{CPU: 1},
+		Outputs:   []string{"lib.a"},
+	}
+	require.ElementsMatch(t, []api.Feature{api.FeatureResources, api.FeatureOutputs, api.FeaturePassEnv}, api.JobFeatures(job))
+	require.Equal(t, []api.Feature{api.FeatureOutputs}, api.JobFeatures(&build.Job{StrictOutputs: true}))
+}
+
+func TestHeartbeatProtocol(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	l := zaptest.NewLogger(t)
+	m := mock.NewMockHeartbeatService(ctrl)
+	mux := http.NewServeMux()
+	api.NewHeartbeatHandler(l, m).Register(mux)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var peers []*api.Protocol
+	m.EXPECT().Heartbeat(gomock.Any(), gomock.Any()).Times(2).
+		DoAndReturn(func(ctx context.Context, req *api.HeartbeatRequest) (*api.HeartbeatResponse, error) {
+			peers = append(peers, api.PeerProtocol(ctx))
+			return &api.HeartbeatResponse{}, nil
+		})
+
+	client := api.NewHeartbeatClient(l, server.URL)
+	require.Nil(t, client.Protocol())
+
+	_, err := client.Heartbeat(context.Background(), &api.HeartbeatRequest{WorkerID: "worker0"})
+	require.NoError(t, err)
+	require.Equal(t, api.LocalProtocol(), client.Protocol())
+
+	post := func(version string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/heartbeat", strings.NewReader(`{"worker_id": "worker1"}`))
+		require.NoError(t, err)
+		if version != "" {
+			req.Header.Set("X-Distbuild-Protocol", version)
+		}
+
+		rsp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return rsp
+	}
+
+	// Old workers do not send any protocol headers. They are still accepted, see TestBuildProtocol for clients.
+	rsp := post("")
+	rsp.Body.Close()
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+
+	rsp = post("0")
+	defer rsp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+	body, err := io.ReadAll(rsp.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), "incompatible protocol version")
+
+	require.Equal(t, []*api.Protocol{api.LocalProtocol(), {Version: 1}}, peers)
+	require.True(t, peers[0].Has(api.FeatureJobOutput))
+	require.False(t, peers[1].Has(api.FeatureJobOutput))
+}
+
+func TestBuildProtocol(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	m := mock.NewMockService(ctrl)
+	mux := http.NewServeMux()
+	api.NewBuildService(zaptest.NewLogger(t), m).Register(mux)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	post := func(version string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/build", strings.NewReader(`{}`))
+		require.NoError(t, err)
+		if version != "" {
+			req.Header.Set("X-Distbuild-Protocol", version)
+		}
+
+		rsp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return rsp
+	}
+
+	// Клиенты без заголовков и клиенты версии 2 строят графы под окружение воркера, сборку они не начинают.
+	for _, version := range []string{"", "2"} {
+		rsp := post(version)
+		body, err := io.ReadAll(rsp.Body)
+		rsp.Body.Close()
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+		require.Contains(t, string(body), "incompatible protocol version")
+	}
+}
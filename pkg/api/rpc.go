@@ -0,0 +1,222 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"connectrpc.com/connect"
+	"golang.org/x/net/http2"
+
+	"distributed_build/pkg/auth"
+)
+
+//go:generate buf generate
+
+const (
+	// ConnectScheme - префикс endpoint-а, выбирающий Connect поверх HTTP вместо JSON API.
+	ConnectScheme = "connect+"
+	// GRPCScheme - префикс endpoint-а, выбирающий gRPC. Без TLS используется HTTP/2 без шифрования (h2c).
+	GRPCScheme = "grpc+"
+)
+
+// HTTPEndpoint убирает из endpoint-а префикс транспорта.
+//
+// Этим адресом нужно пользоваться для запросов, которые всегда идут по HTTP, например для заливки файлов.
+func HTTPEndpoint(endpoint string) string {
+	endpoint = strings.TrimPrefix(endpoint, ConnectScheme)
+	return strings.TrimPrefix(endpoint, GRPCScheme)
+}
+
+// rpcEndpoint разбирает endpoint с префиксом транспорта. Для endpoint-ов без префикса возвращает false,
+// такие клиенты ходят в JSON API.
+//
+// Для https используется client. gRPC поверх https требует HTTP/2, поэтому транспорт client должен его поддерживать.
+func rpcEndpoint(endpoint string, client *http.Client) (connect.HTTPClient, string, []connect.ClientOption, bool) {
+	switch {
+	case strings.HasPrefix(endpoint, ConnectScheme):
+		return client, HTTPEndpoint(endpoint), nil, true
+
+	case strings.HasPrefix(endpoint, GRPCScheme):
+		baseURL := HTTPEndpoint(endpoint)
+		opts := []connect.ClientOption{connect.WithGRPC()}
+		if strings.HasPrefix(baseURL, "https://") {
+			return client, baseURL, opts, true
+		}
+
+		h2c := &http.Client{
+			Transport: &http2.Transport{
+				AllowHTTP: true,
+				DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, network, addr)
+				},
+			},
+		}
+		return h2c, baseURL, opts, true
+
+	default:
+		return nil, "", nil, false
+	}
+}
+
+// protocolInterceptor договаривается о версии протокола через те же заголовки, что и JSON API.
+//
+// На стороне сервера согласованный протокол кладётся в контекст, как это делает negotiateRequest.
+// На стороне клиента он передаётся в negotiated.
+type protocolInterceptor struct {
+	negotiated func(peer *Protocol)
+}
+
+func (i *protocolInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			setProtocolHeaders(req.Header())
+
+			rsp, err := next(ctx, req)
+			if err != nil {
+				return nil, err
+			}
+
+			peer, err := negotiateHeaders(rsp.Header())
+			if err != nil {
+				return nil, err
+			}
+			i.negotiated(peer)
+			return rsp, nil
+		}
+
+		peer, err := negotiateHeaders(req.Header())
+		if err != nil {
+			return nil, connect.NewError(connect.CodeFailedPrecondition, err)
+		}
+
+		rsp, err := next(WithPeerProtocol(ctx, peer), req)
+		if err != nil {
+			return nil, err
+		}
+		setProtocolHeaders(rsp.Header())
+		return rsp, nil
+	}
+}
+
+func (i *protocolInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		conn := next(ctx, spec)
+		setProtocolHeaders(conn.RequestHeader())
+		return &negotiatingClientConn{StreamingClientConn: conn, negotiated: i.negotiated}
+	}
+}
+
+func (i *protocolInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		peer, err := negotiateHeaders(conn.RequestHeader())
+		if err != nil {
+			return connect.NewError(connect.CodeFailedPrecondition, err)
+		}
+
+		setProtocolHeaders(conn.ResponseHeader())
+		return next(WithPeerProtocol(ctx, peer), conn)
+	}
+}
+
+// negotiatingClientConn проверяет версию сервера, когда из потока приходит первое сообщение.
+type negotiatingClientConn struct {
+	connect.StreamingClientConn
+	negotiated func(peer *Protocol)
+	done       bool
+}
+
+func (c *negotiatingClientConn) Receive(msg any) error {
+	if err := c.StreamingClientConn.Receive(msg); err != nil {
+		return err
+	}
+	if c.done {
+		return nil
+	}
+
+	c.done = true
+	peer, err := negotiateHeaders(c.ResponseHeader())
+	if err != nil {
+		return err
+	}
+	c.negotiated(peer)
+	return nil
+}
+
+// authInterceptor проверяет токены так же, как auth.Check в JSON API.
+//
+// На стороне сервера пропускает только роли roles и кладёт владельца токена или клиентского
+// сертификата в контекст. Состояние TLS соединения в контекст кладёт auth.TLSContext.
+// На стороне клиента записывает в запрос токен из credentials.
+type authInterceptor struct {
+	auth  auth.Authenticator
+	roles []auth.Role
+
+	credentials func() auth.Credentials
+}
+
+func (i *authInterceptor) authenticate(ctx context.Context, h http.Header) (context.Context, error) {
+	id, err := auth.Authenticate(ctx, i.auth, h, i.roles...)
+	if errors.Is(err, auth.ErrForbidden) {
+		return nil, connect.NewError(connect.CodePermissionDenied, err)
+	} else if err != nil {
+		return nil, connect.NewError(connect.CodeUnauthenticated, err)
+	} else if id == nil {
+		return ctx, nil
+	}
+	return auth.WithIdentity(ctx, id), nil
+}
+
+func (i *authInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			if err := auth.SetHeader(req.Header(), i.credentials()); err != nil {
+				return nil, err
+			}
+			return next(ctx, req)
+		}
+
+		ctx, err := i.authenticate(ctx, req.Header())
+		if err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+func (i *authInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		conn := next(ctx, spec)
+		if err := auth.SetHeader(conn.RequestHeader(), i.credentials()); err != nil {
+			// Вернуть ошибку отсюда некуда, поэтому её вернёт первый Send или Receive, и запрос без токена не уйдёт.
+			return &failedClientConn{StreamingClientConn: conn, err: err}
+		}
+		return conn
+	}
+}
+
+// failedClientConn - поток, который не удалось начать. Все операции над потоком возвращают err,
+// а запрос на сервер не отправляется.
+type failedClientConn struct {
+	connect.StreamingClientConn
+	err error
+}
+
+func (c *failedClientConn) Send(msg any) error    { return c.err }
+func (c *failedClientConn) Receive(msg any) error { return c.err }
+func (c *failedClientConn) CloseRequest() error   { return c.err }
+func (c *failedClientConn) CloseResponse() error  { return c.err }
+
+func (i *authInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		ctx, err := i.authenticate(ctx, conn.RequestHeader())
+		if err != nil {
+			return err
+		}
+		return next(ctx, conn)
+	}
+}
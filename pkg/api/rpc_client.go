@@ -0,0 +1,142 @@
+//go:build !solution
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"connectrpc.com/connect"
+
+	"distributed_build/pkg/api/apipb"
+	"distributed_build/pkg/build"
+)
+
+func (c *BuildClient) rpcStartBuild(ctx context.Context, request *BuildRequest) (*BuildStarted, StatusReader, error) {
+	req := connect.NewRequest(&apipb.BuildRequest{Graph: graphToProto(&request.Graph), Priority: int32(request.Priority)})
+	stream, err := c.rpc.StartBuild(ctx, req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	if !stream.Receive() {
+		err := stream.Err()
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		stream.Close()
+		if connect.CodeOf(err) == connect.CodeResourceExhausted {
+			return nil, nil, fmt.Errorf("%w: %w", ErrQuotaExceeded, err)
+		}
+		return nil, nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	buildStarted, err := buildStartedFromProto(stream.Msg().GetStarted())
+	if err != nil {
+		stream.Close()
+		return nil, nil, fmt.Errorf("failed to decode build started response: %w", err)
+	}
+
+	sr := &statusReader{
+		ctx:     ctx,
+		client:  c,
+		buildID: buildStarted.ID,
+		stream: &rpcStatusStream[apipb.StartBuildResponse]{
+			stream: stream,
+			update: (*apipb.StartBuildResponse).GetUpdate,
+		},
+	}
+	return buildStarted, sr, nil
+}
+
+func (c *BuildClient) rpcWatch(ctx context.Context, buildID build.ID, fromSeq uint64) (statusStream, error) {
+	req := connect.NewRequest(&apipb.WatchBuildRequest{BuildId: idToProto(buildID), FromSeq: fromSeq})
+	stream, err := c.rpc.WatchBuild(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("status request failed: %w", err)
+	}
+
+	return &rpcStatusStream[apipb.StatusUpdate]{
+		stream: stream,
+		update: func(u *apipb.StatusUpdate) *apipb.StatusUpdate { return u },
+	}, nil
+}
+
+func (c *BuildClient) rpcSignalBuild(ctx context.Context, buildID build.ID, signal *SignalRequest) (*SignalResponse, error) {
+	_, err := c.rpc.SignalBuild(ctx, connect.NewRequest(signalRequestToProto(buildID, signal)))
+	if err != nil {
+		return nil, fmt.Errorf("signal request failed: %w", err)
+	}
+	return &SignalResponse{}, nil
+}
+
+func (c *BuildClient) rpcListBuilds(ctx context.Context) ([]BuildInfo, error) {
+	rsp, err := c.rpc.ListBuilds(ctx, connect.NewRequest(&apipb.ListBuildsRequest{}))
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	var builds []BuildInfo
+	for _, b := range rsp.Msg.GetBuilds() {
+		info, err := buildInfoFromProto(b)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		builds = append(builds, *info)
+	}
+	return builds, nil
+}
+
+func (c *BuildClient) rpcGetBuild(ctx context.Context, buildID build.ID) (*BuildInfo, error) {
+	rsp, err := c.rpc.GetBuild(ctx, connect.NewRequest(&apipb.GetBuildRequest{BuildId: idToProto(buildID)}))
+	if connect.CodeOf(err) == connect.CodeNotFound {
+		return nil, fmt.Errorf("%w: %w", ErrBuildNotFound, err)
+	} else if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	info, err := buildInfoFromProto(rsp.Msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return info, nil
+}
+
+// rpcStatusStream читает обновления статуса из потока StartBuild или WatchBuild.
+type rpcStatusStream[T any] struct {
+	stream *connect.ServerStreamForClient[T]
+	update func(msg *T) *apipb.StatusUpdate
+}
+
+func (s *rpcStatusStream[T]) next() (*StatusUpdate, error) {
+	if !s.stream.Receive() {
+		if err := s.stream.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+
+	update := s.update(s.stream.Msg())
+	if update == nil {
+		return nil, fmt.Errorf("unexpected message in status stream")
+	}
+	return statusUpdateFromProto(update)
+}
+
+func (s *rpcStatusStream[T]) Close() error {
+	return s.stream.Close()
+}
+
+func (c *HeartbeatClient) rpcHeartbeat(ctx context.Context, req *HeartbeatRequest) (*HeartbeatResponse, error) {
+	rsp, err := c.rpc.Heartbeat(ctx, connect.NewRequest(heartbeatRequestToProto(req)))
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	heartbeatResponse, err := heartbeatResponseFromProto(rsp.Msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return heartbeatResponse, nil
+}
@@ -0,0 +1,600 @@
+package api
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"distributed_build/pkg/api/apipb"
+	"distributed_build/pkg/build"
+)
+
+// Функции в этом файле переводят типы пакета api в сообщения apipb и обратно.
+//
+// Пустые списки и словари переводятся в nil, так же как при разборе JSON без этих полей.
+
+func idToProto(id build.ID) []byte {
+	return id[:]
+}
+
+func idFromProto(b []byte) (build.ID, error) {
+	var id build.ID
+	if len(b) != len(id) {
+		return id, fmt.Errorf("invalid id size: %d", len(b))
+	}
+	copy(id[:], b)
+	return id, nil
+}
+
+func idsToProto(ids []build.ID) [][]byte {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	out := make([][]byte, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, idToProto(id))
+	}
+	return out
+}
+
+func idsFromProto(bs [][]byte) ([]build.ID, error) {
+	if len(bs) == 0 {
+		return nil, nil
+	}
+
+	out := make([]build.ID, 0, len(bs))
+	for _, b := range bs {
+		id, err := idFromProto(b)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, nil
+}
+
+func idMapToProto[V any, P any](m map[build.ID]V, convert func(V) P) map[string]P {
+	if len(m) == 0 {
+		return nil
+	}
+
+	out := make(map[string]P, len(m))
+	for id, v := range m {
+		out[id.String()] = convert(v)
+	}
+	return out
+}
+
+func idMapFromProto[P any, V any](m map[string]P, convert func(P) (V, error)) (map[build.ID]V, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+
+	out := make(map[build.ID]V, len(m))
+	for key, p := range m {
+		var id build.ID
+		if err := id.UnmarshalText([]byte(key)); err != nil {
+			return nil, err
+		}
+
+		v, err := convert(p)
+		if err != nil {
+			return nil, err
+		}
+		out[id] = v
+	}
+	return out, nil
+}
+
+func identity[T any](v T) T {
+	return v
+}
+
+func identityErr[T any](v T) (T, error) {
+	return v, nil
+}
+
+func cmdToProto(cmd build.Cmd) *apipb.Cmd {
+	return &apipb.Cmd{
+		Exec:             cmd.Exec,
+		Environ:          cmd.Environ,
+		WorkingDirectory: cmd.WorkingDirectory,
+		CatTemplate:      cmd.CatTemplate,
+		CatOutput:        cmd.CatOutput,
+		PassEnv:          cmd.PassEnv,
+	}
+}
+
+func cmdFromProto(cmd *apipb.Cmd) build.Cmd {
+	return build.Cmd{
+		Exec:             cmd.GetExec(),
+		Environ:          cmd.GetEnviron(),
+		WorkingDirectory: cmd.GetWorkingDirectory(),
+		CatTemplate:      cmd.GetCatTemplate(),
+		CatOutput:        cmd.GetCatOutput(),
+		PassEnv:          cmd.GetPassEnv(),
+	}
+}
+
+func jobToProto(job *build.Job) *apipb.Job {
+	out := &apipb.Job{
+		Id:     idToProto(job.ID),
+		Name:   job.Name,
+		Inputs: job.Inputs,
+		Deps:   idsToProto(job.Deps),
+
+		Retryable:     job.Retryable,
+		Outputs:       job.Outputs,
+		StrictOutputs: job.StrictOutputs,
+	}
+	if job.Resources != (build.Resources{}) {
+		out.Resources = &apipb.Resources{Cpu: job.Resources.CPU, Memory: job.Resources.Memory}
+	}
+	for _, cmd := range job.Cmds {
+		out.Cmds = append(out.Cmds, cmdToProto(cmd))
+	}
+	return out
+}
+
+func jobFromProto(job *apipb.Job) (build.Job, error) {
+	id, err := idFromProto(job.GetId())
+	if err != nil {
+		return build.Job{}, fmt.Errorf("job id: %w", err)
+	}
+	deps, err := idsFromProto(job.GetDeps())
+	if err != nil {
+		return build.Job{}, fmt.Errorf("job deps: %w", err)
+	}
+
+	out := build.Job{
+		ID:     id,
+		Name:   job.GetName(),
+		Inputs: job.GetInputs(),
+		Deps:   deps,
+
+		Retryable:     job.GetRetryable(),
+		Resources:     build.Resources{CPU: job.GetResources().GetCpu(), Memory: job.GetResources().GetMemory()},
+		Outputs:       job.GetOutputs(),
+		StrictOutputs: job.GetStrictOutputs(),
+	}
+	for _, cmd := range job.GetCmds() {
+		out.Cmds = append(out.Cmds, cmdFromProto(cmd))
+	}
+	return out, nil
+}
+
+func graphToProto(graph *build.Graph) *apipb.Graph {
+	out := &apipb.Graph{SourceFiles: idMapToProto(graph.SourceFiles, identity[string])}
+	for i := range graph.Jobs {
+		out.Jobs = append(out.Jobs, jobToProto(&graph.Jobs[i]))
+	}
+	return out
+}
+
+func graphFromProto(graph *apipb.Graph) (build.Graph, error) {
+	sourceFiles, err := idMapFromProto(graph.GetSourceFiles(), identityErr[string])
+	if err != nil {
+		return build.Graph{}, fmt.Errorf("source files: %w", err)
+	}
+
+	out := build.Graph{SourceFiles: sourceFiles}
+	for _, job := range graph.GetJobs() {
+		j, err := jobFromProto(job)
+		if err != nil {
+			return build.Graph{}, err
+		}
+		out.Jobs = append(out.Jobs, j)
+	}
+	return out, nil
+}
+
+func jobResultToProto(res *JobResult) *apipb.JobResult {
+	if res == nil {
+		return nil
+	}
+	return &apipb.JobResult{
+		Id:       idToProto(res.ID),
+		Stdout:   res.Stdout,
+		Stderr:   res.Stderr,
+		ExitCode: int32(res.ExitCode),
+		Error:    res.Error,
+		Failure:  string(res.Failure),
+		Usage:    resourceUsageToProto(res.Usage),
+		Log:      outputLogToProto(res.Log),
+		Outputs:  outputFilesToProto(res.Outputs),
+	}
+}
+
+func outputFilesToProto(files []OutputFile) []*apipb.OutputFile {
+	var out []*apipb.OutputFile
+	for _, f := range files {
+		out = append(out, &apipb.OutputFile{Path: f.Path, Size: f.Size, Digest: f.Digest, Symlink: f.Symlink})
+	}
+	return out
+}
+
+func outputFilesFromProto(files []*apipb.OutputFile) []OutputFile {
+	var out []OutputFile
+	for _, f := range files {
+		out = append(out, OutputFile{Path: f.GetPath(), Size: f.GetSize(), Digest: f.GetDigest(), Symlink: f.GetSymlink()})
+	}
+	return out
+}
+
+func outputLogToProto(l *OutputLog) *apipb.OutputLog {
+	if l == nil {
+		return nil
+	}
+	return &apipb.OutputLog{
+		WorkerId:   string(l.Worker),
+		ArtifactId: idToProto(l.ArtifactID),
+		StdoutSize: l.StdoutSize,
+		StderrSize: l.StderrSize,
+	}
+}
+
+func outputLogFromProto(l *apipb.OutputLog) (*OutputLog, error) {
+	if l == nil {
+		return nil, nil
+	}
+
+	id, err := idFromProto(l.GetArtifactId())
+	if err != nil {
+		return nil, fmt.Errorf("log artifact id: %w", err)
+	}
+	return &OutputLog{
+		Worker:     WorkerID(l.GetWorkerId()),
+		ArtifactID: id,
+		StdoutSize: l.GetStdoutSize(),
+		StderrSize: l.GetStderrSize(),
+	}, nil
+}
+
+func resourceUsageToProto(u *ResourceUsage) *apipb.ResourceUsage {
+	if u == nil {
+		return nil
+	}
+	return &apipb.ResourceUsage{
+		PeakMemory: u.PeakMemory,
+		CpuTime:    durationpb.New(u.CPUTime),
+		ReadBytes:  u.ReadBytes,
+		WriteBytes: u.WriteBytes,
+	}
+}
+
+func resourceUsageFromProto(u *apipb.ResourceUsage) *ResourceUsage {
+	if u == nil {
+		return nil
+	}
+	return &ResourceUsage{
+		PeakMemory: u.GetPeakMemory(),
+		CPUTime:    u.GetCpuTime().AsDuration(),
+		ReadBytes:  u.GetReadBytes(),
+		WriteBytes: u.GetWriteBytes(),
+	}
+}
+
+func jobResultFromProto(res *apipb.JobResult) (*JobResult, error) {
+	if res == nil {
+		return nil, nil
+	}
+
+	id, err := idFromProto(res.GetId())
+	if err != nil {
+		return nil, fmt.Errorf("job result id: %w", err)
+	}
+	log, err := outputLogFromProto(res.GetLog())
+	if err != nil {
+		return nil, err
+	}
+	return &JobResult{
+		ID:       id,
+		Stdout:   res.GetStdout(),
+		Stderr:   res.GetStderr(),
+		ExitCode: int(res.GetExitCode()),
+		Error:    res.Error,
+		Failure:  FailureKind(res.GetFailure()),
+		Usage:    resourceUsageFromProto(res.GetUsage()),
+		Log:      log,
+		Outputs:  outputFilesFromProto(res.GetOutputs()),
+	}, nil
+}
+
+func jobOutputToProto(o *JobOutput) *apipb.JobOutput {
+	if o == nil {
+		return nil
+	}
+	return &apipb.JobOutput{Id: idToProto(o.ID), Stdout: o.Stdout, Stderr: o.Stderr}
+}
+
+func jobOutputFromProto(o *apipb.JobOutput) (*JobOutput, error) {
+	if o == nil {
+		return nil, nil
+	}
+
+	id, err := idFromProto(o.GetId())
+	if err != nil {
+		return nil, fmt.Errorf("job output id: %w", err)
+	}
+	return &JobOutput{ID: id, Stdout: o.GetStdout(), Stderr: o.GetStderr()}, nil
+}
+
+func jobSpecToProto(spec JobSpec) *apipb.JobSpec {
+	return &apipb.JobSpec{
+		SourceFiles: idMapToProto(spec.SourceFiles, identity[string]),
+		Artifacts:   idMapToProto(spec.Artifacts, WorkerID.String),
+		Job:         jobToProto(&spec.Job),
+	}
+}
+
+func jobSpecFromProto(spec *apipb.JobSpec) (JobSpec, error) {
+	sourceFiles, err := idMapFromProto(spec.GetSourceFiles(), identityErr[string])
+	if err != nil {
+		return JobSpec{}, fmt.Errorf("source files: %w", err)
+	}
+	artifacts, err := idMapFromProto(spec.GetArtifacts(), func(w string) (WorkerID, error) {
+		return WorkerID(w), nil
+	})
+	if err != nil {
+		return JobSpec{}, fmt.Errorf("artifacts: %w", err)
+	}
+	job, err := jobFromProto(spec.GetJob())
+	if err != nil {
+		return JobSpec{}, err
+	}
+	return JobSpec{SourceFiles: sourceFiles, Artifacts: artifacts, Job: job}, nil
+}
+
+func heartbeatRequestToProto(req *HeartbeatRequest) *apipb.HeartbeatRequest {
+	out := &apipb.HeartbeatRequest{
+		WorkerId:       string(req.WorkerID),
+		RunningJobs:    idsToProto(req.RunningJobs),
+		FreeSlots:      int32(req.FreeSlots),
+		AddedArtifacts: idsToProto(req.AddedArtifacts),
+		Draining:       req.Draining,
+		Leaving:        req.Leaving,
+		Beat:           req.Beat,
+		Received:       req.Received,
+	}
+	if req.PollTimeout != 0 {
+		out.PollTimeout = durationpb.New(req.PollTimeout)
+	}
+	for i := range req.FinishedJob {
+		out.FinishedJobs = append(out.FinishedJobs, jobResultToProto(&req.FinishedJob[i]))
+	}
+	for i := range req.JobOutput {
+		out.JobOutput = append(out.JobOutput, jobOutputToProto(&req.JobOutput[i]))
+	}
+	return out
+}
+
+func heartbeatRequestFromProto(req *apipb.HeartbeatRequest) (*HeartbeatRequest, error) {
+	runningJobs, err := idsFromProto(req.GetRunningJobs())
+	if err != nil {
+		return nil, fmt.Errorf("running jobs: %w", err)
+	}
+	addedArtifacts, err := idsFromProto(req.GetAddedArtifacts())
+	if err != nil {
+		return nil, fmt.Errorf("added artifacts: %w", err)
+	}
+
+	out := &HeartbeatRequest{
+		WorkerID:       WorkerID(req.GetWorkerId()),
+		RunningJobs:    runningJobs,
+		FreeSlots:      int(req.GetFreeSlots()),
+		AddedArtifacts: addedArtifacts,
+		PollTimeout:    req.GetPollTimeout().AsDuration(),
+		Draining:       req.GetDraining(),
+		Leaving:        req.GetLeaving(),
+		Beat:           req.GetBeat(),
+		Received:       req.GetReceived(),
+	}
+	for _, res := range req.GetFinishedJobs() {
+		r, err := jobResultFromProto(res)
+		if err != nil {
+			return nil, err
+		}
+		out.FinishedJob = append(out.FinishedJob, *r)
+	}
+	for _, o := range req.GetJobOutput() {
+		chunk, err := jobOutputFromProto(o)
+		if err != nil {
+			return nil, err
+		}
+		out.JobOutput = append(out.JobOutput, *chunk)
+	}
+	return out, nil
+}
+
+func heartbeatResponseToProto(rsp *HeartbeatResponse) *apipb.HeartbeatResponse {
+	return &apipb.HeartbeatResponse{
+		JobsToRun:    idMapToProto(rsp.JobsToRun, jobSpecToProto),
+		JobsToCancel: idsToProto(rsp.JobsToCancel),
+		Drained:      rsp.Drained,
+	}
+}
+
+func heartbeatResponseFromProto(rsp *apipb.HeartbeatResponse) (*HeartbeatResponse, error) {
+	jobsToRun, err := idMapFromProto(rsp.GetJobsToRun(), jobSpecFromProto)
+	if err != nil {
+		return nil, fmt.Errorf("jobs to run: %w", err)
+	}
+	jobsToCancel, err := idsFromProto(rsp.GetJobsToCancel())
+	if err != nil {
+		return nil, fmt.Errorf("jobs to cancel: %w", err)
+	}
+	return &HeartbeatResponse{JobsToRun: jobsToRun, JobsToCancel: jobsToCancel, Drained: rsp.GetDrained()}, nil
+}
+
+func buildStartedToProto(rsp *BuildStarted) *apipb.BuildStarted {
+	return &apipb.BuildStarted{Id: idToProto(rsp.ID), MissingFiles: idsToProto(rsp.MissingFiles)}
+}
+
+func buildStartedFromProto(rsp *apipb.BuildStarted) (*BuildStarted, error) {
+	id, err := idFromProto(rsp.GetId())
+	if err != nil {
+		return nil, fmt.Errorf("build id: %w", err)
+	}
+	missingFiles, err := idsFromProto(rsp.GetMissingFiles())
+	if err != nil {
+		return nil, fmt.Errorf("missing files: %w", err)
+	}
+	return &BuildStarted{ID: id, MissingFiles: missingFiles}, nil
+}
+
+func jobRetryToProto(r *JobRetry) *apipb.JobRetry {
+	if r == nil {
+		return nil
+	}
+	return &apipb.JobRetry{
+		Id:      idToProto(r.ID),
+		Attempt: int32(r.Attempt),
+		Worker:  r.Worker.String(),
+		Failure: string(r.Failure),
+		Error:   r.Error,
+	}
+}
+
+func jobRetryFromProto(r *apipb.JobRetry) (*JobRetry, error) {
+	if r == nil {
+		return nil, nil
+	}
+
+	id, err := idFromProto(r.GetId())
+	if err != nil {
+		return nil, fmt.Errorf("job retry id: %w", err)
+	}
+	return &JobRetry{
+		ID:      id,
+		Attempt: int(r.GetAttempt()),
+		Worker:  WorkerID(r.GetWorker()),
+		Failure: FailureKind(r.GetFailure()),
+		Error:   r.GetError(),
+	}, nil
+}
+
+func statusUpdateToProto(update *StatusUpdate) *apipb.StatusUpdate {
+	out := &apipb.StatusUpdate{
+		Seq:         update.Seq,
+		JobOutput:   jobOutputToProto(update.JobOutput),
+		JobRetried:  jobRetryToProto(update.JobRetried),
+		JobFinished: jobResultToProto(update.JobFinished),
+	}
+	if update.BuildFailed != nil {
+		out.BuildFailed = &apipb.BuildFailed{Error: update.BuildFailed.Error}
+	}
+	if update.BuildFinished != nil {
+		out.BuildFinished = &apipb.BuildFinished{}
+	}
+	return out
+}
+
+func statusUpdateFromProto(update *apipb.StatusUpdate) (*StatusUpdate, error) {
+	jobOutput, err := jobOutputFromProto(update.GetJobOutput())
+	if err != nil {
+		return nil, err
+	}
+	jobRetried, err := jobRetryFromProto(update.GetJobRetried())
+	if err != nil {
+		return nil, err
+	}
+	jobFinished, err := jobResultFromProto(update.GetJobFinished())
+	if err != nil {
+		return nil, err
+	}
+
+	out := &StatusUpdate{Seq: update.GetSeq(), JobOutput: jobOutput, JobRetried: jobRetried, JobFinished: jobFinished}
+	if update.BuildFailed != nil {
+		out.BuildFailed = &BuildFailed{Error: update.BuildFailed.GetError()}
+	}
+	if update.BuildFinished != nil {
+		out.BuildFinished = &BuildFinished{}
+	}
+	return out, nil
+}
+
+func signalRequestToProto(buildID build.ID, signal *SignalRequest) *apipb.SignalRequest {
+	out := &apipb.SignalRequest{BuildId: idToProto(buildID)}
+	if signal.UploadDone != nil {
+		out.UploadDone = &apipb.UploadDone{}
+	}
+	if signal.Cancel != nil {
+		out.Cancel = &apipb.Cancel{Reason: signal.Cancel.Reason}
+	}
+	return out
+}
+
+func signalRequestFromProto(signal *apipb.SignalRequest) (build.ID, *SignalRequest, error) {
+	buildID, err := idFromProto(signal.GetBuildId())
+	if err != nil {
+		return buildID, nil, fmt.Errorf("build id: %w", err)
+	}
+
+	out := &SignalRequest{}
+	if signal.UploadDone != nil {
+		out.UploadDone = &UploadDone{}
+	}
+	if signal.Cancel != nil {
+		out.Cancel = &Cancel{Reason: signal.Cancel.GetReason()}
+	}
+	return buildID, out, nil
+}
+
+func buildInfoToProto(info *BuildInfo) *apipb.BuildInfo {
+	out := &apipb.BuildInfo{
+		Id:        idToProto(info.ID),
+		State:     string(info.State),
+		Submitted: timestamppb.New(info.Submitted),
+		Tenant:    info.Tenant,
+	}
+	if len(info.JobCounts) != 0 {
+		out.JobCounts = make(map[string]int32, len(info.JobCounts))
+		for state, count := range info.JobCounts {
+			out.JobCounts[string(state)] = int32(count)
+		}
+	}
+	for _, job := range info.Jobs {
+		out.Jobs = append(out.Jobs, &apipb.JobInfo{
+			Id:     idToProto(job.ID),
+			Name:   job.Name,
+			State:  string(job.State),
+			Worker: string(job.Worker),
+		})
+	}
+	return out
+}
+
+func buildInfoFromProto(info *apipb.BuildInfo) (*BuildInfo, error) {
+	id, err := idFromProto(info.GetId())
+	if err != nil {
+		return nil, fmt.Errorf("build id: %w", err)
+	}
+
+	out := &BuildInfo{
+		ID:        id,
+		State:     BuildState(info.GetState()),
+		Submitted: info.GetSubmitted().AsTime(),
+		Tenant:    info.GetTenant(),
+	}
+	if len(info.GetJobCounts()) != 0 {
+		out.JobCounts = make(map[JobState]int, len(info.GetJobCounts()))
+		for state, count := range info.GetJobCounts() {
+			out.JobCounts[JobState(state)] = int(count)
+		}
+	}
+	for _, job := range info.GetJobs() {
+		jobID, err := idFromProto(job.GetId())
+		if err != nil {
+			return nil, fmt.Errorf("job id: %w", err)
+		}
+		out.Jobs = append(out.Jobs, JobInfo{
+			ID:     jobID,
+			Name:   job.GetName(),
+			State:  JobState(job.GetState()),
+			Worker: WorkerID(job.GetWorker()),
+		})
+	}
+	return out, nil
+}
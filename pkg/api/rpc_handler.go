@@ -0,0 +1,214 @@
+//go:build !solution
+
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"connectrpc.com/connect"
+	"go.uber.org/zap"
+
+	"distributed_build/pkg/api/apipb"
+	"distributed_build/pkg/api/apipb/apipbconnect"
+	"distributed_build/pkg/auth"
+)
+
+// rpcBuildHandler реализует BuildService из api.proto поверх Service.
+//
+// Ошибки обрабатываются так же, как в BuildHandler: ошибка до начала потока возвращается
+// клиенту как ошибка вызова, ошибка после начала потока - как StatusUpdate с BuildFailed.
+type rpcBuildHandler struct {
+	logger  *zap.Logger
+	service Service
+	auth    auth.Authenticator
+}
+
+func (h *rpcBuildHandler) register(mux *http.ServeMux) {
+	interceptors := connect.WithInterceptors(
+		&authInterceptor{auth: h.auth, roles: []auth.Role{auth.RoleClient}},
+		&protocolInterceptor{})
+	path, handler := apipbconnect.NewBuildServiceHandler(h, interceptors)
+	mux.Handle(path, auth.TLSContext(handler))
+}
+
+func (h *rpcBuildHandler) StartBuild(ctx context.Context, req *connect.Request[apipb.BuildRequest], stream *connect.ServerStream[apipb.StartBuildResponse]) error {
+	if err := CheckClientProtocol(PeerProtocol(ctx)); err != nil {
+		h.logger.Error("unable to negotiate protocol: " + err.Error())
+		return connect.NewError(connect.CodeFailedPrecondition, err)
+	}
+
+	graph, err := graphFromProto(req.Msg.GetGraph())
+	if err != nil {
+		errorMessage := "invalid request format " + err.Error()
+		h.logger.Error(errorMessage)
+		return connect.NewError(connect.CodeInvalidArgument, errors.New(errorMessage))
+	}
+
+	writer := &rpcStartWriter{stream: stream}
+	err = h.service.StartBuild(ctx, &BuildRequest{Graph: graph, Priority: int(req.Msg.GetPriority())}, writer)
+	if err != nil {
+		errorMessage := "service error: unable to start build " + err.Error()
+		h.logger.Error(errorMessage)
+		if !writer.started {
+			code := connect.CodeInternal
+			if errors.Is(err, ErrQuotaExceeded) {
+				code = connect.CodeResourceExhausted
+			}
+			return connect.NewError(code, errors.New(errorMessage))
+		}
+		return writer.Updated(&StatusUpdate{BuildFailed: &BuildFailed{Error: errorMessage}})
+	}
+	return nil
+}
+
+func (h *rpcBuildHandler) SignalBuild(ctx context.Context, req *connect.Request[apipb.SignalRequest]) (*connect.Response[apipb.SignalResponse], error) {
+	buildID, signal, err := signalRequestFromProto(req.Msg)
+	if err != nil {
+		errorMessage := "invalid signal format: " + err.Error()
+		h.logger.Error(errorMessage)
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errorMessage))
+	}
+
+	if _, err := h.service.SignalBuild(ctx, buildID, signal); err != nil {
+		errorMessage := "error signal build " + err.Error()
+		h.logger.Error(errorMessage)
+		return nil, connect.NewError(connect.CodeInternal, errors.New(errorMessage))
+	}
+	return connect.NewResponse(&apipb.SignalResponse{}), nil
+}
+
+func (h *rpcBuildHandler) WatchBuild(ctx context.Context, req *connect.Request[apipb.WatchBuildRequest], stream *connect.ServerStream[apipb.StatusUpdate]) error {
+	buildID, err := idFromProto(req.Msg.GetBuildId())
+	if err != nil {
+		errorMessage := "unable to read status buildID: " + err.Error()
+		h.logger.Error(errorMessage)
+		return connect.NewError(connect.CodeInvalidArgument, errors.New(errorMessage))
+	}
+
+	fromSeq := req.Msg.GetFromSeq()
+	h.logger.Info("client reattached to build", zap.String("build_id", buildID.String()), zap.Uint64("from_seq", fromSeq))
+
+	writer := &rpcStatusWriter{stream: stream}
+	err = h.service.WatchBuild(ctx, buildID, fromSeq, writer)
+	if err != nil {
+		errorMessage := "service error: unable to watch build " + err.Error()
+		h.logger.Error(errorMessage)
+		if !writer.started {
+			return connect.NewError(connect.CodeInternal, errors.New(errorMessage))
+		}
+		return writer.Updated(&StatusUpdate{BuildFailed: &BuildFailed{Error: errorMessage}})
+	}
+	return nil
+}
+
+func (h *rpcBuildHandler) ListBuilds(ctx context.Context, req *connect.Request[apipb.ListBuildsRequest]) (*connect.Response[apipb.ListBuildsResponse], error) {
+	builds, err := h.service.ListBuilds(ctx)
+	if err != nil {
+		errorMessage := "error listing builds " + err.Error()
+		h.logger.Error(errorMessage)
+		return nil, connect.NewError(connect.CodeInternal, errors.New(errorMessage))
+	}
+
+	rsp := &apipb.ListBuildsResponse{}
+	for i := range builds {
+		rsp.Builds = append(rsp.Builds, buildInfoToProto(&builds[i]))
+	}
+	return connect.NewResponse(rsp), nil
+}
+
+func (h *rpcBuildHandler) GetBuild(ctx context.Context, req *connect.Request[apipb.GetBuildRequest]) (*connect.Response[apipb.BuildInfo], error) {
+	buildID, err := idFromProto(req.Msg.GetBuildId())
+	if err != nil {
+		errorMessage := "unable to read buildID: " + err.Error()
+		h.logger.Error(errorMessage)
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errorMessage))
+	}
+
+	info, err := h.service.GetBuild(ctx, buildID)
+	if err != nil {
+		errorMessage := "error getting build " + err.Error()
+		h.logger.Error(errorMessage)
+
+		code := connect.CodeInternal
+		if errors.Is(err, ErrBuildNotFound) {
+			code = connect.CodeNotFound
+		}
+		return nil, connect.NewError(code, errors.New(errorMessage))
+	}
+	return connect.NewResponse(buildInfoToProto(info)), nil
+}
+
+type rpcStartWriter struct {
+	stream  *connect.ServerStream[apipb.StartBuildResponse]
+	started bool
+}
+
+func (w *rpcStartWriter) Started(rsp *BuildStarted) error {
+	w.started = true
+	return w.stream.Send(&apipb.StartBuildResponse{
+		Event: &apipb.StartBuildResponse_Started{Started: buildStartedToProto(rsp)},
+	})
+}
+
+func (w *rpcStartWriter) Updated(update *StatusUpdate) error {
+	w.started = true
+	return w.stream.Send(&apipb.StartBuildResponse{
+		Event: &apipb.StartBuildResponse_Update{Update: statusUpdateToProto(update)},
+	})
+}
+
+type rpcStatusWriter struct {
+	stream  *connect.ServerStream[apipb.StatusUpdate]
+	started bool
+}
+
+// Started ничего не делает: в потоке WatchBuild нет сообщения BuildStarted.
+func (w *rpcStatusWriter) Started(rsp *BuildStarted) error {
+	w.started = true
+	return nil
+}
+
+func (w *rpcStatusWriter) Updated(update *StatusUpdate) error {
+	w.started = true
+	return w.stream.Send(statusUpdateToProto(update))
+}
+
+// rpcHeartbeatHandler реализует HeartbeatService из api.proto поверх HeartbeatService.
+type rpcHeartbeatHandler struct {
+	logger  *zap.Logger
+	service HeartbeatService
+	auth    auth.Authenticator
+}
+
+func (h *rpcHeartbeatHandler) register(mux *http.ServeMux) {
+	interceptors := connect.WithInterceptors(
+		&authInterceptor{auth: h.auth, roles: []auth.Role{auth.RoleWorker}},
+		&protocolInterceptor{})
+	path, handler := apipbconnect.NewHeartbeatServiceHandler(h, interceptors)
+	mux.Handle(path, auth.TLSContext(handler))
+}
+
+func (h *rpcHeartbeatHandler) Heartbeat(ctx context.Context, req *connect.Request[apipb.HeartbeatRequest]) (*connect.Response[apipb.HeartbeatResponse], error) {
+	request, err := heartbeatRequestFromProto(req.Msg)
+	if err != nil {
+		errorMessage := "invalid request format " + err.Error()
+		h.logger.Error(errorMessage)
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New(errorMessage))
+	}
+
+	if err := checkWorkerID(ctx, request.WorkerID); err != nil {
+		errorMessage := "unable to authenticate request: " + err.Error()
+		h.logger.Error(errorMessage)
+		return nil, connect.NewError(connect.CodePermissionDenied, errors.New(errorMessage))
+	}
+
+	resp, err := h.service.Heartbeat(ctx, request)
+	if err != nil {
+		errorMessage := "service error " + err.Error()
+		h.logger.Error(errorMessage)
+		return nil, connect.NewError(connect.CodeInternal, errors.New(errorMessage))
+	}
+	return connect.NewResponse(heartbeatResponseToProto(resp)), nil
+}
@@ -0,0 +1,442 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/api/mock"
+	"distributed_build/pkg/auth"
+	"distributed_build/pkg/build"
+	"distributed_build/pkg/mtls"
+)
+
+// transports перечисляет префиксы endpoint-а, по которым клиент выбирает транспорт.
+var transports = map[string]string{
+	"json":    "",
+	"connect": api.ConnectScheme,
+	"grpc":    api.GRPCScheme,
+}
+
+type transportEnv struct {
+	service   *mock.MockService
+	heartbeat *mock.MockHeartbeatService
+	client    *api.BuildClient
+	hbClient  *api.HeartbeatClient
+}
+
+// newTransportEnv поднимает сервер, который отвечает на все транспорты сразу. gRPC без TLS
+// требует HTTP/2, поэтому сервер обёрнут в h2c.
+func newTransportEnv(t *testing.T, scheme string) *transportEnv {
+	return newAuthTransportEnv(t, scheme, nil)
+}
+
+func newAuthTransportEnv(t *testing.T, scheme string, a auth.Authenticator) *transportEnv {
+	ctrl := gomock.NewController(t)
+	l := zaptest.NewLogger(t)
+
+	env := &transportEnv{
+		service:   mock.NewMockService(ctrl),
+		heartbeat: mock.NewMockHeartbeatService(ctrl),
+	}
+
+	mux := http.NewServeMux()
+
+	buildHandler := api.NewBuildService(l, env.service)
+	buildHandler.RequireAuth(a)
+	buildHandler.Register(mux)
+
+	heartbeatHandler := api.NewHeartbeatHandler(l, env.heartbeat)
+	heartbeatHandler.RequireAuth(a)
+	heartbeatHandler.Register(mux)
+
+	server := httptest.NewServer(h2c.NewHandler(mux, &http2.Server{}))
+	t.Cleanup(server.Close)
+
+	env.client = api.NewBuildClient(l, scheme+server.URL)
+	env.hbClient = api.NewHeartbeatClient(l, scheme+server.URL)
+	return env
+}
+
+func forEachTransport(t *testing.T, test func(t *testing.T, env *transportEnv)) {
+	for name, scheme := range transports {
+		t.Run(name, func(t *testing.T) {
+			test(t, newTransportEnv(t, scheme))
+		})
+	}
+}
+
+func TestTransportSignal(t *testing.T) {
+	forEachTransport(t, func(t *testing.T, env *transportEnv) {
+		ctx := context.Background()
+
+		signal := &api.SignalRequest{Cancel: &api.Cancel{Reason: "stop"}}
+		gomock.InOrder(
+			env.service.EXPECT().SignalBuild(gomock.Any(), build.ID{01}, signal).Return(&api.SignalResponse{}, nil),
+			env.service.EXPECT().SignalBuild(gomock.Any(), build.ID{02}, gomock.Any()).Return(nil, fmt.Errorf("foo bar error")),
+		)
+
+		_, err := env.client.SignalBuild(ctx, build.ID{01}, signal)
+		require.NoError(t, err)
+
+		_, err = env.client.SignalBuild(ctx, build.ID{02}, &api.SignalRequest{UploadDone: &api.UploadDone{}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "foo bar error")
+	})
+}
+
+func TestTransportStartError(t *testing.T) {
+	forEachTransport(t, func(t *testing.T, env *transportEnv) {
+		env.service.EXPECT().StartBuild(gomock.Any(), gomock.Any(), gomock.Any()).Return(fmt.Errorf("foo bar error"))
+
+		_, _, err := env.client.StartBuild(context.Background(), &api.BuildRequest{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "foo bar error")
+	})
+}
+
+func TestTransportQuotaExceeded(t *testing.T) {
+	forEachTransport(t, func(t *testing.T, env *transportEnv) {
+		env.service.EXPECT().StartBuild(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(fmt.Errorf("%w: tenant \"alice\" already has 2 unfinished builds", api.ErrQuotaExceeded))
+
+		_, _, err := env.client.StartBuild(context.Background(), &api.BuildRequest{})
+		require.ErrorIs(t, err, api.ErrQuotaExceeded)
+		require.Contains(t, err.Error(), "already has 2 unfinished builds")
+	})
+}
+
+func TestTransportBuildRunning(t *testing.T) {
+	forEachTransport(t, func(t *testing.T, env *transportEnv) {
+		jobID := build.ID{03}
+		exitErr := "exit status 1"
+		req := &api.BuildRequest{
+			Graph: build.Graph{
+				SourceFiles: map[build.ID]string{{01}: "a.txt"},
+				Jobs: []build.Job{{
+					ID:     jobID,
+					Name:   "cat",
+					Inputs: []string{"a.txt"},
+					Deps:   []build.ID{{04}},
+					Cmds: []build.Cmd{
+						{Exec: []string{"cat", "a.txt"}, Environ: []string{"A=1"}, PassEnv: []string{"HTTP_PROXY"}},
+						{CatTemplate: "{{.OutputDir}}", CatOutput: "out"},
+					},
+					Retryable:     true,
+					Resources:     build.Resources{CPU: 0.5, Memory: 1 << 30},
+					Outputs:       []string{"out", "gen/*.go"},
+					StrictOutputs: true,
+				}},
+			},
+			Priority: 10,
+		}
+
+		started := &api.BuildStarted{ID: build.ID{02}, MissingFiles: []build.ID{{01}}}
+		updates := []*api.StatusUpdate{
+			{Seq: 1, JobOutput: &api.JobOutput{ID: jobID, Stdout: []byte("foo")}},
+			{Seq: 2, JobRetried: &api.JobRetry{ID: jobID, Attempt: 2, Worker: "worker0", Failure: api.FailureJob, Error: exitErr}},
+			{Seq: 3, JobFinished: &api.JobResult{ID: jobID, Stdout: []byte("foo"), Stderr: []byte("bar"), ExitCode: 1, Error: &exitErr, Failure: api.FailureOOM,
+				Usage:   &api.ResourceUsage{PeakMemory: 1 << 30, CPUTime: time.Second, ReadBytes: 10, WriteBytes: 20},
+				Log:     &api.OutputLog{Worker: "worker0", ArtifactID: api.LogArtifactID(jobID), StdoutSize: 1 << 30, StderrSize: 3},
+				Outputs: []api.OutputFile{{Path: "out/a", Size: 3, Digest: "abc"}, {Path: "out/b", Symlink: "a"}}}},
+			{Seq: 4, BuildFinished: &api.BuildFinished{}},
+		}
+
+		env.service.EXPECT().StartBuild(gomock.Any(), gomock.Eq(req), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, _ *api.BuildRequest, w api.StatusWriter) error {
+				require.True(t, api.PeerProtocol(ctx).Has(api.FeatureJobOutput))
+
+				if err := w.Started(started); err != nil {
+					return err
+				}
+				for _, u := range updates {
+					if err := w.Updated(u); err != nil {
+						return err
+					}
+				}
+				return fmt.Errorf("foo bar error")
+			})
+
+		rsp, r, err := env.client.StartBuild(context.Background(), req)
+		require.NoError(t, err)
+		defer r.Close()
+		require.Equal(t, started, rsp)
+
+		for _, expected := range updates {
+			u, err := r.Next()
+			require.NoError(t, err)
+			require.Equal(t, expected, u)
+		}
+
+		u, err := r.Next()
+		require.NoError(t, err)
+		require.Contains(t, u.BuildFailed.Error, "foo bar error")
+
+		_, err = r.Next()
+		require.Equal(t, io.EOF, err)
+
+		require.Equal(t, api.ProtocolVersion, env.client.Protocol().Version)
+	})
+}
+
+func TestTransportReattach(t *testing.T) {
+	forEachTransport(t, func(t *testing.T, env *transportEnv) {
+		buildID := build.ID{02}
+		gomock.InOrder(
+			env.service.EXPECT().StartBuild(gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, _ *api.BuildRequest, w api.StatusWriter) error {
+					if err := w.Started(&api.BuildStarted{ID: buildID}); err != nil {
+						return err
+					}
+					if err := w.Updated(&api.StatusUpdate{Seq: 1, JobOutput: &api.JobOutput{Stdout: []byte("a")}}); err != nil {
+						return err
+					}
+					panic(http.ErrAbortHandler)
+				}),
+			env.service.EXPECT().WatchBuild(gomock.Any(), buildID, uint64(2), gomock.Any()).
+				DoAndReturn(func(_ context.Context, _ build.ID, _ uint64, w api.StatusWriter) error {
+					return w.Updated(&api.StatusUpdate{Seq: 2, BuildFinished: &api.BuildFinished{}})
+				}),
+		)
+
+		_, r, err := env.client.StartBuild(context.Background(), &api.BuildRequest{})
+		require.NoError(t, err)
+		defer r.Close()
+
+		u, err := r.Next()
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), u.Seq)
+
+		u, err = r.Next()
+		require.NoError(t, err)
+		require.NotNil(t, u.BuildFinished)
+
+		_, err = r.Next()
+		require.Equal(t, io.EOF, err)
+	})
+}
+
+func TestTransportInspection(t *testing.T) {
+	forEachTransport(t, func(t *testing.T, env *transportEnv) {
+		ctx := context.Background()
+
+		info := api.BuildInfo{
+			ID:        build.ID{01},
+			State:     api.BuildStateRunning,
+			Submitted: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			Tenant:    "alice",
+			JobCounts: map[api.JobState]int{api.JobStateRunning: 1, api.JobStateCached: 2},
+			Jobs:      []api.JobInfo{{ID: build.ID{02}, Name: "cc a.c", State: api.JobStateRunning, Worker: "worker0"}},
+		}
+
+		env.service.EXPECT().ListBuilds(gomock.Any()).Return([]api.BuildInfo{info}, nil)
+		env.service.EXPECT().GetBuild(gomock.Any(), build.ID{01}).Return(&info, nil)
+		env.service.EXPECT().GetBuild(gomock.Any(), build.ID{03}).Return(nil, api.ErrBuildNotFound)
+
+		builds, err := env.client.ListBuilds(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []api.BuildInfo{info}, builds)
+
+		got, err := env.client.GetBuild(ctx, build.ID{01})
+		require.NoError(t, err)
+		require.Equal(t, &info, got)
+
+		_, err = env.client.GetBuild(ctx, build.ID{03})
+		require.Truef(t, errors.Is(err, api.ErrBuildNotFound), "%v", err)
+	})
+}
+
+func TestTransportHeartbeat(t *testing.T) {
+	forEachTransport(t, func(t *testing.T, env *transportEnv) {
+		ctx := context.Background()
+
+		errorMessage := "compiler crashed"
+		req := &api.HeartbeatRequest{
+			WorkerID:       "worker0",
+			RunningJobs:    []build.ID{{01}},
+			FreeSlots:      2,
+			FinishedJob:    []api.JobResult{{ID: build.ID{02}, Stderr: []byte("boom"), ExitCode: 2, Error: &errorMessage}},
+			JobOutput:      []api.JobOutput{{ID: build.ID{01}, Stdout: []byte("foo")}},
+			AddedArtifacts: []build.ID{{03}},
+			Draining:       true,
+			Beat:           7,
+			Received:       5,
+		}
+		rsp := &api.HeartbeatResponse{
+			JobsToRun: map[build.ID]api.JobSpec{
+				{04}: {
+					SourceFiles: map[build.ID]string{{05}: "a.c"},
+					Artifacts:   map[build.ID]api.WorkerID{{03}: "worker1"},
+					Job:         build.Job{ID: build.ID{04}, Name: "cc a.c"},
+				},
+			},
+			JobsToCancel: []build.ID{{01}},
+			Drained:      true,
+		}
+
+		gomock.InOrder(
+			env.heartbeat.EXPECT().Heartbeat(gomock.Any(), gomock.Eq(req)).Return(rsp, nil),
+			env.heartbeat.EXPECT().Heartbeat(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("build error: foo bar")),
+		)
+
+		got, err := env.hbClient.Heartbeat(ctx, req)
+		require.NoError(t, err)
+		require.Equal(t, rsp, got)
+		require.True(t, env.hbClient.Protocol().Has(api.FeatureCancel))
+
+		_, err = env.hbClient.Heartbeat(ctx, req)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "build error: foo bar")
+	})
+}
+
+func TestTransportLongPoll(t *testing.T) {
+	forEachTransport(t, func(t *testing.T, env *transportEnv) {
+		req := &api.HeartbeatRequest{WorkerID: "worker0", FreeSlots: 1, PollTimeout: time.Minute}
+		rsp := &api.HeartbeatResponse{
+			JobsToRun: map[build.ID]api.JobSpec{{01}: {Job: build.Job{ID: build.ID{01}}}},
+		}
+
+		env.heartbeat.EXPECT().Heartbeat(gomock.Any(), gomock.Eq(req)).
+			DoAndReturn(func(ctx context.Context, _ *api.HeartbeatRequest) (*api.HeartbeatResponse, error) {
+				require.True(t, api.PeerProtocol(ctx).Has(api.FeatureLongPoll))
+
+				time.Sleep(50 * time.Millisecond)
+				return rsp, nil
+			})
+
+		got, err := env.hbClient.Heartbeat(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, rsp, got)
+	})
+}
+
+func TestTransportAuth(t *testing.T) {
+	tokens := auth.NewStaticTokens()
+	tokens.Add("client-token", auth.Identity{Subject: "alice", Role: auth.RoleClient})
+	tokens.Add("worker-token", auth.Identity{Subject: "worker0", Role: auth.RoleWorker})
+
+	for name, scheme := range transports {
+		t.Run(name, func(t *testing.T) {
+			env := newAuthTransportEnv(t, scheme, tokens)
+			ctx := context.Background()
+
+			env.service.EXPECT().ListBuilds(gomock.Any()).
+				DoAndReturn(func(ctx context.Context) ([]api.BuildInfo, error) {
+					require.Equal(t, "alice", auth.FromContext(ctx).Subject)
+					return nil, nil
+				})
+			env.heartbeat.EXPECT().Heartbeat(gomock.Any(), gomock.Any()).Return(&api.HeartbeatResponse{}, nil)
+
+			_, err := env.client.ListBuilds(ctx)
+			require.Error(t, err, "request without token must be rejected")
+
+			env.client.SetCredentials(auth.Token("worker-token"))
+			_, err = env.client.ListBuilds(ctx)
+			require.Error(t, err, "workers must not start builds")
+
+			env.client.SetCredentials(auth.Token("client-token"))
+			_, err = env.client.ListBuilds(ctx)
+			require.NoError(t, err)
+
+			env.hbClient.SetCredentials(auth.Token("client-token"))
+			_, err = env.hbClient.Heartbeat(ctx, &api.HeartbeatRequest{WorkerID: "worker0"})
+			require.Error(t, err, "clients must not send heartbeats")
+
+			env.hbClient.SetCredentials(auth.Token("worker-token"))
+			_, err = env.hbClient.Heartbeat(ctx, &api.HeartbeatRequest{WorkerID: "worker1"})
+			require.Error(t, err, "worker must not impersonate another worker")
+
+			_, err = env.hbClient.Heartbeat(ctx, &api.HeartbeatRequest{WorkerID: "worker0"})
+			require.NoError(t, err)
+		})
+	}
+}
+
+type failingCredentials struct{}
+
+func (failingCredentials) Token() (string, error) {
+	return "", errors.New("token signer is unavailable")
+}
+
+func TestTransportCredentialsError(t *testing.T) {
+	forEachTransport(t, func(t *testing.T, env *transportEnv) {
+		env.client.SetCredentials(failingCredentials{})
+
+		_, _, err := env.client.StartBuild(context.Background(), &api.BuildRequest{})
+		require.ErrorContains(t, err, "token signer is unavailable")
+
+		_, err = env.client.ListBuilds(context.Background())
+		require.ErrorContains(t, err, "token signer is unavailable")
+	})
+}
+
+func TestTransportMTLS(t *testing.T) {
+	ca, err := mtls.NewCA()
+	require.NoError(t, err)
+
+	serverTLS, err := ca.Issue(auth.Identity{Subject: "coordinator", Role: auth.RoleCoordinator}, "127.0.0.1")
+	require.NoError(t, err)
+	clientTLS, err := ca.Issue(auth.Identity{Subject: "alice", Role: auth.RoleClient})
+	require.NoError(t, err)
+	workerTLS, err := ca.Issue(auth.Identity{Subject: "https://127.0.0.1/worker/0", Role: auth.RoleWorker})
+	require.NoError(t, err)
+
+	for name, scheme := range transports {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			l := zaptest.NewLogger(t)
+
+			service := mock.NewMockService(ctrl)
+			heartbeat := mock.NewMockHeartbeatService(ctrl)
+
+			mux := http.NewServeMux()
+			api.NewBuildService(l, service).Register(mux)
+			api.NewHeartbeatHandler(l, heartbeat).Register(mux)
+
+			server := httptest.NewUnstartedServer(mux)
+			server.TLS = serverTLS.ServerTLS()
+			server.EnableHTTP2 = true
+			server.StartTLS()
+			t.Cleanup(server.Close)
+
+			ctx := context.Background()
+
+			service.EXPECT().ListBuilds(gomock.Any()).
+				DoAndReturn(func(ctx context.Context) ([]api.BuildInfo, error) {
+					require.Equal(t, &auth.Identity{Subject: "alice", Role: auth.RoleClient}, auth.FromContext(ctx))
+					return nil, nil
+				})
+			heartbeat.EXPECT().Heartbeat(gomock.Any(), gomock.Any()).Return(&api.HeartbeatResponse{}, nil)
+
+			client := api.NewBuildClient(l, scheme+server.URL)
+			client.SetHTTPClient(clientTLS.HTTPClient())
+			_, err := client.ListBuilds(ctx)
+			require.NoError(t, err)
+
+			hbClient := api.NewHeartbeatClient(l, scheme+server.URL)
+			hbClient.SetHTTPClient(clientTLS.HTTPClient())
+			_, err = hbClient.Heartbeat(ctx, &api.HeartbeatRequest{WorkerID: "https://127.0.0.1/worker/0"})
+			require.Error(t, err, "clients must not send heartbeats")
+
+			hbClient.SetHTTPClient(workerTLS.HTTPClient())
+			_, err = hbClient.Heartbeat(ctx, &api.HeartbeatRequest{WorkerID: "https://127.0.0.1/worker/1"})
+			require.Error(t, err, "worker must not impersonate another worker")
+
+			_, err = hbClient.Heartbeat(ctx, &api.HeartbeatRequest{WorkerID: "https://127.0.0.1/worker/0"})
+			require.NoError(t, err)
+		})
+	}
+}
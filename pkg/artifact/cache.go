@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"distributed_build/pkg/build"
 )
@@ -21,6 +22,7 @@ var (
 type Cache struct {
 	tmpDir   string
 	cacheDir string
+	logDir   string
 
 	mu          sync.Mutex
 	writeLocked map[build.ID]struct{}
@@ -42,6 +44,11 @@ func NewCache(root string) (*Cache, error) {
 		return nil, err
 	}
 
+	logDir := filepath.Join(root, "logs")
+	if err := os.MkdirAll(logDir, 0777); err != nil {
+		return nil, err
+	}
+
 	for i := 0; i < 256; i++ {
 		d := hex.EncodeToString([]byte{uint8(i)})
 		if err := os.MkdirAll(filepath.Join(cacheDir, d), 0777); err != nil {
@@ -52,11 +59,16 @@ func NewCache(root string) (*Cache, error) {
 	return &Cache{
 		tmpDir:      tmpDir,
 		cacheDir:    cacheDir,
+		logDir:      logDir,
 		writeLocked: make(map[build.ID]struct{}),
 		readLocked:  make(map[build.ID]int),
 	}, nil
 }
 
+func (c *Cache) logPath(id build.ID) string {
+	return filepath.Join(c.logDir, id.String())
+}
+
 func (c *Cache) readLock(id build.ID) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -84,6 +96,9 @@ func (c *Cache) writeLock(id build.ID, remove bool) error {
 	defer c.mu.Unlock()
 
 	_, err := os.Stat(filepath.Join(c.cacheDir, id.Path()))
+	if os.IsNotExist(err) {
+		_, err = os.Stat(c.logPath(id))
+	}
 	if !os.IsNotExist(err) && err != nil {
 		return err
 	} else if err == nil && !remove {
@@ -108,6 +123,7 @@ func (c *Cache) writeUnlock(id build.ID) {
 	delete(c.writeLocked, id)
 }
 
+// Range обходит артефакты кеша. Логи, созданные через CreateLog, в обход не попадают.
 func (c *Cache) Range(artifactFn func(artifact build.ID) error) error {
 	shards, err := os.ReadDir(c.cacheDir)
 	if err != nil {
@@ -141,7 +157,10 @@ func (c *Cache) Remove(artifact build.ID) error {
 	}
 	defer c.writeUnlock(artifact)
 
-	return os.RemoveAll(filepath.Join(c.cacheDir, artifact.Path()))
+	return errors.Join(
+		os.RemoveAll(filepath.Join(c.cacheDir, artifact.Path())),
+		os.RemoveAll(c.logPath(artifact)),
+	)
 }
 
 func (c *Cache) Create(artifact build.ID) (path string, commit, abort func() error, err error) {
@@ -168,13 +187,70 @@ func (c *Cache) Create(artifact build.ID) (path string, commit, abort func() err
 	return
 }
 
+// CreateLog начинает запись лога джоба. Лог хранится отдельно от артефактов: его можно получить через Get,
+// но Range его не возвращает, поэтому воркер не объявляет логи координатору как артефакты. Старые логи
+// удаляет ExpireLogs.
+func (c *Cache) CreateLog(id build.ID) (path string, commit, abort func() error, err error) {
+	path, _, abort, err = c.Create(id)
+	if err != nil {
+		return
+	}
+
+	commit = func() error {
+		defer c.writeUnlock(id)
+
+		// Время коммита - точка отсчёта для ExpireLogs.
+		now := time.Now()
+		if err := os.Chtimes(path, now, now); err != nil {
+			return err
+		}
+		return os.Rename(path, c.logPath(id))
+	}
+	return
+}
+
+// ExpireLogs удаляет логи, сохранённые раньше before. Логи, которые сейчас читают или пишут, пропускаются.
+func (c *Cache) ExpireLogs(before time.Time) error {
+	logs, err := os.ReadDir(c.logDir)
+	if err != nil {
+		return err
+	}
+
+	for _, l := range logs {
+		var id build.ID
+		if err := id.UnmarshalText([]byte(l.Name())); err != nil {
+			return fmt.Errorf("invalid log name: %w", err)
+		}
+
+		info, err := l.Info()
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if !info.ModTime().Before(before) {
+			continue
+		}
+
+		err = c.Remove(id)
+		if err != nil && !errors.Is(err, ErrReadLocked) && !errors.Is(err, ErrWriteLocked) {
+			return err
+		}
+	}
+	return nil
+}
+
 func (c *Cache) Get(artifact build.ID) (path string, unlock func(), err error) {
 	if err = c.readLock(artifact); err != nil {
 		return
 	}
 
 	path = filepath.Join(c.cacheDir, artifact.Path())
-	if _, err = os.Stat(path); err != nil {
+	if _, err = os.Stat(path); os.IsNotExist(err) {
+		path = c.logPath(artifact)
+		_, err = os.Stat(path)
+	}
+	if err != nil {
 		c.readUnlock(artifact)
 
 		if os.IsNotExist(err) {
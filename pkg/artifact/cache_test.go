@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -96,3 +97,42 @@ func TestArtifactExists(t *testing.T) {
 	_, _, _, err = c.Create(idA)
 	require.Truef(t, errors.Is(err, artifact.ErrExists), "%v", err)
 }
+
+func TestLogs(t *testing.T) {
+	c := newTestCache(t)
+	defer c.cleanup()
+
+	id := build.ID{02}
+	path, commit, _, err := c.CreateLog(id)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(path, "stdout"), []byte("foo"), 0644))
+	require.NoError(t, commit())
+
+	path, unlock, err := c.Get(id)
+	require.NoError(t, err)
+	require.FileExists(t, filepath.Join(path, "stdout"))
+
+	_, _, _, err = c.Create(id)
+	require.ErrorIs(t, err, artifact.ErrExists)
+
+	require.NoError(t, c.Range(func(artifact build.ID) error {
+		t.Errorf("log %v is returned by Range", artifact)
+		return nil
+	}))
+
+	// Лог, который сейчас читают, не удаляется.
+	require.NoError(t, c.ExpireLogs(time.Now().Add(time.Minute)))
+	unlock()
+	_, unlock, err = c.Get(id)
+	require.NoError(t, err)
+	unlock()
+
+	require.NoError(t, c.ExpireLogs(time.Now().Add(-time.Minute)))
+	_, unlock, err = c.Get(id)
+	require.NoError(t, err)
+	unlock()
+
+	require.NoError(t, c.ExpireLogs(time.Now().Add(time.Minute)))
+	_, _, err = c.Get(id)
+	require.ErrorIs(t, err, artifact.ErrNotFound)
+}
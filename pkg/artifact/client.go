@@ -4,16 +4,77 @@ package artifact
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"time"
 
+	"distributed_build/pkg/auth"
 	"distributed_build/pkg/build"
 	"distributed_build/pkg/tarstream"
 )
 
+// lockRetryInterval is how often Download checks whether the artifact write lock was released.
+const lockRetryInterval = 10 * time.Millisecond
+
 // Download artifact from remote cache into local cache.
+//
+// If the artifact is already in the local cache, Download does nothing. If the artifact is being
+// written to the local cache, e.g. by a speculative copy of the job that produced it, Download waits
+// until the writer commits or aborts.
+//
+// Credentials are taken from ctx, see auth.WithCredentials.
 func Download(ctx context.Context, endpoint string, c *Cache, artifactID build.ID) error {
+	return DownloadWithClient(ctx, http.DefaultClient, endpoint, c, artifactID)
+}
+
+// DownloadWithClient works like Download, but uses the given client, e.g. configured for mTLS.
+func DownloadWithClient(ctx context.Context, client *http.Client, endpoint string, c *Cache, artifactID build.ID) error {
+	path, commit, abort, err := create(ctx, c, artifactID)
+	if errors.Is(err, ErrExists) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("unable to create artifact storage: %w", err)
+	}
+
+	if err := receive(ctx, client, endpoint, artifactID, path); err != nil {
+		_ = abort()
+		return err
+	}
+	return commit()
+}
+
+// Fetch downloads artifact from remote cache into directory dir outside of any cache, creating dir
+// if needed. Client uses it to download job logs, see api.OutputLog.
+//
+// Credentials are taken from ctx, see auth.WithCredentials.
+func Fetch(ctx context.Context, client *http.Client, endpoint string, artifactID build.ID, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("unable to create artifact directory: %w", err)
+	}
+	return receive(ctx, client, endpoint, artifactID, dir)
+}
+
+// create starts writing the artifact, waiting for the write lock held by someone else.
+func create(ctx context.Context, c *Cache, artifactID build.ID) (path string, commit, abort func() error, err error) {
+	for {
+		path, commit, abort, err = c.Create(artifactID)
+		if !errors.Is(err, ErrWriteLocked) {
+			return
+		}
+
+		select {
+		case <-time.After(lockRetryInterval):
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		}
+	}
+}
+
+func receive(ctx context.Context, client *http.Client, endpoint string, artifactID build.ID, path string) error {
 	artifactIDText := artifactID.String()
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/artifact?id=%s", endpoint, artifactIDText), nil)
 	if err != nil {
@@ -21,8 +82,11 @@ func Download(ctx context.Context, endpoint string, c *Cache, artifactID build.I
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	if err := auth.Authorize(req, nil); err != nil {
+		return err
+	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("get artifact request failed: %w", err)
 	}
@@ -35,14 +99,9 @@ func Download(ctx context.Context, endpoint string, c *Cache, artifactID build.I
 		}
 		return fmt.Errorf("service error: %s", string(errorData))
 	}
-	path, commit, abort, err := c.Create(artifactID)
-	if err != nil {
-		return fmt.Errorf("unable to create artifact storage: %w", err)
-	}
-	err = tarstream.Receive(path, resp.Body)
-	if err != nil {
-		abort()
+
+	if err := tarstream.Receive(path, resp.Body); err != nil {
 		return fmt.Errorf("unable to save artifact: %w", err)
 	}
-	return commit()
+	return nil
 }
@@ -7,11 +7,14 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/jonboulle/clockwork"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap/zaptest"
 
 	"distributed_build/pkg/artifact"
+	"distributed_build/pkg/auth"
 	"distributed_build/pkg/build"
 )
 
@@ -48,4 +51,81 @@ func TestArtifactTransfer(t *testing.T) {
 
 	err = artifact.Download(ctx, server.URL, localCache.Cache, build.ID{0x02})
 	require.Error(t, err)
+
+	fetched := filepath.Join(t.TempDir(), "fetched")
+	require.NoError(t, artifact.Fetch(ctx, http.DefaultClient, server.URL, id, fetched))
+	content, err = os.ReadFile(filepath.Join(fetched, "a.txt"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("foobar"), content)
+}
+
+func TestArtifactTransferAuth(t *testing.T) {
+	remoteCache := newTestCache(t)
+	localCache := newTestCache(t)
+
+	id := build.ID{0x01}
+	_, commit, _, err := remoteCache.Create(id)
+	require.NoError(t, err)
+	require.NoError(t, commit())
+
+	signer := auth.NewSigner([]byte("key"), clockwork.NewRealClock())
+
+	h := artifact.NewHandler(zaptest.NewLogger(t), remoteCache.Cache)
+	h.RequireAuth(signer)
+	mux := http.NewServeMux()
+	h.Register(mux)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ctx := context.Background()
+	require.Error(t, artifact.Download(ctx, server.URL, localCache.Cache, id), "download without token must be rejected")
+
+	creds := signer.Credentials(auth.Identity{Subject: "worker1", Role: auth.RoleWorker}, time.Minute)
+	require.NoError(t, artifact.Download(auth.WithCredentials(ctx, creds), server.URL, localCache.Cache, id))
+}
+
+func TestArtifactTransferConcurrentWrite(t *testing.T) {
+	remoteCache := newTestCache(t)
+	localCache := newTestCache(t)
+
+	id := build.ID{0x01}
+	dir, commit, _, err := remoteCache.Create(id)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("remote"), 0777))
+	require.NoError(t, commit())
+
+	h := artifact.NewHandler(zaptest.NewLogger(t), remoteCache.Cache)
+	mux := http.NewServeMux()
+	h.Register(mux)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// Локальная копия джоба ещё пишет артефакт, Download должен дождаться её.
+	dir, commit, _, err = localCache.Create(id)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("local"), 0777))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- artifact.Download(context.Background(), server.URL, localCache.Cache, id)
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("download must wait for local writer: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.NoError(t, commit())
+	require.NoError(t, <-done, "artifact already in cache")
+
+	dir, unlock, err := localCache.Get(id)
+	require.NoError(t, err)
+	defer unlock()
+
+	content, err := os.ReadFile(filepath.Join(dir, "a.txt"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("local"), content)
 }
@@ -3,6 +3,7 @@
 package artifact
 
 import (
+	"distributed_build/pkg/auth"
 	"distributed_build/pkg/build"
 	"distributed_build/pkg/tarstream"
 	"net/http"
@@ -13,14 +14,26 @@ import (
 type Handler struct {
 	logger *zap.Logger
 	cache  *Cache
+	auth   auth.Authenticator
 }
 
 func NewHandler(l *zap.Logger, c *Cache) *Handler {
 	return &Handler{logger: l, cache: c}
 }
 
+// RequireAuth включает проверку токенов: скачивать артефакты могут воркеры и клиенты.
+// Вызывается до Register.
+func (h *Handler) RequireAuth(a auth.Authenticator) {
+	h.auth = a
+}
+
 func (h *Handler) Register(mux *http.ServeMux) {
 	mux.HandleFunc("/artifact", func(w http.ResponseWriter, r *http.Request) {
+		r, ok := auth.Check(h.logger, h.auth, w, r, auth.RoleWorker, auth.RoleClient)
+		if !ok {
+			return
+		}
+
 		artifactIDData := r.URL.Query().Get("id")
 		var artifactID build.ID
 		err := artifactID.UnmarshalText([]byte(artifactIDData))
@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Role определяет, какие вызовы разрешены владельцу токена.
+type Role string
+
+const (
+	// RoleClient - пользователь, который запускает сборки и заливает исходные файлы.
+	RoleClient Role = "client"
+	// RoleWorker - воркер, который ходит с heartbeat-ами и скачивает файлы и артефакты.
+	RoleWorker Role = "worker"
+	// RoleAdmin - дежурный, которому доступен админский API координатора.
+	RoleAdmin Role = "admin"
+	// RoleCoordinator - серверный сертификат координатора при mTLS. Ни один хендлер не пускает эту роль.
+	RoleCoordinator Role = "coordinator"
+)
+
+var (
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrForbidden       = errors.New("forbidden")
+)
+
+// Identity описывает владельца токена.
+type Identity struct {
+	// Subject - имя владельца токена. Для воркера это его WorkerID.
+	Subject string `json:"sub"`
+	Role    Role   `json:"role"`
+}
+
+func (id *Identity) String() string {
+	return string(id.Role) + ":" + id.Subject
+}
+
+// Authenticator проверяет токен и возвращает его владельца.
+type Authenticator interface {
+	Authenticate(token string) (*Identity, error)
+}
+
+type anyAuthenticator []Authenticator
+
+// Any возвращает Authenticator, который принимает токен, если его принимает хотя бы один из auths.
+func Any(auths ...Authenticator) Authenticator {
+	return anyAuthenticator(auths)
+}
+
+func (a anyAuthenticator) Authenticate(token string) (*Identity, error) {
+	err := ErrUnauthenticated
+	for _, auth := range a {
+		var id *Identity
+		if id, err = auth.Authenticate(token); err == nil {
+			return id, nil
+		}
+	}
+	return nil, err
+}
+
+// Credentials возвращает токен, который клиент посылает с каждым запросом.
+type Credentials interface {
+	Token() (string, error)
+}
+
+// Token - статический токен, например из файла токенов.
+type Token string
+
+func (t Token) Token() (string, error) {
+	return string(t), nil
+}
+
+type identityKey struct{}
+
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext возвращает владельца токена, с которым пришёл запрос, или nil, если проверка
+// токенов выключена.
+func FromContext(ctx context.Context) *Identity {
+	id, _ := ctx.Value(identityKey{}).(*Identity)
+	return id
+}
+
+type credentialsKey struct{}
+
+// WithCredentials сохраняет в контексте учётные данные для функций, которые сами делают HTTP запросы,
+// например artifact.Download.
+func WithCredentials(ctx context.Context, c Credentials) context.Context {
+	return context.WithValue(ctx, credentialsKey{}, c)
+}
+
+const (
+	authorizationHeader = "Authorization"
+	bearerPrefix        = "Bearer "
+)
+
+// SetHeader записывает токен в заголовок Authorization. Если c == nil, ничего не делает.
+func SetHeader(h http.Header, c Credentials) error {
+	if c == nil {
+		return nil
+	}
+
+	token, err := c.Token()
+	if err != nil {
+		return fmt.Errorf("failed to get auth token: %w", err)
+	}
+	h.Set(authorizationHeader, bearerPrefix+token)
+	return nil
+}
+
+// Authorize записывает в запрос токен из c или, если c == nil, из контекста запроса.
+func Authorize(req *http.Request, c Credentials) error {
+	if c == nil {
+		c, _ = req.Context().Value(credentialsKey{}).(Credentials)
+	}
+	return SetHeader(req.Header, c)
+}
+
+// Authenticate находит владельца запроса и проверяет его роль.
+//
+// Если соединение пришло с проверенным клиентским сертификатом (mTLS), владелец берётся из сертификата.
+// Иначе проверяется токен из заголовка Authorization. Если сертификата нет и a == nil, проверка
+// выключена и Authenticate возвращает nil без ошибки.
+func Authenticate(ctx context.Context, a Authenticator, h http.Header, roles ...Role) (*Identity, error) {
+	id, err := identify(ctx, a, h)
+	if err != nil || id == nil {
+		return nil, err
+	}
+
+	if !slices.Contains(roles, id.Role) {
+		return nil, fmt.Errorf("%w: %s is not allowed here", ErrForbidden, id)
+	}
+	return id, nil
+}
+
+func identify(ctx context.Context, a Authenticator, h http.Header) (*Identity, error) {
+	if cert := peerCertificate(ctx); cert != nil {
+		return IdentityFromCertificate(cert)
+	}
+	if a == nil {
+		return nil, nil
+	}
+
+	header := h.Get(authorizationHeader)
+	if !strings.HasPrefix(header, bearerPrefix) {
+		return nil, fmt.Errorf("%w: missing bearer token", ErrUnauthenticated)
+	}
+	return a.Authenticate(strings.TrimPrefix(header, bearerPrefix))
+}
+
+// Check проверяет владельца запроса и кладёт его в контекст запроса. Если проверить
+// не удалось, отвечает 401 или 403 и возвращает false.
+//
+// Если проверка выключена (см. Authenticate), запрос пропускается как есть.
+func Check(l *zap.Logger, a Authenticator, w http.ResponseWriter, r *http.Request, roles ...Role) (*http.Request, bool) {
+	ctx := withConnectionState(r.Context(), r.TLS)
+
+	id, err := Authenticate(ctx, a, r.Header, roles...)
+	if err != nil {
+		errorMessage := "unable to authenticate request: " + err.Error()
+		l.Error(errorMessage, zap.String("path", r.URL.Path))
+
+		status := http.StatusUnauthorized
+		if errors.Is(err, ErrForbidden) {
+			status = http.StatusForbidden
+		}
+		http.Error(w, errorMessage, status)
+		return nil, false
+	}
+	if id == nil {
+		return r, true
+	}
+	return r.WithContext(WithIdentity(ctx, id)), true
+}
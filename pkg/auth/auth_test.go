@@ -0,0 +1,149 @@
+package auth_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"distributed_build/pkg/auth"
+)
+
+const tokensFile = `
+# token role subject
+s3cr3t client alice
+w0rk3r worker http://localhost:1234
+`
+
+func TestStaticTokens(t *testing.T) {
+	tokens, err := auth.ReadTokens(strings.NewReader(tokensFile))
+	require.NoError(t, err)
+
+	id, err := tokens.Authenticate("s3cr3t")
+	require.NoError(t, err)
+	require.Equal(t, &auth.Identity{Subject: "alice", Role: auth.RoleClient}, id)
+
+	id, err = tokens.Authenticate("w0rk3r")
+	require.NoError(t, err)
+	require.Equal(t, &auth.Identity{Subject: "http://localhost:1234", Role: auth.RoleWorker}, id)
+
+	_, err = tokens.Authenticate("guess")
+	require.True(t, errors.Is(err, auth.ErrUnauthenticated))
+
+	_, err = auth.ReadTokens(strings.NewReader("token root bob"))
+	require.Error(t, err)
+
+	tokens, err = auth.ReadTokens(strings.NewReader("0ncall admin bob"))
+	require.NoError(t, err)
+	id, err = tokens.Authenticate("0ncall")
+	require.NoError(t, err)
+	require.Equal(t, &auth.Identity{Subject: "bob", Role: auth.RoleAdmin}, id)
+}
+
+func TestSigner(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	signer := auth.NewSigner([]byte("key"), clock)
+
+	worker := auth.Identity{Subject: "worker0", Role: auth.RoleWorker}
+	token, err := signer.Issue(worker, time.Minute)
+	require.NoError(t, err)
+
+	id, err := signer.Authenticate(token)
+	require.NoError(t, err)
+	require.Equal(t, &worker, id)
+
+	_, err = auth.NewSigner([]byte("other key"), clock).Authenticate(token)
+	require.True(t, errors.Is(err, auth.ErrUnauthenticated), "%v", err)
+
+	payload, signature, _ := strings.Cut(token, ".")
+	_, err = signer.Authenticate(payload + "x." + signature)
+	require.True(t, errors.Is(err, auth.ErrUnauthenticated), "tampered token must be rejected")
+
+	clock.Advance(2 * time.Minute)
+	_, err = signer.Authenticate(token)
+	require.True(t, errors.Is(err, auth.ErrUnauthenticated), "expired token must be rejected")
+}
+
+func TestSignerCredentials(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	signer := auth.NewSigner([]byte("key"), clock)
+	creds := signer.Credentials(auth.Identity{Subject: "worker0", Role: auth.RoleWorker}, time.Minute)
+
+	first, err := creds.Token()
+	require.NoError(t, err)
+
+	clock.Advance(10 * time.Second)
+	second, err := creds.Token()
+	require.NoError(t, err)
+	require.Equal(t, first, second)
+
+	clock.Advance(time.Hour)
+	fresh, err := creds.Token()
+	require.NoError(t, err)
+
+	_, err = signer.Authenticate(fresh)
+	require.NoError(t, err)
+}
+
+func TestIdentitySigner(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	signer := auth.NewSigner([]byte("key"), clock)
+
+	worker := auth.Identity{Subject: "worker0", Role: auth.RoleWorker}
+	workerSigner := auth.NewIdentitySigner(worker, signer.DeriveKey(worker), clock)
+
+	token, err := workerSigner.Credentials(time.Minute).Token()
+	require.NoError(t, err)
+	id, err := signer.Authenticate(token)
+	require.NoError(t, err)
+	require.Equal(t, &worker, id)
+
+	// Ключом воркера нельзя выпустить токен ни для другого воркера, ни для другой роли.
+	for _, forged := range []auth.Identity{
+		{Subject: "worker1", Role: auth.RoleWorker},
+		{Subject: "worker0", Role: auth.RoleAdmin},
+		{Subject: "alice", Role: auth.RoleClient},
+	} {
+		token, err := auth.NewIdentitySigner(forged, signer.DeriveKey(worker), clock).Issue(time.Minute)
+		require.NoError(t, err)
+		_, err = signer.Authenticate(token)
+		require.True(t, errors.Is(err, auth.ErrUnauthenticated), "forged %v must be rejected", forged)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tokens := auth.NewStaticTokens()
+	tokens.Add("client-token", auth.Identity{Subject: "alice", Role: auth.RoleClient})
+	tokens.Add("worker-token", auth.Identity{Subject: "worker0", Role: auth.RoleWorker})
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r, ok := auth.Check(zaptest.NewLogger(t), tokens, w, r, auth.RoleClient)
+		if !ok {
+			return
+		}
+		_, _ = w.Write([]byte(auth.FromContext(r.Context()).Subject))
+	})
+
+	for _, tc := range []struct {
+		token  auth.Credentials
+		status int
+	}{
+		{token: nil, status: http.StatusUnauthorized},
+		{token: auth.Token("bad-token"), status: http.StatusUnauthorized},
+		{token: auth.Token("worker-token"), status: http.StatusForbidden},
+		{token: auth.Token("client-token"), status: http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/build", nil)
+		require.NoError(t, auth.Authorize(req, tc.token))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		require.Equal(t, tc.status, w.Code)
+	}
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+)
+
+// IdentityFromCertificate возвращает владельца клиентского сертификата.
+//
+// Роль задаётся в OrganizationalUnit. Subject берётся из первого URI в SAN, а если его нет - из CommonName.
+// Сертификаты воркеров содержат в URI их WorkerID.
+func IdentityFromCertificate(cert *x509.Certificate) (*Identity, error) {
+	if len(cert.Subject.OrganizationalUnit) != 1 {
+		return nil, fmt.Errorf("%w: certificate %q has no role", ErrUnauthenticated, cert.Subject.CommonName)
+	}
+
+	id := &Identity{Role: Role(cert.Subject.OrganizationalUnit[0]), Subject: cert.Subject.CommonName}
+	if len(cert.URIs) != 0 {
+		id.Subject = cert.URIs[0].String()
+	}
+	return id, nil
+}
+
+type connStateKey struct{}
+
+func withConnectionState(ctx context.Context, cs *tls.ConnectionState) context.Context {
+	if cs == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, connStateKey{}, cs)
+}
+
+// peerCertificate возвращает проверенный клиентский сертификат соединения, если он есть.
+func peerCertificate(ctx context.Context) *x509.Certificate {
+	cs, _ := ctx.Value(connStateKey{}).(*tls.ConnectionState)
+	if cs == nil || len(cs.VerifiedChains) == 0 {
+		return nil
+	}
+	return cs.VerifiedChains[0][0]
+}
+
+// TLSContext кладёт в контекст запроса состояние TLS соединения, чтобы Authenticate могла
+// достать из него клиентский сертификат. Нужен хендлерам, которые проверяют запрос не через Check.
+func TLSContext(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(withConnectionState(r.Context(), r.TLS)))
+	})
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+)
+
+// Signer выпускает и проверяет короткоживущие токены, подписанные HMAC-SHA256.
+//
+// Токен имеет вид "<payload>.<signature>", где payload - base64 от json с Identity и временем
+// истечения. Токен подписывается не общим ключом, а ключом своего владельца, который выводится
+// из общего ключа через DeriveKey. Общий ключ должен быть только у координатора: воркеру выдаётся
+// ключ для его Identity, и через IdentitySigner воркер может выпускать токены только на себя.
+type Signer struct {
+	key   []byte
+	clock clockwork.Clock
+}
+
+func NewSigner(key []byte, clock clockwork.Clock) *Signer {
+	return &Signer{key: key, clock: clock}
+}
+
+type signedClaims struct {
+	Identity
+	Expires int64 `json:"exp"`
+}
+
+// DeriveKey возвращает ключ, которым подписываются токены id. По нему нельзя получить ни общий ключ,
+// ни ключ другой Identity, поэтому его можно отдать владельцу, см. NewIdentitySigner.
+func (s *Signer) DeriveKey(id Identity) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(string(id.Role) + "\x00" + id.Subject))
+	return mac.Sum(nil)
+}
+
+func sign(key []byte, payload string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func issue(key []byte, id Identity, expires time.Time) (string, error) {
+	claims, err := json.Marshal(signedClaims{Identity: id, Expires: expires.Unix()})
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(claims)
+	return payload + "." + sign(key, payload), nil
+}
+
+// Issue выпускает токен для id, действительный ttl.
+func (s *Signer) Issue(id Identity, ttl time.Duration) (string, error) {
+	return issue(s.DeriveKey(id), id, s.clock.Now().Add(ttl))
+}
+
+func (s *Signer) Authenticate(token string) (*Identity, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, fmt.Errorf("%w: malformed token", ErrUnauthenticated)
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed token: %v", ErrUnauthenticated, err)
+	}
+
+	var claims signedClaims
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed token: %v", ErrUnauthenticated, err)
+	}
+
+	// Подпись проверяется ключом владельца из токена: токен, подписанный ключом другой Identity, не пройдёт.
+	if !hmac.Equal([]byte(signature), []byte(sign(s.DeriveKey(claims.Identity), payload))) {
+		return nil, fmt.Errorf("%w: invalid token signature", ErrUnauthenticated)
+	}
+	if !s.clock.Now().Before(time.Unix(claims.Expires, 0)) {
+		return nil, fmt.Errorf("%w: token expired", ErrUnauthenticated)
+	}
+	return &claims.Identity, nil
+}
+
+// Credentials возвращает учётные данные, которые сами перевыпускают токен для id, когда
+// прошла половина ttl. Нужны тем, у кого есть общий ключ, например админским утилитам.
+func (s *Signer) Credentials(id Identity, ttl time.Duration) Credentials {
+	return &signerCredentials{key: s.DeriveKey(id), id: id, ttl: ttl, clock: s.clock}
+}
+
+// IdentitySigner выпускает токены только для одной Identity ключом, который выдал Signer.DeriveKey.
+// Его получает воркер вместо общего ключа координатора.
+type IdentitySigner struct {
+	id    Identity
+	key   []byte
+	clock clockwork.Clock
+}
+
+func NewIdentitySigner(id Identity, key []byte, clock clockwork.Clock) *IdentitySigner {
+	return &IdentitySigner{id: id, key: key, clock: clock}
+}
+
+// Issue выпускает токен, действительный ttl.
+func (s *IdentitySigner) Issue(ttl time.Duration) (string, error) {
+	return issue(s.key, s.id, s.clock.Now().Add(ttl))
+}
+
+// Credentials возвращает учётные данные, которые сами перевыпускают токен, когда прошла половина ttl.
+// Так воркер всегда ходит со свежим токеном.
+func (s *IdentitySigner) Credentials(ttl time.Duration) Credentials {
+	return &signerCredentials{key: s.key, id: s.id, ttl: ttl, clock: s.clock}
+}
+
+type signerCredentials struct {
+	key   []byte
+	id    Identity
+	ttl   time.Duration
+	clock clockwork.Clock
+
+	mu      sync.Mutex
+	token   string
+	refresh time.Time
+}
+
+func (c *signerCredentials) Token() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	if c.token != "" && now.Before(c.refresh) {
+		return c.token, nil
+	}
+
+	token, err := issue(c.key, c.id, now.Add(c.ttl))
+	if err != nil {
+		return "", err
+	}
+	c.token = token
+	c.refresh = now.Add(c.ttl / 2)
+	return token, nil
+}
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// StaticTokens проверяет токены по заранее заданному списку.
+type StaticTokens struct {
+	// Токены хранятся в виде хешей, чтобы время поиска не зависело от совпадающего префикса.
+	tokens map[[sha256.Size]byte]Identity
+}
+
+func NewStaticTokens() *StaticTokens {
+	return &StaticTokens{tokens: make(map[[sha256.Size]byte]Identity)}
+}
+
+// Add разрешает вход по токену token.
+func (s *StaticTokens) Add(token string, id Identity) {
+	s.tokens[sha256.Sum256([]byte(token))] = id
+}
+
+func (s *StaticTokens) Authenticate(token string) (*Identity, error) {
+	id, ok := s.tokens[sha256.Sum256([]byte(token))]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown token", ErrUnauthenticated)
+	}
+	return &id, nil
+}
+
+// LoadTokens читает файл токенов.
+//
+// Каждая непустая строка файла имеет вид "<token> <role> <subject>". Строки, начинающиеся с #,
+// считаются комментариями. Для воркеров subject должен совпадать с WorkerID.
+func LoadTokens(path string) (*StaticTokens, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadTokens(f)
+}
+
+func ReadTokens(r io.Reader) (*StaticTokens, error) {
+	s := NewStaticTokens()
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("tokens line %d: expected \"<token> <role> <subject>\"", line)
+		}
+
+		role := Role(fields[1])
+		if role != RoleClient && role != RoleWorker && role != RoleAdmin {
+			return nil, fmt.Errorf("tokens line %d: unknown role %q", line, role)
+		}
+		s.Add(fields[0], Identity{Role: role, Subject: fields[2]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
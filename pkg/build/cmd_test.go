@@ -29,3 +29,27 @@ func TestCmdRender(t *testing.T) {
 
 	require.Equal(t, expected, result)
 }
+
+func TestHostSpecificEnv(t *testing.T) {
+	env := []string{
+		"GOOS=linux",
+		"CGO_ENABLED=0",
+		"USER=alice",
+		"SSH_AUTH_SOCK=/tmp/ssh-agent.sock",
+		"GOPATH=/home/alice/go",
+		"PATH=/usr/bin:/root/bin",
+		"GOFLAGS=-mod=mod",
+	}
+	require.Equal(t, []string{"USER", "SSH_AUTH_SOCK", "GOPATH", "PATH"}, HostSpecificEnv(env))
+	require.Empty(t, HostSpecificEnv([]string{"A=b", "GOCACHE={{.OutputDir}}/cache"}))
+}
+
+func TestHostSpecificJobEnv(t *testing.T) {
+	job := &Job{Cmds: []Cmd{
+		{Environ: []string{"USER=alice", "GOPATH=/home/alice/go", "CGO_ENABLED=0"}},
+		{Environ: []string{"GOPATH=/home/alice/go"}},
+		{CatTemplate: "hello", CatOutput: "{{.OutputDir}}/hello"},
+	}}
+	require.Equal(t, []string{"GOPATH", "USER"}, HostSpecificJobEnv(job))
+	require.Empty(t, HostSpecificJobEnv(&Job{Cmds: []Cmd{{Environ: []string{"A=b"}}}}))
+}
@@ -0,0 +1,36 @@
+package build
+
+import "time"
+
+// CriticalPath computes for every job the length of the longest path from the job to the end of the graph,
+// including the job itself. The length of a path is the sum of cost over its jobs.
+//
+// cost usually comes from historical job durations. If cost is nil, every job costs one second,
+// so the result measures the number of jobs on the path.
+//
+// Jobs with longer remaining paths should be started first: any delay of such job delays the whole build.
+func CriticalPath(jobs []Job, cost func(job *Job) time.Duration) map[ID]time.Duration {
+	if cost == nil {
+		cost = func(*Job) time.Duration { return time.Second }
+	}
+
+	dependents := map[ID][]ID{}
+	for _, job := range jobs {
+		for _, dep := range job.Deps {
+			dependents[dep] = append(dependents[dep], job.ID)
+		}
+	}
+
+	path := make(map[ID]time.Duration, len(jobs))
+	sorted := TopSort(jobs)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		job := &sorted[i]
+
+		var longest time.Duration
+		for _, next := range dependents[job.ID] {
+			longest = max(longest, path[next])
+		}
+		path[job.ID] = longest + cost(job)
+	}
+	return path
+}
@@ -0,0 +1,31 @@
+package build
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCriticalPath(t *testing.T) {
+	// a <- b <- d
+	// c <- d
+	jobs := []Job{
+		{ID: ID{'d'}, Name: "link", Deps: []ID{{'b'}, {'c'}}},
+		{ID: ID{'a'}, Name: "gen"},
+		{ID: ID{'b'}, Name: "compile", Deps: []ID{{'a'}}},
+		{ID: ID{'c'}, Name: "compile"},
+	}
+
+	require.Equal(t, map[ID]time.Duration{
+		{'a'}: 3 * time.Second,
+		{'b'}: 2 * time.Second,
+		{'c'}: 2 * time.Second,
+		{'d'}: time.Second,
+	}, CriticalPath(jobs, nil))
+
+	durations := map[string]time.Duration{"gen": time.Millisecond, "compile": time.Minute, "link": time.Second}
+	path := CriticalPath(jobs, func(job *Job) time.Duration { return durations[job.Name] })
+	require.Equal(t, time.Minute+time.Second+time.Millisecond, path[ID{'a'}])
+	require.Equal(t, time.Minute+time.Second, path[ID{'c'}])
+}
@@ -0,0 +1,57 @@
+package build
+
+import (
+	"slices"
+	"strings"
+)
+
+// hostVars - переменные, значения которых зависят от машины или сессии пользователя.
+var hostVars = []string{
+	"HOME", "USER", "LOGNAME", "HOSTNAME", "SHELL", "PWD", "OLDPWD", "SHLVL", "_",
+	"TERM", "DISPLAY", "TMPDIR", "XDG_RUNTIME_DIR", "XDG_SESSION_ID", "DBUS_SESSION_BUS_ADDRESS",
+}
+
+// homePrefixes - начала путей внутри домашних директорий пользователей.
+var homePrefixes = []string{"/home/", "/Users/", "/root/"}
+
+// HostSpecificEnv возвращает имена переменных из environ, значения которых похожи на специфичные
+// для машины, на которой собран граф: домашние директории, имя пользователя, SSH-сессия и т.п.
+//
+// Такие переменные попадают в ID джоба, и джоб, собранный на другой машине, не найдётся в кеше.
+// Клиент перед запуском сборки и воркер перед запуском джоба пишут о них предупреждение в лог,
+// но джоб не отклоняют.
+func HostSpecificEnv(environ []string) []string {
+	var names []string
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		if slices.Contains(hostVars, name) || strings.HasPrefix(name, "SSH_") || hostPath(value) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// HostSpecificJobEnv возвращает отсортированные имена переменных из Environ всех команд джоба, которые
+// HostSpecificEnv считает специфичными для машины, без повторов.
+func HostSpecificJobEnv(job *Job) []string {
+	var names []string
+	for _, cmd := range job.Cmds {
+		names = append(names, HostSpecificEnv(cmd.Environ)...)
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+func hostPath(value string) bool {
+	for _, path := range strings.Split(value, ":") {
+		if path == "/root" {
+			return true
+		}
+		for _, prefix := range homePrefixes {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
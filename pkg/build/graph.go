@@ -29,6 +29,33 @@ type Job struct {
 
 	// Cmds описывает список команд, которые нужно выполнить в рамках этого джоба.
 	Cmds []Cmd
+
+	// Retryable разрешает координатору перезапустить джоб, который завершился с ошибкой.
+	//
+	// Нужен для нестабильных тестов. Джобы, упавшие из-за воркера, перезапускаются и без этого флага,
+	// а джобы, убитые за превышение лимита памяти, не перезапускаются и с ним.
+	Retryable bool
+
+	// Resources задаёт ресурсы, которые воркер выделяет джобу. Нулевые значения означают отсутствие ограничений.
+	Resources Resources
+
+	// Outputs перечисляет файлы, которые джоб должен создать в {{.OutputDir}}: пути относительно выходной
+	// директории или шаблоны в синтаксисе path.Match, например "lib.a" или "gen/*.go". Путь директории
+	// объявляет всё её содержимое. Если под путь или шаблон не подошёл ни один файл, джоб завершается
+	// с ошибкой, см. CheckOutputs. Пустой Outputs ничего не проверяет.
+	Outputs []string
+
+	// StrictOutputs запрещает джобу оставлять в выходной директории файлы, не объявленные в Outputs.
+	StrictOutputs bool
+}
+
+// Resources описывает ограничения ресурсов джоба.
+type Resources struct {
+	// CPU задаёт, сколько ядер может использовать джоб, например 0.5 или 2.
+	CPU float64
+
+	// Memory задаёт, сколько байт памяти может использовать джоб. Джоб, превысивший лимит, убивается.
+	Memory int64
 }
 
 // Cmd описывает одну команду сборки.
@@ -51,8 +78,17 @@ type Cmd struct {
 	Exec []string
 
 	// Environ описывает переменные окружения, которые необходимы для работы команды из Exec.
+	//
+	// Воркер не передаёт команде своё окружение: он добавляет к Environ только PATH из директорий тулчейна
+	// и HOME и TMPDIR во временной директории джоба. Переменные из Environ входят в ID джоба, поэтому
+	// в них не должно быть значений, специфичных для машины клиента, см. HostSpecificEnv.
 	Environ []string
 
+	// PassEnv перечисляет имена переменных окружения воркера, которые передаются команде как есть,
+	// например прокси или адрес сервиса, нужного тестам. Значения этих переменных не входят в ID джоба,
+	// поэтому от них не должен зависеть выход команды.
+	PassEnv []string
+
 	// WorkingDirectory задаёт рабочую директорию для команды из Exec.
 	WorkingDirectory string
 
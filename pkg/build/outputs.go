@@ -0,0 +1,77 @@
+package build
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// OutputError описывает выход джоба, который не соответствует Job.Outputs.
+type OutputError struct {
+	// Missing - объявленные пути и шаблоны, под которые не подошёл ни один файл.
+	Missing []string
+	// Undeclared - файлы, не объявленные в Outputs. Заполняется только для Job.StrictOutputs.
+	Undeclared []string
+}
+
+func (e *OutputError) Error() string {
+	var parts []string
+	if len(e.Missing) != 0 {
+		parts = append(parts, "missing declared outputs: "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Undeclared) != 0 {
+		parts = append(parts, "undeclared outputs: "+strings.Join(e.Undeclared, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// CheckOutputs проверяет файлы files, которые джоб оставил в выходной директории, по Outputs и StrictOutputs.
+// Пути в files относительные и разделены "/". Возвращает *OutputError или ошибку в шаблоне Outputs.
+func (j *Job) CheckOutputs(files []string) error {
+	if len(j.Outputs) == 0 && !j.StrictOutputs {
+		return nil
+	}
+
+	matched := make([]bool, len(j.Outputs))
+	var outErr OutputError
+	for _, file := range files {
+		declared := false
+		for i, pattern := range j.Outputs {
+			ok, err := matchOutput(pattern, file)
+			if err != nil {
+				return fmt.Errorf("invalid output pattern %q: %w", pattern, err)
+			}
+			if ok {
+				matched[i], declared = true, true
+			}
+		}
+		if !declared && j.StrictOutputs {
+			outErr.Undeclared = append(outErr.Undeclared, file)
+		}
+	}
+
+	for i, pattern := range j.Outputs {
+		if !matched[i] {
+			outErr.Missing = append(outErr.Missing, pattern)
+		}
+	}
+	if len(outErr.Missing) != 0 || len(outErr.Undeclared) != 0 {
+		return &outErr
+	}
+	return nil
+}
+
+// matchOutput проверяет, подходит ли файл file под шаблон pattern сам или через одну из своих директорий.
+func matchOutput(pattern, file string) (bool, error) {
+	pattern = strings.TrimSuffix(path.Clean(pattern), "/")
+	for i := 0; i <= len(file); i++ {
+		if i != len(file) && file[i] != '/' {
+			continue
+		}
+		ok, err := path.Match(pattern, file[:i])
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,24 @@
+package build
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckOutputs(t *testing.T) {
+	files := []string{"lib.a", "gen/a.go", "gen/b.go", "doc/index.html", "tmp.o"}
+
+	job := Job{Outputs: []string{"lib.a", "gen/*.go", "doc"}}
+	require.NoError(t, job.CheckOutputs(files))
+
+	job.StrictOutputs = true
+	require.Equal(t, &OutputError{Undeclared: []string{"tmp.o"}}, job.CheckOutputs(files))
+
+	job.Outputs = append(job.Outputs, "bin/tool", "*.o")
+	require.Equal(t, &OutputError{Missing: []string{"bin/tool"}}, job.CheckOutputs(files))
+	require.EqualError(t, job.CheckOutputs(nil), "missing declared outputs: lib.a, gen/*.go, doc, bin/tool, *.o")
+
+	require.NoError(t, (&Job{}).CheckOutputs(files))
+	require.Error(t, (&Job{Outputs: []string{"["}}).CheckOutputs(files))
+}
@@ -0,0 +1,295 @@
+// Package cgroup ограничивает и считает ресурсы джобов через cgroup v2.
+package cgroup
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+)
+
+// ErrUnavailable означает, что воркеру не делегирована cgroup v2 с контроллерами memory и cpu.
+// Воркер в этом случае выполняет джобы без лимитов и не сообщает JobResult.Usage.
+var ErrUnavailable = errors.New("cgroup v2 delegation is unavailable")
+
+// cpuPeriod - период cpu.max, за который джоб может потратить CPU * cpuPeriod процессорного времени.
+const cpuPeriod = 100 * time.Millisecond
+
+// minCPUQuota - наименьшая квота cpu.max, которую принимает ядро. На меньшую квоту запись возвращает EINVAL.
+const minCPUQuota = time.Millisecond
+
+// closeTimeout ограничивает, сколько Close ждёт, пока в cgroup не останется процессов. closePoll - как часто
+// Close проверяет cgroup.events.
+const (
+	closeTimeout = 10 * time.Second
+	closePoll    = 10 * time.Millisecond
+)
+
+// requiredControllers нужны для лимитов. Контроллер io необязателен: без него не считается ввод-вывод.
+var requiredControllers = []string{"memory", "cpu"}
+
+// Manager создаёт cgroup джобов внутри cgroup воркера.
+type Manager struct {
+	dir string
+}
+
+// New готовит делегированную воркеру cgroup dir к созданию cgroup джобов.
+//
+// В cgroup v2 процессы могут жить только в листьях дерева, поэтому если в dir есть процессы, New переносит
+// их в dir/worker. После этого в dir включаются контроллеры memory, cpu и, если он есть, io.
+// Если это сделать нельзя, возвращает ошибку, обёрнутую в ErrUnavailable.
+func New(dir string) (*Manager, error) {
+	controllers, err := readFields(filepath.Join(dir, "cgroup.controllers"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	for _, c := range requiredControllers {
+		if !slices.Contains(controllers, c) {
+			return nil, fmt.Errorf("%w: controller %q is not enabled in %s", ErrUnavailable, c, dir)
+		}
+	}
+
+	if err := evacuate(dir); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+
+	enable := []string{"+memory", "+cpu"}
+	if slices.Contains(controllers, "io") {
+		enable = append(enable, "+io")
+	}
+	if err := write(dir, "cgroup.subtree_control", strings.Join(enable, " ")); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	return &Manager{dir: dir}, nil
+}
+
+// evacuate переносит процессы из dir в лист dir/worker.
+func evacuate(dir string) error {
+	procs, err := readFields(filepath.Join(dir, "cgroup.procs"))
+	if err != nil || len(procs) == 0 {
+		return err
+	}
+
+	leaf := filepath.Join(dir, "worker")
+	if err := os.MkdirAll(leaf, 0755); err != nil {
+		return err
+	}
+	for _, pid := range procs {
+		// Процесс мог успеть завершиться.
+		if err := write(leaf, "cgroup.procs", pid); err != nil && !errors.Is(err, syscall.ESRCH) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Group - cgroup одного запуска джоба.
+type Group struct {
+	dir string
+	fd  *os.File
+}
+
+// Create создаёт cgroup с лимитами r. Имя cgroup начинается с name и дополняется случайным суффиксом,
+// поэтому cgroup, оставшаяся от прошлого запуска, не мешает следующему. Процессы джоба попадают в неё
+// через Group.Apply.
+func (m *Manager) Create(name string, r build.Resources) (*Group, error) {
+	dir, err := os.MkdirTemp(m.dir, name+"-")
+	if err != nil {
+		return nil, err
+	}
+	g := &Group{dir: dir}
+
+	if err := g.limit(r); err != nil {
+		_ = g.Close()
+		return nil, err
+	}
+	return g, nil
+}
+
+func (g *Group) limit(r build.Resources) error {
+	if r.Memory > 0 {
+		if err := write(g.dir, "memory.max", strconv.FormatInt(r.Memory, 10)); err != nil {
+			return err
+		}
+		// Без swap джоб упирается в лимит, а не уходит в swap. Файла нет, если swap не настроен.
+		if err := writeOptional(g.dir, "memory.swap.max", "0"); err != nil {
+			return err
+		}
+		// При OOM убивается весь джоб, а не один его процесс.
+		if err := writeOptional(g.dir, "memory.oom.group", "1"); err != nil {
+			return err
+		}
+	}
+	if r.CPU > 0 {
+		quota := max(time.Duration(r.CPU*float64(cpuPeriod)), minCPUQuota)
+		if err := write(g.dir, "cpu.max", fmt.Sprintf("%d %d", quota.Microseconds(), cpuPeriod.Microseconds())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Dir возвращает путь cgroup.
+func (g *Group) Dir() string {
+	return g.dir
+}
+
+// Usage возвращает ресурсы, потраченные процессами cgroup.
+func (g *Group) Usage() (*api.ResourceUsage, error) {
+	var u api.ResourceUsage
+
+	cpu, err := readKeyed(filepath.Join(g.dir, "cpu.stat"))
+	if err != nil {
+		return nil, err
+	}
+	u.CPUTime = time.Duration(cpu["usage_usec"]) * time.Microsecond
+
+	// memory.peak появился в Linux 5.19.
+	if peak, err := os.ReadFile(filepath.Join(g.dir, "memory.peak")); err == nil {
+		if u.PeakMemory, err = strconv.ParseInt(strings.TrimSpace(string(peak)), 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid memory.peak: %w", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	// Без контроллера io файла нет.
+	if err := readIOStat(filepath.Join(g.dir, "io.stat"), &u); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// OOMKilled возвращает true, если ядро убивало процессы cgroup за превышение лимита памяти.
+func (g *Group) OOMKilled() bool {
+	events, err := readKeyed(filepath.Join(g.dir, "memory.events"))
+	return err == nil && events["oom_kill"] > 0
+}
+
+// Close убивает оставшиеся процессы cgroup, ждёт их завершения и удаляет cgroup.
+func (g *Group) Close() error {
+	if g.fd != nil {
+		_ = g.fd.Close()
+	}
+
+	// cgroup.kill появился в Linux 5.14. На старых ядрах процессы джоба должен убить воркер.
+	if err := writeOptional(g.dir, "cgroup.kill", "1"); err != nil {
+		return err
+	}
+	// cgroup.kill только посылает SIGKILL, а cgroup с процессами удалить нельзя.
+	if err := g.waitEmpty(closeTimeout); err != nil {
+		return err
+	}
+
+	// rmdir удаляет cgroup вместе с её интерфейсными файлами. Сами файлы в cgroupfs удалить нельзя,
+	// поэтому os.RemoveAll здесь не подходит.
+	return os.Remove(g.dir)
+}
+
+// waitEmpty ждёт, пока в cgroup.events не появится "populated 0".
+func (g *Group) waitEmpty(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		events, err := readKeyed(filepath.Join(g.dir, "cgroup.events"))
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			return nil
+		case err != nil:
+			return err
+		case events["populated"] == 0:
+			return nil
+		case time.Now().After(deadline):
+			return fmt.Errorf("cgroup %s still has processes after %v", g.dir, timeout)
+		}
+		time.Sleep(closePoll)
+	}
+}
+
+func write(dir, file, value string) error {
+	return os.WriteFile(filepath.Join(dir, file), []byte(value), 0644)
+}
+
+// writeOptional пишет в файл, которого может не быть на старых ядрах или без нужного контроллера.
+// В cgroupfs нельзя создавать файлы, поэтому отсутствие файла проверяется заранее.
+func writeOptional(dir, file, value string) error {
+	if _, err := os.Stat(filepath.Join(dir, file)); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return write(dir, file, value)
+}
+
+func readFields(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(data)), nil
+}
+
+// readKeyed читает файлы вида "key value" по строке на ключ, например cpu.stat и memory.events.
+func readKeyed(path string) (map[string]int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]int64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", filepath.Base(path), err)
+		}
+		values[key] = n
+	}
+	return values, scanner.Err()
+}
+
+// readIOStat суммирует rbytes и wbytes по всем устройствам из io.stat.
+func readIOStat(path string, u *api.ResourceUsage) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// 8:0 rbytes=1024 wbytes=2048 rios=1 wios=2 dbytes=0 dios=0
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid io.stat: %w", err)
+			}
+			switch key {
+			case "rbytes":
+				u.ReadBytes += n
+			case "wbytes":
+				u.WriteBytes += n
+			}
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,76 @@
+//go:build linux
+
+package cgroup
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// Detect находит cgroup v2 текущего процесса и готовит её через New. Воркер должен быть запущен в
+// делегированной ему cgroup, например через systemd с Delegate=yes.
+func Detect() (*Manager, error) {
+	mount, err := mountpoint()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			return New(filepath.Join(mount, path))
+		}
+	}
+	return nil, fmt.Errorf("%w: process is not in cgroup v2 hierarchy", ErrUnavailable)
+}
+
+// mountpoint возвращает, куда смонтирована cgroup v2.
+func mountpoint() (string, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// 36 25 0:31 / /sys/fs/cgroup rw,nosuid - cgroup2 cgroup2 rw
+		fields := strings.Fields(scanner.Text())
+		for i, field := range fields {
+			if field == "-" && i+1 < len(fields) && fields[i+1] == "cgroup2" {
+				return fields[4], nil
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	return "", fmt.Errorf("%w: cgroup2 is not mounted", ErrUnavailable)
+}
+
+// Apply запускает cmd сразу внутри cgroup, так что ни один процесс джоба не выполнится вне её.
+// Вызывается до cmd.Start. Работает на Linux 5.7 и новее.
+func (g *Group) Apply(cmd *exec.Cmd) error {
+	if g.fd == nil {
+		fd, err := os.Open(g.dir)
+		if err != nil {
+			return err
+		}
+		g.fd = fd
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(g.fd.Fd())
+	return nil
+}
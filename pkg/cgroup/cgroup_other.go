@@ -0,0 +1,17 @@
+//go:build !linux
+
+package cgroup
+
+import (
+	"os/exec"
+)
+
+// Detect на системах без cgroup всегда возвращает ErrUnavailable.
+func Detect() (*Manager, error) {
+	return nil, ErrUnavailable
+}
+
+// Apply на системах без cgroup всегда возвращает ErrUnavailable.
+func (g *Group) Apply(cmd *exec.Cmd) error {
+	return ErrUnavailable
+}
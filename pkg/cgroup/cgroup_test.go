@@ -0,0 +1,146 @@
+package cgroup_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+	"distributed_build/pkg/cgroup"
+)
+
+// fakeCgroup создаёт директорию, которая выглядит как делегированная cgroup v2 без процессов.
+func fakeCgroup(t *testing.T, controllers string) string {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cgroup.controllers"), []byte(controllers+"\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cgroup.procs"), nil, 0644))
+	return dir
+}
+
+// emptyCgroup удаляет файлы из фейковой cgroup, как будто это интерфейсные файлы cgroupfs, которые исчезают
+// вместе с директорией при rmdir.
+func emptyCgroup(t *testing.T, dir string, keep ...string) {
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	for _, e := range entries {
+		if !slices.Contains(keep, e.Name()) {
+			require.NoError(t, os.Remove(filepath.Join(dir, e.Name())))
+		}
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestUnavailable(t *testing.T) {
+	_, err := cgroup.New(t.TempDir())
+	require.True(t, errors.Is(err, cgroup.ErrUnavailable), "%v", err)
+
+	_, err = cgroup.New(fakeCgroup(t, "cpuset pids"))
+	require.True(t, errors.Is(err, cgroup.ErrUnavailable), "%v", err)
+}
+
+func TestLimits(t *testing.T) {
+	dir := fakeCgroup(t, "cpuset cpu io memory pids")
+	m, err := cgroup.New(dir)
+	require.NoError(t, err)
+	require.Equal(t, "+memory +cpu +io", readFile(t, filepath.Join(dir, "cgroup.subtree_control")))
+
+	g, err := m.Create("job", build.Resources{CPU: 1.5, Memory: 1 << 20})
+	require.NoError(t, err)
+
+	job := g.Dir()
+	require.Equal(t, dir, filepath.Dir(job))
+	require.True(t, strings.HasPrefix(filepath.Base(job), "job-"), job)
+	require.Equal(t, "1048576", readFile(t, filepath.Join(job, "memory.max")))
+	require.Equal(t, "150000 100000", readFile(t, filepath.Join(job, "cpu.max")))
+
+	emptyCgroup(t, job)
+	require.NoError(t, g.Close())
+	require.NoDirExists(t, job)
+
+	tiny, err := m.Create("tiny", build.Resources{CPU: 0.001})
+	require.NoError(t, err)
+	require.Equal(t, "1000 100000", readFile(t, filepath.Join(tiny.Dir(), "cpu.max")), "quota must not fall below kernel minimum")
+
+	unlimited, err := m.Create("unlimited", build.Resources{})
+	require.NoError(t, err)
+	require.NoFileExists(t, filepath.Join(unlimited.Dir(), "memory.max"))
+}
+
+func TestLeftoverGroup(t *testing.T) {
+	dir := fakeCgroup(t, "cpu memory")
+	m, err := cgroup.New(dir)
+	require.NoError(t, err)
+
+	// cgroup, оставшаяся от упавшего воркера, не мешает перезапуску джоба.
+	leftover, err := m.Create("job", build.Resources{})
+	require.NoError(t, err)
+	g, err := m.Create("job", build.Resources{})
+	require.NoError(t, err)
+	require.NotEqual(t, leftover.Dir(), g.Dir())
+}
+
+func TestCloseWaitsForProcesses(t *testing.T) {
+	dir := fakeCgroup(t, "cpu memory")
+	m, err := cgroup.New(dir)
+	require.NoError(t, err)
+
+	g, err := m.Create("job", build.Resources{})
+	require.NoError(t, err)
+
+	events := filepath.Join(g.Dir(), "cgroup.events")
+	require.NoError(t, os.WriteFile(events, []byte("populated 1\nfrozen 0\n"), 0644))
+	emptyCgroup(t, g.Dir(), "cgroup.events")
+
+	closed := make(chan error, 1)
+	go func() { closed <- g.Close() }()
+
+	select {
+	case err := <-closed:
+		t.Fatalf("Close returned before cgroup became empty: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	require.DirExists(t, g.Dir())
+
+	// Без cgroup.events фейковая cgroup считается пустой, и rmdir её удалит.
+	require.NoError(t, os.Remove(events))
+	require.NoError(t, <-closed)
+	require.NoDirExists(t, g.Dir())
+}
+
+func TestUsage(t *testing.T) {
+	dir := fakeCgroup(t, "cpu memory")
+	m, err := cgroup.New(dir)
+	require.NoError(t, err)
+
+	g, err := m.Create("job", build.Resources{})
+	require.NoError(t, err)
+	defer g.Close()
+
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(g.Dir(), name), []byte(content), 0644))
+	}
+	write("cpu.stat", "usage_usec 1500000\nuser_usec 1000000\nsystem_usec 500000\n")
+	write("memory.peak", "4096\n")
+	write("memory.events", "low 0\nhigh 0\nmax 3\noom 1\noom_kill 0\n")
+	write("io.stat", "8:0 rbytes=100 wbytes=200 rios=1 wios=2 dbytes=0 dios=0\n8:16 rbytes=10 wbytes=20 rios=1 wios=1 dbytes=0 dios=0\n")
+
+	u, err := g.Usage()
+	require.NoError(t, err)
+	require.Equal(t, &api.ResourceUsage{PeakMemory: 4096, CPUTime: 1500 * time.Millisecond, ReadBytes: 110, WriteBytes: 220}, u)
+	require.False(t, g.OOMKilled())
+
+	write("memory.events", "low 0\nhigh 0\nmax 3\noom 1\noom_kill 1\n")
+	require.True(t, g.OOMKilled())
+}
@@ -7,7 +7,9 @@ import (
 
 	"go.uber.org/zap"
 
+	"distributed_build/pkg/api"
 	"distributed_build/pkg/build"
+	"distributed_build/pkg/mtls"
 )
 
 type Client struct {
@@ -21,6 +23,11 @@ func NewClient(
 	panic("implement me")
 }
 
+// SetTLS включает mTLS: клиент ходит к координатору с сертификатом из cfg. Вызывается до Build.
+func (c *Client) SetTLS(cfg *mtls.Config) {
+	panic("implement me")
+}
+
 type BuildListener interface {
 	OnJobStdout(jobID build.ID, stdout []byte) error
 	OnJobStderr(jobID build.ID, stderr []byte) error
@@ -29,6 +36,15 @@ type BuildListener interface {
 	OnJobFailed(jobID build.ID, code int, error string) error
 }
 
+// Build запускает сборку graph на координаторе и сообщает lsn о ходе сборки. Перед запуском Build
+// предупреждает в лог о джобах с переменными окружения, специфичными для машины клиента, см. warnHostEnv.
 func (c *Client) Build(ctx context.Context, graph build.Graph, lsn BuildListener) error {
 	panic("implement me")
 }
+
+// DownloadLog скачивает полный вывод джоба, который воркер обрезал, в директорию dir: файлы api.LogStdout
+// и api.LogStderr. log приходит в LogListener.OnJobLog. Лог лежит в кеше воркера, поэтому скачать его
+// можно, пока воркер жив и не удалил лог.
+func (c *Client) DownloadLog(ctx context.Context, log *api.OutputLog, dir string) error {
+	panic("implement me")
+}
@@ -0,0 +1,25 @@
+package client
+
+import (
+	"go.uber.org/zap"
+
+	"distributed_build/pkg/build"
+)
+
+// warnHostEnv предупреждает о джобах графа, в окружении которых есть переменные, специфичные для машины
+// клиента, см. build.HostSpecificEnv. Такие джобы не находятся в кеше сборок с других машин, но сборку
+// клиент всё равно запускает.
+func warnHostEnv(l *zap.Logger, graph *build.Graph) {
+	for i := range graph.Jobs {
+		job := &graph.Jobs[i]
+		names := build.HostSpecificJobEnv(job)
+		if len(names) == 0 {
+			continue
+		}
+
+		l.Warn("job environment contains host-specific variables",
+			zap.String("job_id", job.ID.String()),
+			zap.String("job_name", job.Name),
+			zap.Strings("vars", names))
+	}
+}
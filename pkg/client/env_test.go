@@ -0,0 +1,29 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"distributed_build/pkg/build"
+)
+
+func TestWarnHostEnv(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+
+	graph := &build.Graph{Jobs: []build.Job{
+		{ID: build.ID{'a'}, Name: "compile", Cmds: []build.Cmd{
+			{Environ: []string{"GOPATH=/home/alice/go", "CGO_ENABLED=0"}},
+			{Environ: []string{"USER=alice", "GOPATH=/home/alice/go"}},
+		}},
+		{ID: build.ID{'b'}, Name: "test", Cmds: []build.Cmd{{Environ: []string{"GOCACHE={{.OutputDir}}/cache"}}}},
+	}}
+	warnHostEnv(zap.New(core), graph)
+
+	require.Equal(t, 1, logs.Len())
+	fields := logs.All()[0].ContextMap()
+	require.Equal(t, "compile", fields["job_name"])
+	require.Equal(t, []any{"GOPATH", "USER"}, fields["vars"])
+}
@@ -0,0 +1,18 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/artifact"
+)
+
+// downloadLog скачивает лог-артефакт log из кеша воркера в директорию dir.
+func downloadLog(ctx context.Context, client *http.Client, log *api.OutputLog, dir string) error {
+	if err := artifact.Fetch(ctx, client, log.Worker.String(), log.ArtifactID, dir); err != nil {
+		return fmt.Errorf("failed to download job log from %s: %w", log.Worker, err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/artifact"
+	"distributed_build/pkg/build"
+)
+
+func TestDownloadLog(t *testing.T) {
+	cache, err := artifact.NewCache(t.TempDir())
+	require.NoError(t, err)
+
+	jobID := build.ID{'a'}
+	dir, commit, _, err := cache.Create(api.LogArtifactID(jobID))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, api.LogStdout), []byte("full stdout"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, api.LogStderr), nil, 0644))
+	require.NoError(t, commit())
+
+	mux := http.NewServeMux()
+	artifact.NewHandler(zaptest.NewLogger(t), cache).Register(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	log := &api.OutputLog{Worker: api.WorkerID(server.URL), ArtifactID: api.LogArtifactID(jobID), StdoutSize: 11}
+	out := filepath.Join(t.TempDir(), "log")
+	require.NoError(t, downloadLog(context.Background(), http.DefaultClient, log, out))
+
+	stdout, err := os.ReadFile(filepath.Join(out, api.LogStdout))
+	require.NoError(t, err)
+	require.Equal(t, "full stdout", string(stdout))
+
+	log.ArtifactID = build.ID{'b'}
+	require.Error(t, downloadLog(context.Background(), http.DefaultClient, log, out))
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+)
+
+// RetryListener - необязательное расширение BuildListener. Если listener его реализует,
+// клиент сообщает ему о перезапусках джобов из StatusUpdate.JobRetried.
+type RetryListener interface {
+	OnJobRetry(jobID build.ID, attempt int, error string) error
+}
+
+// UsageListener - необязательное расширение BuildListener. Если listener его реализует,
+// клиент сообщает ему, сколько ресурсов потратил выполненный джоб (JobResult.Usage).
+// Для джобов из кеша и джобов на воркерах без cgroup v2 OnJobUsage не вызывается.
+type UsageListener interface {
+	OnJobUsage(jobID build.ID, usage *api.ResourceUsage) error
+}
+
+// LogListener - необязательное расширение BuildListener. Если listener его реализует, клиент сообщает ему
+// о джобах, вывод которых воркер обрезал. Полный вывод можно скачать через Client.DownloadLog.
+type LogListener interface {
+	OnJobLog(jobID build.ID, log *api.OutputLog) error
+}
+
+// outputTracker доставляет вывод джобов в BuildListener без повторов.
+//
+// Пока джоб выполняется, координатор присылает куски его вывода в StatusUpdate.JobOutput.
+// После завершения джоба в StatusUpdate.JobFinished приходит полный вывод, из которого
+// в listener нужно отдать только ещё не доставленную часть. Для джобов из кеша кусков
+// не бывает, и весь вывод доставляется из JobFinished.
+type outputTracker struct {
+	lsn       BuildListener
+	delivered map[build.ID]*deliveredOutput
+}
+
+type deliveredOutput struct {
+	stdout int
+	stderr int
+}
+
+func newOutputTracker(lsn BuildListener) *outputTracker {
+	return &outputTracker{lsn: lsn, delivered: make(map[build.ID]*deliveredOutput)}
+}
+
+func (t *outputTracker) job(id build.ID) *deliveredOutput {
+	d, ok := t.delivered[id]
+	if !ok {
+		d = &deliveredOutput{}
+		t.delivered[id] = d
+	}
+	return d
+}
+
+func (t *outputTracker) onOutput(o *api.JobOutput) error {
+	d := t.job(o.ID)
+	if len(o.Stdout) != 0 {
+		if err := t.lsn.OnJobStdout(o.ID, o.Stdout); err != nil {
+			return err
+		}
+		d.stdout += len(o.Stdout)
+	}
+	if len(o.Stderr) != 0 {
+		if err := t.lsn.OnJobStderr(o.ID, o.Stderr); err != nil {
+			return err
+		}
+		d.stderr += len(o.Stderr)
+	}
+	return nil
+}
+
+// onRetry сообщает listener-у о перезапуске джоба. Вывод следующего запуска доставляется заново,
+// а полный вывод в JobFinished будет только от последнего запуска.
+func (t *outputTracker) onRetry(r *api.JobRetry) error {
+	delete(t.delivered, r.ID)
+
+	if lsn, ok := t.lsn.(RetryListener); ok {
+		return lsn.OnJobRetry(r.ID, r.Attempt, r.Error)
+	}
+	return nil
+}
+
+// onFinished доставляет остаток вывода завершившегося джоба, ссылку на полный вывод и потраченные ресурсы.
+func (t *outputTracker) onFinished(res *api.JobResult) error {
+	d := t.job(res.ID)
+	delete(t.delivered, res.ID)
+
+	if len(res.Stdout) > d.stdout {
+		if err := t.lsn.OnJobStdout(res.ID, res.Stdout[d.stdout:]); err != nil {
+			return err
+		}
+	}
+	if len(res.Stderr) > d.stderr {
+		if err := t.lsn.OnJobStderr(res.ID, res.Stderr[d.stderr:]); err != nil {
+			return err
+		}
+	}
+
+	if lsn, ok := t.lsn.(LogListener); ok && res.Log != nil {
+		if err := lsn.OnJobLog(res.ID, res.Log); err != nil {
+			return err
+		}
+	}
+	if lsn, ok := t.lsn.(UsageListener); ok && res.Usage != nil {
+		return lsn.OnJobUsage(res.ID, res.Usage)
+	}
+	return nil
+}
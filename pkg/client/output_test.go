@@ -0,0 +1,115 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+)
+
+type outputRecorder struct {
+	BuildListener
+
+	stdout []string
+	stderr []string
+}
+
+func (r *outputRecorder) OnJobStdout(jobID build.ID, stdout []byte) error {
+	r.stdout = append(r.stdout, string(stdout))
+	return nil
+}
+
+func (r *outputRecorder) OnJobStderr(jobID build.ID, stderr []byte) error {
+	r.stderr = append(r.stderr, string(stderr))
+	return nil
+}
+
+func TestOutputTracker(t *testing.T) {
+	var r outputRecorder
+	tracker := newOutputTracker(&r)
+
+	streamed, cached := build.ID{'a'}, build.ID{'b'}
+
+	require.NoError(t, tracker.onOutput(&api.JobOutput{ID: streamed, Stdout: []byte("foo")}))
+	require.NoError(t, tracker.onOutput(&api.JobOutput{ID: streamed, Stdout: []byte("bar"), Stderr: []byte("err")}))
+	require.NoError(t, tracker.onFinished(&api.JobResult{ID: streamed, Stdout: []byte("foobarbaz"), Stderr: []byte("err")}))
+	require.NoError(t, tracker.onFinished(&api.JobResult{ID: cached, Stdout: []byte("OK")}))
+
+	require.Equal(t, []string{"foo", "bar", "baz", "OK"}, r.stdout)
+	require.Equal(t, []string{"err"}, r.stderr)
+}
+
+type retryRecorder struct {
+	outputRecorder
+
+	attempts []int
+}
+
+func (r *retryRecorder) OnJobRetry(jobID build.ID, attempt int, error string) error {
+	r.attempts = append(r.attempts, attempt)
+	return nil
+}
+
+func TestOutputTrackerRetry(t *testing.T) {
+	var r retryRecorder
+	tracker := newOutputTracker(&r)
+
+	id := build.ID{'a'}
+	require.NoError(t, tracker.onOutput(&api.JobOutput{ID: id, Stdout: []byte("first")}))
+	require.NoError(t, tracker.onRetry(&api.JobRetry{ID: id, Attempt: 2, Failure: api.FailureInfra, Error: "worker lost"}))
+	require.NoError(t, tracker.onOutput(&api.JobOutput{ID: id, Stdout: []byte("sec")}))
+	require.NoError(t, tracker.onFinished(&api.JobResult{ID: id, Stdout: []byte("second")}))
+
+	require.Equal(t, []string{"first", "sec", "ond"}, r.stdout)
+	require.Equal(t, []int{2}, r.attempts)
+}
+
+type usageRecorder struct {
+	outputRecorder
+
+	usage map[build.ID]*api.ResourceUsage
+}
+
+func (r *usageRecorder) OnJobUsage(jobID build.ID, usage *api.ResourceUsage) error {
+	r.usage[jobID] = usage
+	return nil
+}
+
+func TestOutputTrackerUsage(t *testing.T) {
+	r := usageRecorder{usage: make(map[build.ID]*api.ResourceUsage)}
+	tracker := newOutputTracker(&r)
+
+	run, cached := build.ID{'a'}, build.ID{'b'}
+	usage := &api.ResourceUsage{PeakMemory: 1 << 20, CPUTime: 3 * time.Second}
+	require.NoError(t, tracker.onFinished(&api.JobResult{ID: run, Stdout: []byte("OK"), Usage: usage}))
+	require.NoError(t, tracker.onFinished(&api.JobResult{ID: cached}))
+
+	require.Equal(t, map[build.ID]*api.ResourceUsage{run: usage}, r.usage)
+	require.Equal(t, []string{"OK"}, r.stdout)
+}
+
+type logRecorder struct {
+	outputRecorder
+
+	logs map[build.ID]*api.OutputLog
+}
+
+func (r *logRecorder) OnJobLog(jobID build.ID, log *api.OutputLog) error {
+	r.logs[jobID] = log
+	return nil
+}
+
+func TestOutputTrackerLog(t *testing.T) {
+	r := logRecorder{logs: make(map[build.ID]*api.OutputLog)}
+	tracker := newOutputTracker(&r)
+
+	truncated, full := build.ID{'a'}, build.ID{'b'}
+	log := &api.OutputLog{Worker: "worker0", ArtifactID: api.LogArtifactID(truncated), StdoutSize: 1 << 30}
+	require.NoError(t, tracker.onFinished(&api.JobResult{ID: truncated, Log: log}))
+	require.NoError(t, tracker.onFinished(&api.JobResult{ID: full}))
+
+	require.Equal(t, map[build.ID]*api.OutputLog{truncated: log}, r.logs)
+}
@@ -0,0 +1,26 @@
+package dist
+
+import (
+	"context"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/scheduler"
+)
+
+// adminService реализует api.AdminService поверх шедулера координатора.
+type adminService struct {
+	scheduler *scheduler.Scheduler
+}
+
+func (s *adminService) ListWorkers(ctx context.Context) ([]api.WorkerInfo, error) {
+	return s.scheduler.Workers(), nil
+}
+
+func (s *adminService) QuarantineWorker(ctx context.Context, req *api.QuarantineRequest) error {
+	if req.Release {
+		return s.scheduler.Unquarantine(req.WorkerID)
+	}
+
+	s.scheduler.Quarantine(req.WorkerID, req.Reason)
+	return nil
+}
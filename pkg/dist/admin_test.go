@@ -0,0 +1,40 @@
+package dist
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/scheduler"
+)
+
+func TestAdminQuarantine(t *testing.T) {
+	s := scheduler.NewScheduler(zaptest.NewLogger(t), defaultConfig, time.After)
+	defer s.Stop()
+
+	admin := &adminService{scheduler: s}
+	ctx := context.Background()
+
+	require.NoError(t, admin.QuarantineWorker(ctx, &api.QuarantineRequest{WorkerID: "worker0", Reason: "disk full"}))
+
+	workers, err := admin.ListWorkers(ctx)
+	require.NoError(t, err)
+	require.Len(t, workers, 1)
+	require.Equal(t, "disk full", workers[0].Quarantine.Reason)
+
+	req := &api.HeartbeatRequest{WorkerID: "worker0", FreeSlots: 1}
+	s.ScheduleJob(&api.JobSpec{})
+	jobs, _ := pollWork(ctx, s, req)
+	require.Empty(t, jobs, "quarantined worker must not get jobs")
+
+	release := &api.QuarantineRequest{WorkerID: "worker0", Release: true}
+	require.NoError(t, admin.QuarantineWorker(ctx, release))
+	require.ErrorIs(t, admin.QuarantineWorker(ctx, release), api.ErrWorkerNotFound)
+
+	jobs, _ = pollWork(ctx, s, req)
+	require.Len(t, jobs, 1)
+}
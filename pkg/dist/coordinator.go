@@ -18,6 +18,23 @@ type Coordinator struct {
 var defaultConfig = scheduler.Config{
 	CacheTimeout: time.Millisecond * 10,
 	DepsTimeout:  time.Millisecond * 100,
+
+	WorkerTimeout: time.Second,
+
+	Retry: scheduler.RetryConfig{
+		MaxInfraRetries: 3,
+		Backoff:         time.Millisecond * 100,
+		MaxBackoff:      time.Second,
+	},
+	Quarantine: scheduler.QuarantineConfig{
+		Window:           10,
+		MaxInfraFailures: 5,
+		Cooldown:         time.Minute,
+	},
+	Speculation: scheduler.SpeculationConfig{
+		Factor:     2,
+		MinRuntime: time.Second,
+	},
 }
 
 func NewCoordinator(
@@ -0,0 +1,70 @@
+package dist
+
+import (
+	"context"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+	"distributed_build/pkg/scheduler"
+)
+
+// pollWork выбирает работу для воркера, приславшего heartbeat: до req.FreeSlots джобов
+// и джобы, которые воркер должен убить.
+//
+// Если воркер поддерживает FeatureLongPoll и прислал PollTimeout, а работы пока нет, pollWork
+// держит запрос, пока шедулер не назначит работу, но не дольше PollTimeout. Так джоб уходит
+// на воркер сразу, а не на следующей итерации его цикла.
+//
+// pollWork отмечает в шедулере, что воркер жив, поэтому координатор вызывает его после того,
+// как передал шедулеру req.FinishedJob. Протокол воркера pollWork передаёт в scheduler.SetProtocol,
+// поэтому джобы, которые воркер выполнил бы не так, как они описаны, ему не достаются.
+//
+// Если воркер не дождался ответа и long-poll оборвался, pollWork ничего не назначает: воркер джобы не получит.
+// Джобы, которые всё же потерялись по дороге, шедулер вернёт в очередь, когда воркер сообщит, что дождался
+// ответа, см. OnNumberedHeartbeat. Поэтому назначенные джобы pollWork привязывает к req.Beat.
+//
+// Воркер с req.Draining pollWork переводит в scheduler.Drain, а воркера с req.Leaving убирает из шедулера
+// и ничего ему не назначает. Ответ Drained координатор берёт из drained.
+func pollWork(ctx context.Context, s *scheduler.Scheduler, req *api.HeartbeatRequest) ([]*scheduler.PendingJob, []build.ID) {
+	if req.Leaving {
+		s.Deregister(req.WorkerID)
+		return nil, nil
+	}
+
+	if peer := api.PeerProtocol(ctx); peer != nil {
+		s.SetProtocol(req.WorkerID, peer)
+	}
+	if req.Beat != 0 {
+		s.OnNumberedHeartbeat(req.WorkerID, req.RunningJobs, req.Beat, req.Received)
+	} else {
+		s.OnHeartbeat(req.WorkerID, req.RunningJobs)
+	}
+	if req.Draining {
+		s.Drain(req.WorkerID)
+	}
+
+	if req.PollTimeout > 0 && api.PeerProtocol(ctx).Has(api.FeatureLongPoll) {
+		waitCtx, cancel := context.WithTimeout(ctx, req.PollTimeout)
+		s.WaitWork(waitCtx, req.WorkerID, req.FreeSlots)
+		cancel()
+	}
+	if ctx.Err() != nil {
+		return nil, nil
+	}
+
+	var jobs []*scheduler.PendingJob
+	for len(jobs) < req.FreeSlots {
+		job := s.TryPickJobFor(req.WorkerID, req.Beat)
+		if job == nil {
+			break
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, s.JobsToCancel(req.WorkerID)
+}
+
+// drained возвращает HeartbeatResponse.Drained для воркера, приславшего heartbeat: воркер выводится
+// из кластера и может уйти. Координатор вызывает его после pollWork.
+func drained(s *scheduler.Scheduler, req *api.HeartbeatRequest) bool {
+	return req.Draining && s.Drained(req.WorkerID)
+}
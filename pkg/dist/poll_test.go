@@ -0,0 +1,178 @@
+package dist
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+	"distributed_build/pkg/scheduler"
+)
+
+func TestPollWork(t *testing.T) {
+	s := scheduler.NewScheduler(zaptest.NewLogger(t), defaultConfig, time.After)
+	defer s.Stop()
+
+	longPoll := api.WithPeerProtocol(context.Background(), api.LocalProtocol())
+	req := &api.HeartbeatRequest{WorkerID: "worker0", FreeSlots: 2, PollTimeout: 10 * time.Millisecond}
+
+	jobs, cancelled := pollWork(longPoll, s, req)
+	require.Empty(t, jobs)
+	require.Empty(t, cancelled)
+
+	req.PollTimeout = time.Minute
+	scheduled := make(chan *scheduler.PendingJob)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		scheduled <- s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: build.ID{01}}})
+	}()
+
+	start := time.Now()
+	jobs, _ = pollWork(longPoll, s, req)
+	require.Equal(t, []*scheduler.PendingJob{<-scheduled}, jobs)
+	require.Less(t, time.Since(start), time.Minute/2, "job must be pushed as soon as it is scheduled")
+}
+
+func TestPollWorkCancelled(t *testing.T) {
+	s := scheduler.NewScheduler(zaptest.NewLogger(t), defaultConfig, time.After)
+	defer s.Stop()
+
+	ctx, cancel := context.WithCancel(api.WithPeerProtocol(context.Background(), api.LocalProtocol()))
+	req := &api.HeartbeatRequest{WorkerID: "worker0", FreeSlots: 1, PollTimeout: time.Minute}
+
+	done := make(chan []*scheduler.PendingJob)
+	go func() {
+		jobs, _ := pollWork(ctx, s, req)
+		done <- jobs
+	}()
+
+	// Воркер обрывает long-poll, и в тот же момент появляется джоб.
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	job := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: build.ID{01}}})
+	require.Empty(t, <-done, "jobs must not be assigned to a dropped long-poll")
+
+	req = &api.HeartbeatRequest{WorkerID: "worker0", FreeSlots: 1}
+	jobs, _ := pollWork(api.WithPeerProtocol(context.Background(), api.LocalProtocol()), s, req)
+	require.Equal(t, []*scheduler.PendingJob{job}, jobs)
+}
+
+func TestPollWorkLegacyWorker(t *testing.T) {
+	s := scheduler.NewScheduler(zaptest.NewLogger(t), defaultConfig, time.After)
+	defer s.Stop()
+
+	legacy := api.WithPeerProtocol(context.Background(), &api.Protocol{Version: 1})
+	req := &api.HeartbeatRequest{WorkerID: "worker0", FreeSlots: 1, PollTimeout: time.Minute}
+
+	done := make(chan []*scheduler.PendingJob)
+	go func() {
+		jobs, _ := pollWork(legacy, s, req)
+		done <- jobs
+	}()
+
+	select {
+	case jobs := <-done:
+		require.Empty(t, jobs)
+	case <-time.After(time.Second):
+		t.Fatal("heartbeat of a worker without long_poll must not be held")
+	}
+}
+
+func TestPollWorkJobFeatures(t *testing.T) {
+	s := scheduler.NewScheduler(zaptest.NewLogger(t), defaultConfig, time.After)
+	defer s.Stop()
+
+	outputs := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: build.ID{01}, Outputs: []string{"lib.a"}}})
+	passEnv := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: build.ID{02}, Cmds: []build.Cmd{{PassEnv: []string{"HTTP_PROXY"}}}}})
+	plain := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: build.ID{03}}})
+
+	legacy := api.WithPeerProtocol(context.Background(), &api.Protocol{Version: 1})
+	req := &api.HeartbeatRequest{WorkerID: "worker0", FreeSlots: 3}
+	jobs, _ := pollWork(legacy, s, req)
+	require.Equal(t, []*scheduler.PendingJob{plain}, jobs, "old worker must not get jobs it would run incorrectly")
+
+	ctx := api.WithPeerProtocol(context.Background(), api.LocalProtocol())
+	req = &api.HeartbeatRequest{WorkerID: "worker1", FreeSlots: 3}
+	jobs, _ = pollWork(ctx, s, req)
+	require.ElementsMatch(t, []*scheduler.PendingJob{outputs, passEnv}, jobs)
+}
+
+func TestPollWorkDrain(t *testing.T) {
+	s := scheduler.NewScheduler(zaptest.NewLogger(t), defaultConfig, time.After)
+	defer s.Stop()
+
+	ctx := api.WithPeerProtocol(context.Background(), api.LocalProtocol())
+	jobID := build.ID{01}
+	job := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: jobID}})
+
+	req := &api.HeartbeatRequest{WorkerID: "worker0", FreeSlots: 1}
+	jobs, _ := pollWork(ctx, s, req)
+	require.Equal(t, []*scheduler.PendingJob{job}, jobs)
+
+	req = &api.HeartbeatRequest{WorkerID: "worker0", RunningJobs: []build.ID{jobID}, Draining: true}
+	jobs, _ = pollWork(ctx, s, req)
+	require.Empty(t, jobs)
+	require.False(t, drained(s, req), "job is still running")
+
+	require.True(t, s.OnJobComplete("worker0", jobID, &api.JobResult{ID: jobID}))
+	req = &api.HeartbeatRequest{WorkerID: "worker0", Draining: true}
+	_, _ = pollWork(ctx, s, req)
+	require.True(t, drained(s, req))
+
+	req = &api.HeartbeatRequest{WorkerID: "worker0", Leaving: true}
+	_, _ = pollWork(ctx, s, req)
+	_, ok := s.LocateArtifact(jobID)
+	require.False(t, ok, "artifacts of worker that left must be forgotten")
+}
+
+func TestPollWorkOverlappingHeartbeat(t *testing.T) {
+	s := scheduler.NewScheduler(zaptest.NewLogger(t), defaultConfig, time.After)
+	defer s.Stop()
+
+	ctx := api.WithPeerProtocol(context.Background(), api.LocalProtocol())
+	poll := &api.HeartbeatRequest{WorkerID: "worker0", FreeSlots: 1, PollTimeout: time.Minute, Beat: 1}
+
+	done := make(chan []*scheduler.PendingJob)
+	go func() {
+		jobs, _ := pollWork(ctx, s, poll)
+		done <- jobs
+	}()
+	job := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: build.ID{01}}})
+	require.Equal(t, []*scheduler.PendingJob{job}, <-done)
+
+	// Heartbeat с результатом ушёл раньше, чем воркер получил ответ на long-poll.
+	res := &api.HeartbeatRequest{WorkerID: "worker0", Beat: 2}
+	jobs, _ := pollWork(ctx, s, res)
+	require.Empty(t, jobs)
+
+	other := &api.HeartbeatRequest{WorkerID: "worker1", FreeSlots: 1}
+	jobs, _ = pollWork(ctx, s, other)
+	require.Empty(t, jobs, "job from pending long-poll response must not be requeued")
+
+	res = &api.HeartbeatRequest{WorkerID: "worker0", RunningJobs: []build.ID{{01}}, Beat: 3, Received: 2}
+	jobs, _ = pollWork(ctx, s, res)
+	require.Empty(t, jobs)
+	jobs, _ = pollWork(ctx, s, other)
+	require.Empty(t, jobs)
+}
+
+func TestPollWorkLongerThanWorkerTimeout(t *testing.T) {
+	config := defaultConfig
+	config.WorkerTimeout = 20 * time.Millisecond
+	s := scheduler.NewScheduler(zaptest.NewLogger(t), config, time.After)
+	defer s.Stop()
+
+	ctx := api.WithPeerProtocol(context.Background(), api.LocalProtocol())
+	req := &api.HeartbeatRequest{WorkerID: "worker0", FreeSlots: 1, PollTimeout: 10 * config.WorkerTimeout}
+	s.OnArtifactsAdded("worker0", []build.ID{{01}})
+
+	jobs, _ := pollWork(ctx, s, req)
+	require.Empty(t, jobs)
+
+	_, ok := s.LocateArtifact(build.ID{01})
+	require.True(t, ok, "worker holding a long-poll must not be lost")
+}
@@ -0,0 +1,349 @@
+package dist
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+)
+
+// statusLog хранит все обновления статуса одной сборки.
+//
+// Каждое обновление получает порядковый номер Seq, начиная с 1. Журнал живёт дольше, чем
+// HTTP запрос /build, поэтому клиент с оборвавшимся соединением может переподключиться
+// через /build/status и дочитать обновления без пропусков и повторов.
+//
+// Исключение - куски вывода JobOutput: полный вывод джоба всё равно приходит в JobFinished, поэтому
+// журнал забывает куски завершённых джобов и самые старые куски сверх maxLoggedOutput. Самый старый кусок
+// всегда первый из оставшихся кусков своего джоба, поэтому джоб теряет только начало вывода. Клиент, который
+// переподключился, забытых кусков не получит: вместо них в следующем куске того же джоба он увидит пометку
+// о пропуске, см. stream.
+type statusLog struct {
+	clock clockwork.Clock
+
+	mu       sync.Mutex
+	updates  []*api.StatusUpdate
+	finished bool
+	watchers int
+	detached time.Time
+	wakeup   chan struct{}
+
+	// outputs - номера ещё не забытых обновлений с JobOutput по порядку, outputSize - их суммарный размер.
+	outputs    []uint64
+	outputSize int
+	// forgotten - джоб и размер каждого забытого куска вывода по его номеру.
+	forgotten map[uint64]forgottenOutput
+
+	// onFinished вызывается один раз, когда в журнал попадает BuildFinished или BuildFailed.
+	onFinished func()
+}
+
+// DetachedBuildTimeout - сколько координатор ждёт, пока клиент переподключится к сборке, прежде чем её отменить.
+const DetachedBuildTimeout = time.Minute
+
+// FinishedBuildRetention - сколько координатор хранит журнал завершённой сборки.
+const FinishedBuildRetention = 10 * time.Minute
+
+// maxLoggedOutput ограничивает суммарный размер кусков вывода, которые журнал хранит для переподключения.
+const maxLoggedOutput = 1 << 20
+
+type forgottenOutput struct {
+	id   build.ID
+	size int
+}
+
+func newStatusLog(clock clockwork.Clock) *statusLog {
+	return &statusLog{
+		clock:     clock,
+		detached:  clock.Now(),
+		wakeup:    make(chan struct{}),
+		forgotten: make(map[uint64]forgottenOutput),
+	}
+}
+
+// append нумерует обновление и добавляет его в журнал. Обновления после BuildFinished
+// или BuildFailed игнорируются.
+func (l *statusLog) append(update *api.StatusUpdate) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.finished {
+		return
+	}
+
+	update.Seq = uint64(len(l.updates)) + 1
+	l.updates = append(l.updates, update)
+	l.compact(update)
+
+	l.finished = update.BuildFinished != nil || update.BuildFailed != nil
+	if l.finished && l.onFinished != nil {
+		l.onFinished()
+	}
+	l.notify()
+}
+
+// compact забывает куски вывода, которые больше не нужны после update. Вызывается под l.mu.
+func (l *statusLog) compact(update *api.StatusUpdate) {
+	switch {
+	case update.JobOutput != nil:
+		l.outputs = append(l.outputs, update.Seq)
+		l.outputSize += outputSize(update.JobOutput)
+		for l.outputSize > maxLoggedOutput {
+			l.forget(l.outputs[0])
+			l.outputs = l.outputs[1:]
+		}
+
+	case update.JobFinished != nil:
+		l.outputs = slices.DeleteFunc(l.outputs, func(seq uint64) bool {
+			if l.updates[seq-1].JobOutput.ID != update.JobFinished.ID {
+				return false
+			}
+			l.forget(seq)
+			return true
+		})
+
+	case update.BuildFinished != nil, update.BuildFailed != nil:
+		for _, seq := range l.outputs {
+			l.forget(seq)
+		}
+		l.outputs = nil
+	}
+}
+
+// forget убирает из журнала обновление seq, оставляя его номер за ним. Вызывается под l.mu.
+func (l *statusLog) forget(seq uint64) {
+	output := l.updates[seq-1].JobOutput
+	l.outputSize -= outputSize(output)
+	l.forgotten[seq] = forgottenOutput{id: output.ID, size: outputSize(output)}
+	l.updates[seq-1] = nil
+}
+
+func outputSize(output *api.JobOutput) int {
+	return len(output.Stdout) + len(output.Stderr)
+}
+
+// notify будит stream и waitDetached. Вызывается под l.mu.
+func (l *statusLog) notify() {
+	close(l.wakeup)
+	l.wakeup = make(chan struct{})
+}
+
+// watch учитывает клиента, который читает журнал, и возвращает функцию, которая его отключает.
+func (l *statusLog) watch() func() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.watchers++
+	l.notify()
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		l.watchers--
+		if l.watchers == 0 {
+			l.detached = l.clock.Now()
+		}
+		l.notify()
+	}
+}
+
+// waitDetached возвращает true, если сборка не завершилась, а журнал никто не читает уже timeout.
+// Такую сборку координатор отменяет: клиент от неё отключился и не переподключился. Возвращает false,
+// если сборка завершилась или отменён ctx.
+func (l *statusLog) waitDetached(ctx context.Context, timeout time.Duration) bool {
+	for {
+		l.mu.Lock()
+		finished, watchers, detached, wakeup := l.finished, l.watchers, l.detached, l.wakeup
+		l.mu.Unlock()
+
+		if finished {
+			return false
+		}
+
+		var timer clockwork.Timer
+		var expired <-chan time.Time
+		if watchers == 0 {
+			left := detached.Add(timeout).Sub(l.clock.Now())
+			if left <= 0 {
+				return true
+			}
+			timer = l.clock.NewTimer(left)
+			expired = timer.Chan()
+		}
+
+		select {
+		case <-expired:
+		case <-wakeup:
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+	}
+}
+
+// stream пишет в w обновления, начиная с номера fromSeq, пока сборка не завершится.
+// Пока stream работает, сборка считается подключённой, см. waitDetached.
+//
+// Если журнал забыл куски вывода джоба, которые клиент не получил, stream дописывает в начало Stdout
+// следующего куска этого джоба пометку с числом пропущенных байт. Если джоб завершился, пометка не нужна:
+// полный вывод клиент получит в JobFinished.
+//
+// Поля, которые клиент из api.PeerProtocol(ctx) не поддерживает, stream убирает, см. peerStatusWriter.
+func (l *statusLog) stream(ctx context.Context, fromSeq uint64, w api.StatusWriter) error {
+	defer l.watch()()
+
+	if peer := api.PeerProtocol(ctx); peer != nil {
+		w = &peerStatusWriter{StatusWriter: w, peer: peer}
+	}
+
+	next := fromSeq
+	if next == 0 {
+		next = 1
+	}
+
+	// skipped - сколько байт вывода каждого джоба клиент пропустил.
+	skipped := make(map[build.ID]int)
+
+	for {
+		l.mu.Lock()
+		var updates []*api.StatusUpdate
+		var forgotten []forgottenOutput
+		if next <= uint64(len(l.updates)) {
+			// Копия: compact может забыть обновление, пока мы пишем в w.
+			updates = slices.Clone(l.updates[next-1:])
+			forgotten = make([]forgottenOutput, len(updates))
+			for i, update := range updates {
+				if update == nil {
+					forgotten[i] = l.forgotten[next+uint64(i)]
+				}
+			}
+		}
+		finished := l.finished
+		wakeup := l.wakeup
+		l.mu.Unlock()
+
+		for i, update := range updates {
+			next++
+			switch {
+			case update == nil:
+				// Кусок вывода, который журнал уже забыл.
+				skipped[forgotten[i].id] += forgotten[i].size
+				continue
+
+			case update.JobFinished != nil:
+				delete(skipped, update.JobFinished.ID)
+
+			case update.JobOutput != nil && skipped[update.JobOutput.ID] > 0:
+				update = withSkipped(update, skipped[update.JobOutput.ID])
+				delete(skipped, update.JobOutput.ID)
+			}
+
+			if err := w.Updated(update); err != nil {
+				return err
+			}
+		}
+
+		if finished {
+			return nil
+		}
+
+		select {
+		case <-wakeup:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// withSkipped возвращает копию update с пометкой о skipped пропущенных байтах вывода в начале Stdout.
+func withSkipped(update *api.StatusUpdate, skipped int) *api.StatusUpdate {
+	output := *update.JobOutput
+	output.Stdout = append([]byte(fmt.Sprintf("... [%d bytes skipped] ...\n", skipped)), output.Stdout...)
+
+	marked := *update
+	marked.JobOutput = &output
+	return &marked
+}
+
+// peerStatusWriter убирает из обновлений поля, которых нет в протоколе клиента: старый клиент принял бы
+// обновление только с JobOutput или JobRetried за пустое. Обновления, в которых ничего не осталось,
+// клиенту не пишутся.
+type peerStatusWriter struct {
+	api.StatusWriter
+	peer *api.Protocol
+}
+
+func (w *peerStatusWriter) Updated(update *api.StatusUpdate) error {
+	filtered := *update
+	if !w.peer.Has(api.FeatureReattach) {
+		filtered.Seq = 0
+	}
+	if !w.peer.Has(api.FeatureJobOutput) {
+		filtered.JobOutput = nil
+	}
+	if !w.peer.Has(api.FeatureRetry) {
+		filtered.JobRetried = nil
+	}
+
+	if filtered.JobOutput == nil && filtered.JobRetried == nil && filtered.JobFinished == nil &&
+		filtered.BuildFailed == nil && filtered.BuildFinished == nil {
+		return nil
+	}
+	return w.StatusWriter.Updated(&filtered)
+}
+
+// statusLogs хранит журналы сборок координатора. Журнал завершённой сборки забывается
+// через retention после её завершения.
+type statusLogs struct {
+	clock     clockwork.Clock
+	retention time.Duration
+
+	mu   sync.Mutex
+	logs map[build.ID]*statusLog
+}
+
+func newStatusLogs(clock clockwork.Clock, retention time.Duration) *statusLogs {
+	return &statusLogs{clock: clock, retention: retention, logs: make(map[build.ID]*statusLog)}
+}
+
+// create заводит журнал сборки buildID.
+func (s *statusLogs) create(buildID build.ID) *statusLog {
+	l := newStatusLog(s.clock)
+	l.onFinished = func() {
+		s.clock.AfterFunc(s.retention, func() { s.release(buildID, l) })
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.logs[buildID] = l
+	return l
+}
+
+// get возвращает журнал сборки buildID. Возвращает false, если сборка неизвестна или её журнал уже забыт.
+func (s *statusLogs) get(buildID build.ID) (*statusLog, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.logs[buildID]
+	return l, ok
+}
+
+func (s *statusLogs) release(buildID build.ID, l *statusLog) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.logs[buildID] == l {
+		delete(s.logs, buildID)
+	}
+}
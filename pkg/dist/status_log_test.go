@@ -0,0 +1,207 @@
+package dist
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+)
+
+type updatesRecorder struct {
+	api.StatusWriter
+
+	seqs    []uint64
+	updates []*api.StatusUpdate
+}
+
+func (r *updatesRecorder) Updated(update *api.StatusUpdate) error {
+	r.seqs = append(r.seqs, update.Seq)
+	r.updates = append(r.updates, update)
+	return nil
+}
+
+func TestStatusLog(t *testing.T) {
+	l := newStatusLog(clockwork.NewFakeClock())
+	l.append(&api.StatusUpdate{JobFinished: &api.JobResult{ID: build.ID{'a'}}})
+	l.append(&api.StatusUpdate{JobFinished: &api.JobResult{ID: build.ID{'b'}}})
+
+	done := make(chan error)
+	var live updatesRecorder
+	go func() {
+		done <- l.stream(context.Background(), 0, &live)
+	}()
+
+	l.append(&api.StatusUpdate{BuildFinished: &api.BuildFinished{}})
+	l.append(&api.StatusUpdate{BuildFailed: &api.BuildFailed{Error: "too late"}})
+
+	require.NoError(t, <-done)
+	require.Equal(t, []uint64{1, 2, 3}, live.seqs)
+
+	var reattached updatesRecorder
+	require.NoError(t, l.stream(context.Background(), 2, &reattached))
+	require.Equal(t, []uint64{2, 3}, reattached.seqs)
+
+	var past updatesRecorder
+	require.NoError(t, l.stream(context.Background(), 10, &past))
+	require.Empty(t, past.seqs)
+}
+
+func blockUntil(t *testing.T, clock *clockwork.FakeClock, n int) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, clock.BlockUntilContext(ctx, n))
+}
+
+func TestStatusLogCancel(t *testing.T) {
+	l := newStatusLog(clockwork.NewFakeClock())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var r updatesRecorder
+	require.ErrorIs(t, l.stream(ctx, 0, &r), context.Canceled)
+}
+
+func TestStatusLogDetached(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	l := newStatusLog(clock)
+
+	detached := make(chan bool)
+	go func() {
+		detached <- l.waitDetached(context.Background(), time.Minute)
+	}()
+	blockUntil(t, clock, 1)
+	clock.Advance(time.Minute)
+	require.True(t, <-detached)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	streamed := make(chan error)
+	go func() {
+		streamed <- l.stream(ctx, 0, &updatesRecorder{})
+	}()
+	require.Eventually(t, func() bool {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.watchers == 1
+	}, time.Second, time.Millisecond)
+
+	// Пока клиент подключён, сборка не отменяется, сколько бы она ни шла.
+	go func() {
+		detached <- l.waitDetached(context.Background(), time.Minute)
+	}()
+	clock.Advance(time.Hour)
+	select {
+	case <-detached:
+		t.Fatal("build with a watcher is detached")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// Таймаут отсчитывается от момента, когда отключился последний клиент.
+	cancel()
+	require.ErrorIs(t, <-streamed, context.Canceled)
+	blockUntil(t, clock, 1)
+	clock.Advance(time.Minute - time.Second)
+	select {
+	case <-detached:
+		t.Fatal("build is detached before the timeout")
+	case <-time.After(10 * time.Millisecond):
+	}
+	clock.Advance(time.Second)
+	require.True(t, <-detached)
+
+	l.append(&api.StatusUpdate{BuildFinished: &api.BuildFinished{}})
+	require.False(t, l.waitDetached(context.Background(), 0))
+}
+
+func TestStatusLogCompactsOutput(t *testing.T) {
+	l := newStatusLog(clockwork.NewFakeClock())
+
+	jobA, jobB := build.ID{'a'}, build.ID{'b'}
+	chunk := make([]byte, maxLoggedOutput/2)
+	l.append(&api.StatusUpdate{JobOutput: &api.JobOutput{ID: jobA, Stdout: chunk}})
+	l.append(&api.StatusUpdate{JobOutput: &api.JobOutput{ID: jobB, Stdout: chunk}})
+	l.append(&api.StatusUpdate{JobOutput: &api.JobOutput{ID: jobA, Stderr: chunk}})
+	l.append(&api.StatusUpdate{JobFinished: &api.JobResult{ID: jobB}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Первый кусок вытеснен по размеру, второй забыт вместе с завершённым джобом.
+	var r updatesRecorder
+	require.ErrorIs(t, l.stream(ctx, 0, &r), context.Canceled)
+	require.Equal(t, []uint64{3, 4}, r.seqs)
+	require.Equal(t, fmt.Sprintf("... [%d bytes skipped] ...\n", len(chunk)), string(r.updates[0].JobOutput.Stdout),
+		"client must see that the beginning of the output is lost")
+	require.Equal(t, chunk, r.updates[0].JobOutput.Stderr)
+
+	// Клиент, который получил первый кусок, пометку не получает.
+	r.seqs, r.updates = nil, nil
+	require.ErrorIs(t, l.stream(ctx, 3, &r), context.Canceled)
+	require.Empty(t, r.updates[0].JobOutput.Stdout)
+	require.Equal(t, maxLoggedOutput/2, l.outputSize)
+
+	l.append(&api.StatusUpdate{BuildFinished: &api.BuildFinished{}})
+	require.Zero(t, l.outputSize)
+
+	r.seqs = nil
+	require.NoError(t, l.stream(context.Background(), 0, &r))
+	require.Equal(t, []uint64{4, 5}, r.seqs)
+}
+
+func TestStatusLogsRelease(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	logs := newStatusLogs(clock, time.Minute)
+
+	running, finished := build.ID{'r'}, build.ID{'f'}
+	logs.create(running)
+	logs.create(finished).append(&api.StatusUpdate{BuildFinished: &api.BuildFinished{}})
+
+	clock.Advance(time.Minute - time.Second)
+	_, ok := logs.get(finished)
+	require.True(t, ok, "finished build must be kept for a while")
+
+	clock.Advance(time.Second)
+	require.Eventually(t, func() bool {
+		_, ok := logs.get(finished)
+		return !ok
+	}, time.Second, time.Millisecond)
+
+	_, ok = logs.get(running)
+	require.True(t, ok, "running build must be kept")
+}
+
+func TestStatusLogLegacyClient(t *testing.T) {
+	l := newStatusLog(clockwork.NewFakeClock())
+
+	jobID := build.ID{'a'}
+	l.append(&api.StatusUpdate{JobOutput: &api.JobOutput{ID: jobID, Stdout: []byte("hello")}})
+	l.append(&api.StatusUpdate{JobRetried: &api.JobRetry{ID: jobID, Attempt: 2}})
+
+	legacyCtx, cancel := context.WithCancel(api.WithPeerProtocol(context.Background(), &api.Protocol{Version: 1}))
+	cancel()
+	var legacy updatesRecorder
+	require.ErrorIs(t, l.stream(legacyCtx, 0, &legacy), context.Canceled)
+	require.Empty(t, legacy.updates, "old client must not get updates it does not understand")
+
+	ctx, cancel := context.WithCancel(api.WithPeerProtocol(context.Background(), api.LocalProtocol()))
+	cancel()
+	var current updatesRecorder
+	require.ErrorIs(t, l.stream(ctx, 0, &current), context.Canceled)
+	require.Equal(t, []uint64{1, 2}, current.seqs)
+
+	l.append(&api.StatusUpdate{JobFinished: &api.JobResult{ID: jobID}})
+	l.append(&api.StatusUpdate{BuildFinished: &api.BuildFinished{}})
+
+	legacyCtx = api.WithPeerProtocol(context.Background(), &api.Protocol{Version: 1})
+	require.NoError(t, l.stream(legacyCtx, 0, &legacy))
+	require.Equal(t, []*api.StatusUpdate{
+		{JobFinished: &api.JobResult{ID: jobID}},
+		{BuildFinished: &api.BuildFinished{}},
+	}, legacy.updates)
+}
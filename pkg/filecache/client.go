@@ -11,13 +11,15 @@ import (
 
 	"go.uber.org/zap"
 
+	"distributed_build/pkg/auth"
 	"distributed_build/pkg/build"
 )
 
 type Client struct {
-	client   *http.Client
-	logger   *zap.Logger
-	endpoint string
+	client      *http.Client
+	logger      *zap.Logger
+	endpoint    string
+	credentials auth.Credentials
 }
 
 func NewClient(l *zap.Logger, endpoint string) *Client {
@@ -27,6 +29,24 @@ func NewClient(l *zap.Logger, endpoint string) *Client {
 		endpoint: endpoint,
 	}
 }
+
+// SetHTTPClient задаёт HTTP клиент, например с настройками mTLS. Вызывается до первого запроса.
+func (c *Client) SetHTTPClient(client *http.Client) {
+	c.client = client
+}
+
+// SetCredentials задаёт токен, который клиент посылает с каждым запросом. Вызывается до первого запроса.
+func (c *Client) SetCredentials(creds auth.Credentials) {
+	c.credentials = creds
+}
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if err := auth.Authorize(req, c.credentials); err != nil {
+		return nil, err
+	}
+	return c.client.Do(req)
+}
+
 func (c *Client) Upload(ctx context.Context, id build.ID, localPath string) error {
 	file, err := os.Open(localPath)
 	if err != nil {
@@ -35,7 +55,7 @@ func (c *Client) Upload(ctx context.Context, id build.ID, localPath string) erro
 	}
 	defer file.Close()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.endpoint+"/file?id="+id.String(), file)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.fileURL(id), file)
 	if err != nil {
 		c.logger.Error("failed to create request", zap.Error(err))
 		return err
@@ -43,7 +63,7 @@ func (c *Client) Upload(ctx context.Context, id build.ID, localPath string) erro
 
 	req.Header.Set("Content-Type", "application/octet-stream")
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		c.logger.Error("failed to perform request", zap.Error(err))
 		return err
@@ -61,39 +81,158 @@ func (c *Client) Upload(ctx context.Context, id build.ID, localPath string) erro
 	return nil
 }
 
-func (c *Client) Download(ctx context.Context, localCache *Cache, id build.ID) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+"/file?id="+id.String(), nil)
+// maxDownloadAttempts ограничивает число попыток докачать файл после обрыва соединения.
+const maxDownloadAttempts = 5
+
+func (c *Client) fileURL(id build.ID) string {
+	return c.endpoint + "/file?id=" + id.String()
+}
+
+// Exists проверяет наличие файла на сервере, не скачивая его содержимое.
+func (c *Client) Exists(ctx context.Context, id build.ID) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, c.fileURL(id), nil)
 	if err != nil {
 		c.logger.Error("failed to create request", zap.Error(err))
-		return err
+		return false, err
 	}
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		c.logger.Error("failed to perform request", zap.Error(err))
-		return err
+		return false, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		errorData, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return fmt.Errorf("failed to read error response: %w", err)
-		}
-		return fmt.Errorf("download failed with status: %s", string(errorData))
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("head failed with status: %s", resp.Status)
 	}
+}
 
-	writer, abort, err := localCache.Write(id)
-	if err != nil {
-		c.logger.Error("failed to get writer for local cache", zap.Error(err))
-		return err
+// Download скачивает файл в локальный кеш.
+//
+// Если файл уже есть в локальном кеше, запрос делается с If-None-Match и файл не перезаписывается.
+// Если соединение оборвалось посреди скачивания, Download докачивает оставшуюся часть через Range.
+// Запрос на докачку, который не дошёл до сервера, повторяется с того же места.
+func (c *Client) Download(ctx context.Context, localCache *Cache, id build.ID) error {
+	_, unlock, err := localCache.Get(id)
+	cached := err == nil
+	if cached {
+		unlock()
 	}
-	defer writer.Close()
-	if _, err := io.Copy(writer, resp.Body); err != nil {
-		c.logger.Error("failed write to local cache", zap.Error(err))
-		abort()
-		return err
+
+	var (
+		writer  io.WriteCloser
+		abort   func() error
+		written int64
+	)
+	defer func() {
+		if abort != nil {
+			_ = abort()
+		}
+	}()
+
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.fileURL(id), nil)
+		if err != nil {
+			c.logger.Error("failed to create request", zap.Error(err))
+			return err
+		}
+		if cached {
+			req.Header.Set("If-None-Match", etag(id))
+		}
+		if written > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", written))
+			req.Header.Set("If-Range", etag(id))
+		}
+
+		resp, err := c.do(req)
+		if err != nil {
+			if written == 0 || attempt >= maxDownloadAttempts || ctx.Err() != nil {
+				c.logger.Error("failed to perform request", zap.Error(err))
+				return err
+			}
+			// Докачка не удалась, но уже скачанная часть файла остаётся: пробуем снова с того же места.
+			c.logger.Warn("failed to resume download, retrying",
+				zap.String("id", id.String()),
+				zap.Int64("offset", written),
+				zap.Error(err))
+			continue
+		}
+
+		switch resp.StatusCode {
+		case http.StatusNotModified:
+			resp.Body.Close()
+			c.logger.Info("file is up to date in local cache", zap.String("id", id.String()))
+			return nil
+
+		case http.StatusOK:
+			if abort != nil {
+				// Сервер проигнорировал Range, начинаем запись заново.
+				_ = abort()
+				abort = nil
+			}
+			if cached {
+				if err := localCache.Remove(id); err != nil {
+					resp.Body.Close()
+					c.logger.Error("failed to remove stale file from local cache", zap.Error(err))
+					return err
+				}
+				cached = false
+			}
+
+			writer, abort, err = localCache.Write(id)
+			if err != nil {
+				resp.Body.Close()
+				c.logger.Error("failed to get writer for local cache", zap.Error(err))
+				return err
+			}
+			written = 0
+
+		case http.StatusPartialContent:
+			if start, ok := rangeStart(resp.Header.Get("Content-Range")); !ok || start != written {
+				resp.Body.Close()
+				return fmt.Errorf("unexpected content range %q, expected offset %d", resp.Header.Get("Content-Range"), written)
+			}
+
+		default:
+			errorData, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return fmt.Errorf("failed to read error response: %w", err)
+			}
+			return fmt.Errorf("download failed with status: %s", string(errorData))
+		}
+
+		n, err := io.Copy(writer, resp.Body)
+		resp.Body.Close()
+		written += n
+
+		if err == nil {
+			abort = nil
+			return writer.Close()
+		}
+
+		if attempt >= maxDownloadAttempts || ctx.Err() != nil {
+			c.logger.Error("failed write to local cache", zap.Error(err))
+			return err
+		}
+		c.logger.Warn("download interrupted, resuming",
+			zap.String("id", id.String()),
+			zap.Int64("offset", written),
+			zap.Error(err))
 	}
-	return nil
+}
 
+// rangeStart разбирает начало диапазона из заголовка вида "bytes 100-199/200".
+func rangeStart(contentRange string) (int64, bool) {
+	var start, end, size int64
+	if _, err := fmt.Sscanf(contentRange, "bytes %d-%d/%d", &start, &end, &size); err != nil {
+		return 0, false
+	}
+	return start, true
 }
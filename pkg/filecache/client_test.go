@@ -3,6 +3,7 @@ package filecache_test
 import (
 	"bytes"
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -14,6 +15,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap/zaptest"
 
+	"distributed_build/pkg/auth"
 	"distributed_build/pkg/build"
 	"distributed_build/pkg/filecache"
 )
@@ -127,3 +129,193 @@ func TestFileDownload(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, []byte("foobar"), content)
 }
+
+func TestFileExists(t *testing.T) {
+	env := newEnv(t)
+	ctx := context.Background()
+
+	id := build.ID{0x01}
+	writeFile(t, env.cache, id, "foobar")
+
+	ok, err := env.client.Exists(ctx, id)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = env.client.Exists(ctx, build.ID{0x02})
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+// abortingWriter drops the connection after limit bytes of the body were written.
+type abortingWriter struct {
+	http.ResponseWriter
+	limit int
+}
+
+func (w *abortingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		n, _ := w.ResponseWriter.Write(p[:w.limit])
+		w.limit -= n
+		http.NewResponseController(w.ResponseWriter).Flush()
+		panic(http.ErrAbortHandler)
+	}
+
+	n, err := w.ResponseWriter.Write(p)
+	w.limit -= n
+	return n, err
+}
+
+func TestFileDownloadResume(t *testing.T) {
+	l := zaptest.NewLogger(t)
+	remoteCache := newCache(t)
+	localCache := newCache(t)
+
+	mux := http.NewServeMux()
+	filecache.NewHandler(l, remoteCache.Cache).Register(mux)
+
+	var (
+		mu       sync.Mutex
+		requests []*http.Request
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r)
+		first := len(requests) == 1
+		mu.Unlock()
+
+		if first {
+			w = &abortingWriter{ResponseWriter: w, limit: 1024 * 1024}
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	client := filecache.NewClient(l, server.URL)
+
+	id := build.ID{0x01}
+	content := bytes.Repeat([]byte("foobar"), 1024*1024)
+	writeFile(t, remoteCache, id, string(content))
+
+	ctx := context.Background()
+	require.NoError(t, client.Download(ctx, localCache.Cache, id))
+
+	path, unlock, err := localCache.Get(id)
+	require.NoError(t, err)
+	actualContent, err := os.ReadFile(path)
+	unlock()
+	require.NoError(t, err)
+	require.Equal(t, content, actualContent)
+
+	require.Len(t, requests, 2)
+	require.Empty(t, requests[0].Header.Get("Range"))
+	require.Regexp(t, `^bytes=[1-9]\d*-$`, requests[1].Header.Get("Range"))
+
+	// File is already cached locally, so repeated download is a conditional request.
+	require.NoError(t, client.Download(ctx, localCache.Cache, id))
+	require.Len(t, requests, 3)
+	require.Equal(t, `"`+id.String()+`"`, requests[2].Header.Get("If-None-Match"))
+}
+
+// failingTransport обрывает запросы с номерами из fail.
+type failingTransport struct {
+	mu       sync.Mutex
+	requests int
+	fail     map[int]bool
+}
+
+func (f *failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	f.requests++
+	fail := f.fail[f.requests]
+	f.mu.Unlock()
+
+	if fail {
+		return nil, errors.New("connection refused")
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestFileDownloadResumeReconnect(t *testing.T) {
+	l := zaptest.NewLogger(t)
+	remoteCache := newCache(t)
+	localCache := newCache(t)
+
+	mux := http.NewServeMux()
+	filecache.NewHandler(l, remoteCache.Cache).Register(mux)
+
+	var (
+		mu     sync.Mutex
+		ranges []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		first := len(ranges) == 1
+		mu.Unlock()
+
+		if first {
+			w = &abortingWriter{ResponseWriter: w, limit: 1024 * 1024}
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	// Первая докачка не доходит до сервера.
+	client := filecache.NewClient(l, server.URL)
+	client.SetHTTPClient(&http.Client{Transport: &failingTransport{fail: map[int]bool{2: true}}})
+
+	id := build.ID{0x01}
+	content := bytes.Repeat([]byte("foobar"), 1024*1024)
+	writeFile(t, remoteCache, id, string(content))
+
+	require.NoError(t, client.Download(context.Background(), localCache.Cache, id))
+
+	path, unlock, err := localCache.Get(id)
+	require.NoError(t, err)
+	actualContent, err := os.ReadFile(path)
+	unlock()
+	require.NoError(t, err)
+	require.Equal(t, content, actualContent)
+
+	require.Len(t, ranges, 2)
+	require.Empty(t, ranges[0])
+	require.Regexp(t, `^bytes=[1-9]\d*-$`, ranges[1], "download must resume from the partial file")
+}
+
+func TestFileAuth(t *testing.T) {
+	l := zaptest.NewLogger(t)
+	cache := newCache(t)
+
+	tokens := auth.NewStaticTokens()
+	tokens.Add("client-token", auth.Identity{Subject: "alice", Role: auth.RoleClient})
+	tokens.Add("worker-token", auth.Identity{Subject: "worker0", Role: auth.RoleWorker})
+
+	mux := http.NewServeMux()
+	handler := filecache.NewHandler(l, cache.Cache)
+	handler.RequireAuth(tokens)
+	handler.Register(mux)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tmpFilePath := filepath.Join(cache.tmpDir, "foo.txt")
+	require.NoError(t, os.WriteFile(tmpFilePath, []byte("foo"), 0666))
+
+	ctx := context.Background()
+	id := build.ID{0x01}
+
+	client := filecache.NewClient(l, server.URL)
+	require.Error(t, client.Upload(ctx, id, tmpFilePath), "upload without token must be rejected")
+
+	client.SetCredentials(auth.Token("worker-token"))
+	require.Error(t, client.Upload(ctx, id, tmpFilePath), "workers must not upload source files")
+
+	client.SetCredentials(auth.Token("client-token"))
+	require.NoError(t, client.Upload(ctx, id, tmpFilePath))
+
+	worker := filecache.NewClient(l, server.URL)
+	worker.SetCredentials(auth.Token("worker-token"))
+
+	downloadCache := newCache(t)
+	require.NoError(t, worker.Download(ctx, downloadCache.Cache, id))
+}
@@ -0,0 +1,223 @@
+package filecache
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"go.uber.org/zap"
+
+	"distributed_build/pkg/build"
+)
+
+type GCConfig struct {
+	// Retention задаёт, сколько времени файлы завершённой сборки остаются в кеше.
+	//
+	// Этот же интервал защищает недавно залитые файлы, на которые ещё не сослалась ни одна сборка.
+	Retention time.Duration
+
+	// Interval задаёт период запуска сборки мусора в Run. Ноль означает DefaultGCInterval.
+	Interval time.Duration
+}
+
+// DefaultGCInterval - период сборки мусора, если GCConfig.Interval не задан.
+const DefaultGCInterval = time.Minute
+
+// GCReport описывает результат одного прохода сборки мусора.
+type GCReport struct {
+	DryRun bool
+
+	// Removed перечисляет файлы, которые были удалены (или были бы удалены в режиме DryRun).
+	Removed []build.ID
+
+	// Locked перечисляет файлы, которые не удалось удалить, потому что их кто-то читает или пишет.
+	Locked []build.ID
+
+	// ReclaimedBytes - суммарный размер файлов из Removed.
+	ReclaimedBytes int64
+}
+
+type buildRefs struct {
+	files    map[build.ID]struct{}
+	finished time.Time
+}
+
+// GC удаляет из кеша файлы, на которые не ссылается ни одна активная или недавно завершённая сборка.
+type GC struct {
+	logger *zap.Logger
+	cache  *Cache
+	config GCConfig
+	clock  clockwork.Clock
+
+	mu     sync.Mutex
+	builds map[build.ID]*buildRefs
+	// live считает, сколько сборок из builds ссылается на файл.
+	live map[build.ID]int
+}
+
+func NewGC(l *zap.Logger, cache *Cache, config GCConfig, clock clockwork.Clock) *GC {
+	if config.Interval <= 0 {
+		config.Interval = DefaultGCInterval
+	}
+
+	return &GC{
+		logger: l,
+		cache:  cache,
+		config: config,
+		clock:  clock,
+		builds: make(map[build.ID]*buildRefs),
+		live:   make(map[build.ID]int),
+	}
+}
+
+// Reference запоминает, что активная сборка buildID использует файлы files.
+func (g *GC) Reference(buildID build.ID, files []build.ID) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	refs, ok := g.builds[buildID]
+	if !ok {
+		refs = &buildRefs{files: make(map[build.ID]struct{})}
+		g.builds[buildID] = refs
+	}
+	for _, id := range files {
+		if _, ok := refs.files[id]; !ok {
+			refs.files[id] = struct{}{}
+			g.live[id]++
+		}
+	}
+	refs.finished = time.Time{}
+}
+
+// Release помечает сборку завершённой. Её файлы хранятся ещё Retention после этого момента.
+func (g *GC) Release(buildID build.ID) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if refs, ok := g.builds[buildID]; ok {
+		refs.finished = g.clock.Now()
+	}
+}
+
+// expire забывает сборки, завершившиеся больше Retention назад.
+func (g *GC) expire(now time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for buildID, refs := range g.builds {
+		if refs.finished.IsZero() || now.Sub(refs.finished) <= g.config.Retention {
+			continue
+		}
+
+		delete(g.builds, buildID)
+		for id := range refs.files {
+			if g.live[id]--; g.live[id] == 0 {
+				delete(g.live, id)
+			}
+		}
+	}
+}
+
+func (g *GC) isLive(id build.ID) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.live[id] > 0
+}
+
+// remove удаляет файл id, если на него так и не сослалась ни одна сборка. Проверка и удаление идут под g.mu,
+// поэтому Reference, пришедший во время прохода, файл не потеряет.
+func (g *GC) remove(id build.ID) (removed bool, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.live[id] > 0 {
+		return false, nil
+	}
+	return true, g.cache.Remove(id)
+}
+
+func (g *GC) stat(id build.ID) (os.FileInfo, error) {
+	path, unlock, err := g.cache.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return os.Stat(path)
+}
+
+// Collect удаляет из кеша файлы, не используемые ни одной сборкой.
+//
+// В режиме dryRun файлы не удаляются, а отчёт описывает, сколько места можно освободить.
+func (g *GC) Collect(dryRun bool) (*GCReport, error) {
+	now := g.clock.Now()
+	g.expire(now)
+	report := &GCReport{DryRun: dryRun}
+
+	err := g.cache.Range(func(id build.ID) error {
+		if g.isLive(id) {
+			return nil
+		}
+
+		info, err := g.stat(id)
+		switch {
+		case errors.Is(err, ErrWriteLocked):
+			report.Locked = append(report.Locked, id)
+			return nil
+		case errors.Is(err, ErrNotFound), os.IsNotExist(err):
+			return nil
+		case err != nil:
+			return err
+		}
+
+		if now.Sub(info.ModTime()) <= g.config.Retention {
+			return nil
+		}
+
+		if !dryRun {
+			removed, err := g.remove(id)
+			switch {
+			case errors.Is(err, ErrReadLocked), errors.Is(err, ErrWriteLocked):
+				report.Locked = append(report.Locked, id)
+				return nil
+			case err != nil:
+				return err
+			case !removed:
+				return nil
+			}
+		}
+
+		report.Removed = append(report.Removed, id)
+		report.ReclaimedBytes += info.Size()
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	g.logger.Info("file cache gc finished",
+		zap.Bool("dry_run", dryRun),
+		zap.Int("removed", len(report.Removed)),
+		zap.Int("locked", len(report.Locked)),
+		zap.Int64("reclaimed_bytes", report.ReclaimedBytes))
+	return report, nil
+}
+
+// Run периодически запускает Collect, пока не будет отменён ctx.
+func (g *GC) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-g.clock.After(g.config.Interval):
+		}
+
+		if _, err := g.Collect(false); err != nil {
+			g.logger.Error("file cache gc failed", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package filecache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"distributed_build/pkg/build"
+	"distributed_build/pkg/filecache"
+)
+
+func writeFile(t *testing.T, cache *testCache, id build.ID, content string) {
+	w, _, err := cache.Write(id)
+	require.NoError(t, err)
+
+	_, err = w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+}
+
+func requireExists(t *testing.T, cache *testCache, id build.ID, exists bool) {
+	_, unlock, err := cache.Get(id)
+	if exists {
+		require.NoError(t, err)
+		unlock()
+	} else {
+		require.Truef(t, errors.Is(err, filecache.ErrNotFound), "%v", err)
+	}
+}
+
+func TestGC(t *testing.T) {
+	cache := newCache(t)
+	clock := clockwork.NewFakeClockAt(time.Now())
+	gc := filecache.NewGC(zaptest.NewLogger(t), cache.Cache, filecache.GCConfig{Retention: time.Hour}, clock)
+
+	idA, idB, idC := build.ID{'a'}, build.ID{'b'}, build.ID{'c'}
+	writeFile(t, cache, idA, "a")
+	writeFile(t, cache, idB, "b")
+	writeFile(t, cache, idC, "ccc")
+
+	buildID := build.ID{'x'}
+	gc.Reference(buildID, []build.ID{idA, idB})
+
+	report, err := gc.Collect(false)
+	require.NoError(t, err)
+	require.Empty(t, report.Removed, "fresh uploads must be kept")
+
+	clock.Advance(2 * time.Hour)
+
+	report, err = gc.Collect(true)
+	require.NoError(t, err)
+	require.Equal(t, []build.ID{idC}, report.Removed)
+	require.Equal(t, int64(3), report.ReclaimedBytes)
+	requireExists(t, cache, idC, true)
+
+	report, err = gc.Collect(false)
+	require.NoError(t, err)
+	require.Equal(t, []build.ID{idC}, report.Removed)
+	requireExists(t, cache, idA, true)
+	requireExists(t, cache, idB, true)
+	requireExists(t, cache, idC, false)
+
+	gc.Release(buildID)
+	clock.Advance(time.Minute)
+
+	report, err = gc.Collect(false)
+	require.NoError(t, err)
+	require.Empty(t, report.Removed, "files of recent builds must be kept")
+
+	clock.Advance(2 * time.Hour)
+
+	report, err = gc.Collect(false)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []build.ID{idA, idB}, report.Removed)
+	require.Equal(t, int64(2), report.ReclaimedBytes)
+}
+
+func TestGCSharedFiles(t *testing.T) {
+	cache := newCache(t)
+	clock := clockwork.NewFakeClockAt(time.Now())
+	gc := filecache.NewGC(zaptest.NewLogger(t), cache.Cache, filecache.GCConfig{Retention: time.Hour}, clock)
+
+	id := build.ID{'a'}
+	writeFile(t, cache, id, "a")
+
+	buildX, buildY := build.ID{'x'}, build.ID{'y'}
+	gc.Reference(buildX, []build.ID{id, id})
+	gc.Reference(buildX, []build.ID{id})
+	gc.Reference(buildY, []build.ID{id})
+
+	gc.Release(buildX)
+	clock.Advance(2 * time.Hour)
+
+	report, err := gc.Collect(false)
+	require.NoError(t, err)
+	require.Empty(t, report.Removed, "file is still used by another build")
+
+	gc.Release(buildY)
+	clock.Advance(2 * time.Hour)
+
+	report, err = gc.Collect(false)
+	require.NoError(t, err)
+	require.Equal(t, []build.ID{id}, report.Removed)
+}
+
+func TestGCSkipsLockedFiles(t *testing.T) {
+	cache := newCache(t)
+	clock := clockwork.NewFakeClockAt(time.Now())
+	gc := filecache.NewGC(zaptest.NewLogger(t), cache.Cache, filecache.GCConfig{Retention: time.Hour}, clock)
+
+	id := build.ID{'a'}
+	writeFile(t, cache, id, "a")
+	clock.Advance(2 * time.Hour)
+
+	_, unlock, err := cache.Get(id)
+	require.NoError(t, err)
+
+	report, err := gc.Collect(false)
+	require.NoError(t, err)
+	require.Empty(t, report.Removed)
+	require.Equal(t, []build.ID{id}, report.Locked)
+
+	unlock()
+
+	report, err = gc.Collect(false)
+	require.NoError(t, err)
+	require.Equal(t, []build.ID{id}, report.Removed)
+	requireExists(t, cache, id, false)
+}
+
+func TestGCRunDefaultInterval(t *testing.T) {
+	cache := newCache(t)
+	clock := clockwork.NewFakeClockAt(time.Now())
+	gc := filecache.NewGC(zaptest.NewLogger(t), cache.Cache, filecache.GCConfig{Retention: time.Hour}, clock)
+
+	id := build.ID{'a'}
+	writeFile(t, cache, id, "a")
+	clock.Advance(2 * time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- gc.Run(ctx)
+	}()
+
+	// Без Interval Run не должен крутить Collect в цикле.
+	require.NoError(t, clock.BlockUntilContext(ctx, 1))
+	requireExists(t, cache, id, true)
+
+	clock.Advance(filecache.DefaultGCInterval)
+	require.Eventually(t, func() bool {
+		_, unlock, err := cache.Get(id)
+		if err == nil {
+			unlock()
+		}
+		return errors.Is(err, filecache.ErrNotFound)
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+}
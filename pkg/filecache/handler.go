@@ -3,6 +3,7 @@
 package filecache
 
 import (
+	"distributed_build/pkg/auth"
 	"distributed_build/pkg/build"
 	"fmt"
 	"io"
@@ -17,12 +18,19 @@ type Handler struct {
 	group  *singleflight.Group
 	logger *zap.Logger
 	cache  *Cache
+	auth   auth.Authenticator
 }
 
 func NewHandler(l *zap.Logger, cache *Cache) *Handler {
 	return &Handler{logger: l, cache: cache, group: new(singleflight.Group)}
 }
 
+// RequireAuth включает проверку токенов: заливать файлы может только auth.RoleClient,
+// читать - клиенты и воркеры. Вызывается до Register.
+func (h *Handler) RequireAuth(a auth.Authenticator) {
+	h.auth = a
+}
+
 func (h *Handler) Register(mux *http.ServeMux) {
 	mux.HandleFunc("/file", func(w http.ResponseWriter, r *http.Request) {
 		fileIDData := r.URL.Query().Get("id")
@@ -36,10 +44,14 @@ func (h *Handler) Register(mux *http.ServeMux) {
 		}
 
 		switch r.Method {
-		case http.MethodGet:
-			h.getFile(w, r, fileID)
+		case http.MethodGet, http.MethodHead:
+			if r, ok := auth.Check(h.logger, h.auth, w, r, auth.RoleClient, auth.RoleWorker); ok {
+				h.getFile(w, r, fileID)
+			}
 		case http.MethodPut:
-			h.putFile(w, r, fileID)
+			if r, ok := auth.Check(h.logger, h.auth, w, r, auth.RoleClient); ok {
+				h.putFile(w, r, fileID)
+			}
 		default:
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		}
@@ -54,9 +66,16 @@ func (h *Handler) getFile(w http.ResponseWriter, r *http.Request, id build.ID) {
 	}
 	defer unlock()
 
+	// ServeFile сам обрабатывает HEAD, Range, If-Range и If-None-Match, если ETag уже выставлен.
+	w.Header().Set("ETag", etag(id))
 	http.ServeFile(w, r, path)
 }
 
+// etag возвращает ETag файла. Содержимое файла целиком определяется его id.
+func etag(id build.ID) string {
+	return `"` + id.String() + `"`
+}
+
 type WriterAbort struct {
 	Writer io.WriteCloser
 	Abort  func() error
@@ -0,0 +1,104 @@
+package mtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// Config хранит сертификат компонента и сертификат CA, которым подписаны сертификаты всех
+// компонентов кластера.
+//
+// Один и тот же Config используется и на сервере, и на клиенте: воркер одновременно принимает
+// запросы за артефактами и сам ходит к координатору и другим воркерам.
+type Config struct {
+	Certificate tls.Certificate
+	CAs         *x509.CertPool
+
+	caPEM, certPEM, keyPEM []byte
+}
+
+// LoadConfig читает сертификат CA, сертификат компонента и его ключ из PEM файлов.
+func LoadConfig(caFile, certFile, keyFile string) (*Config, error) {
+	caPEM, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return newConfig(caPEM, certPEM, keyPEM)
+}
+
+func newConfig(caPEM, certPEM, keyPEM []byte) (*Config, error) {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	cas := x509.NewCertPool()
+	if !cas.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("no CA certificates found")
+	}
+
+	return &Config{
+		Certificate: cert,
+		CAs:         cas,
+		caPEM:       caPEM,
+		certPEM:     certPEM,
+		keyPEM:      keyPEM,
+	}, nil
+}
+
+// WriteFiles записывает сертификаты и ключ в dir в формате, который читает LoadConfig.
+func (c *Config) WriteFiles(dir string) (caFile, certFile, keyFile string, err error) {
+	caFile = filepath.Join(dir, "ca.pem")
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+
+	if err = os.WriteFile(caFile, c.caPEM, 0644); err != nil {
+		return
+	}
+	if err = os.WriteFile(certFile, c.certPEM, 0644); err != nil {
+		return
+	}
+	err = os.WriteFile(keyFile, c.keyPEM, 0600)
+	return
+}
+
+// ServerTLS возвращает настройки сервера, который требует от всех клиентов сертификат, подписанный CA.
+func (c *Config) ServerTLS() *tls.Config {
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{c.Certificate},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    c.CAs,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+}
+
+// ClientTLS возвращает настройки клиента, который предъявляет свой сертификат и проверяет сертификат сервера.
+func (c *Config) ClientTLS() *tls.Config {
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{c.Certificate},
+		RootCAs:      c.CAs,
+	}
+}
+
+// HTTPClient возвращает HTTP клиент с настройками ClientTLS. Клиент поддерживает HTTP/2, поэтому
+// подходит и для gRPC.
+func (c *Config) HTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = c.ClientTLS()
+	transport.ForceAttemptHTTP2 = true
+	return &http.Client{Transport: transport}
+}
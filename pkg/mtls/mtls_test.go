@@ -0,0 +1,69 @@
+package mtls_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"distributed_build/pkg/auth"
+	"distributed_build/pkg/mtls"
+)
+
+func TestMutualTLS(t *testing.T) {
+	ca, err := mtls.NewCA()
+	require.NoError(t, err)
+
+	serverTLS, err := ca.Issue(auth.Identity{Subject: "coordinator", Role: auth.RoleCoordinator}, "127.0.0.1")
+	require.NoError(t, err)
+	workerTLS, err := ca.Issue(auth.Identity{Subject: "https://127.0.0.1/worker/0", Role: auth.RoleWorker})
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := auth.IdentityFromCertificate(r.TLS.VerifiedChains[0][0])
+		require.NoError(t, err)
+		_, _ = io.WriteString(w, id.String())
+	}))
+	server.TLS = serverTLS.ServerTLS()
+	server.StartTLS()
+	defer server.Close()
+
+	rsp, err := workerTLS.HTTPClient().Get(server.URL)
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+
+	body, err := io.ReadAll(rsp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "worker:https://127.0.0.1/worker/0", string(body))
+
+	otherCA, err := mtls.NewCA()
+	require.NoError(t, err)
+	foreignTLS, err := otherCA.Issue(auth.Identity{Subject: "mallory", Role: auth.RoleClient})
+	require.NoError(t, err)
+
+	_, err = foreignTLS.HTTPClient().Get(server.URL)
+	require.Error(t, err, "certificates of another CA must be rejected")
+
+	_, err = http.Get(server.URL)
+	require.Error(t, err)
+}
+
+func TestLoadConfig(t *testing.T) {
+	ca, err := mtls.NewCA()
+	require.NoError(t, err)
+
+	cfg, err := ca.Issue(auth.Identity{Subject: "alice", Role: auth.RoleClient})
+	require.NoError(t, err)
+
+	caFile, certFile, keyFile, err := cfg.WriteFiles(t.TempDir())
+	require.NoError(t, err)
+
+	loaded, err := mtls.LoadConfig(caFile, certFile, keyFile)
+	require.NoError(t, err)
+	require.Equal(t, cfg.Certificate.Certificate, loaded.Certificate.Certificate)
+
+	_, err = mtls.LoadConfig(certFile, certFile, caFile)
+	require.Error(t, err)
+}
@@ -0,0 +1,49 @@
+// Package sandbox запускает команды джобов в изолированном окружении на Linux namespace-ах.
+package sandbox
+
+import (
+	"errors"
+)
+
+// ErrUnsupported возвращается на системах, где песочница недоступна.
+var ErrUnsupported = errors.New("sandbox is not supported on this system")
+
+// initArg - argv[0] процесса, который Wrap запускает вместо команды. Init узнаёт по нему, что должен
+// подготовить песочницу.
+const initArg = "distbuild-sandbox-init"
+
+// SystemDirs - директории хоста, которые по умолчанию видны в песочнице на чтение: без них не запустится
+// ни компилятор, ни shell.
+var SystemDirs = []string{"/bin", "/sbin", "/usr", "/lib", "/lib32", "/lib64", "/etc", "/opt"}
+
+// Config описывает песочницу одного запуска команды.
+//
+// Директории монтируются в песочницу по тем же путям, что и на хосте, поэтому отрендеренная команда
+// джоба работает без изменений. Всё, что не перечислено в Config, кроме /proc, /dev и пустого /tmp,
+// в песочнице не видно.
+type Config struct {
+	// ReadOnly перечисляет директории и файлы, доступные только на чтение: исходники и артефакты зависимостей.
+	// Несуществующие системные директории пропускаются, остальные пути должны существовать.
+	ReadOnly []string `json:"read_only"`
+
+	// Writable перечисляет директории, доступные на запись: выходная директория джоба.
+	Writable []string `json:"writable"`
+
+	// Network оставляет команде сеть хоста. По умолчанию у команды есть только свой loopback.
+	Network bool `json:"network"`
+}
+
+// WithSystemDirs возвращает копию конфига, в которой к ReadOnly добавлены SystemDirs.
+func (c *Config) WithSystemDirs() *Config {
+	out := *c
+	out.ReadOnly = append(append([]string(nil), SystemDirs...), c.ReadOnly...)
+	return &out
+}
+
+// config - то, что Wrap передаёт в Init: конфиг песочницы, рабочая директория команды и временная директория
+// воркера, в которой Init создаёт корень песочницы.
+type config struct {
+	Config
+	Dir     string `json:"dir"`
+	TempDir string `json:"temp_dir"`
+}
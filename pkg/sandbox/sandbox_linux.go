@@ -0,0 +1,341 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Capabilities, которые нужны процессу Init в своём user namespace: capSysAdmin - чтобы монтировать
+// файловые системы, capNetAdmin - чтобы поднять loopback. Команда их не получает.
+const (
+	capNetAdmin = 12
+	capSysAdmin = 21
+)
+
+// Securebits из linux/securebits.h: uid 0 не получает capabilities при exec.
+const (
+	secbitNoRoot       = 1 << 0
+	secbitNoRootLocked = 1 << 1
+)
+
+// devices - устройства хоста, которые видны в /dev песочницы.
+var devices = []string{"null", "zero", "full", "random", "urandom", "tty"}
+
+// Wrap подменяет cmd так, что команда запускается в песочнице c. Вызывается до cmd.Start.
+//
+// Вместо команды запускается текущий бинарник в новых user, mount, PID, IPC, UTS и, если !c.Network,
+// network namespace-ах. Он готовит файловую систему песочницы в Init и выполняет исходную команду.
+// Поэтому бинарник, который вызывает Wrap, должен первым делом вызывать Init.
+//
+// Команда выполняется с uid и gid воркера и не получает никаких capabilities. Права root не нужны.
+// cmd.Dir должна быть видна в песочнице. Если cmd.Dir не задана, команда выполняется в корне песочницы.
+func (c *Config) Wrap(cmd *exec.Cmd) error {
+	if cmd.Path == "" || cmd.Err != nil {
+		return fmt.Errorf("invalid command: %w", cmd.Err)
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("unable to find sandbox init binary: %w", err)
+	}
+
+	// Рабочая директория воркера в песочнице не видна.
+	dir := cmd.Dir
+	if dir == "" {
+		dir = "/"
+	}
+
+	// TMPDIR команды может указывать в директорию из Writable. Корень песочницы, созданный там, был бы виден
+	// в ней самой, и после pivot его не удалось бы удалить, поэтому Init берёт временную директорию воркера.
+	data, err := json.Marshal(&config{Config: *c, Dir: dir, TempDir: os.TempDir()})
+	if err != nil {
+		return err
+	}
+
+	args := []string{initArg, string(data), cmd.Path}
+	cmd.Args = append(args, cmd.Args...)
+	cmd.Path = self
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	attr := cmd.SysProcAttr
+	attr.Cloneflags |= syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+	if !c.Network {
+		attr.Cloneflags |= syscall.CLONE_NEWNET
+	}
+	attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}}
+	attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}}
+	attr.AmbientCaps = append(attr.AmbientCaps, capSysAdmin, capNetAdmin)
+	attr.Pdeathsig = syscall.SIGKILL
+	return nil
+}
+
+// Init готовит песочницу и выполняет в ней команду, если текущий процесс запустил Wrap. В этом случае Init
+// не возвращается. Иначе Init ничего не делает.
+//
+// Бинарник воркера вызывает Init в начале main, а тесты, которые запускают команды в песочнице, - в TestMain.
+func Init() {
+	if len(os.Args) < 3 || os.Args[0] != initArg {
+		return
+	}
+
+	if err := run(os.Args[1], os.Args[2], os.Args[3:]); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+		os.Exit(127)
+	}
+}
+
+func run(data, path string, argv []string) error {
+	var c config
+	if err := json.Unmarshal([]byte(data), &c); err != nil {
+		return fmt.Errorf("invalid sandbox config: %w", err)
+	}
+
+	if err := c.setup(); err != nil {
+		return err
+	}
+
+	// Команда не должна получить capabilities, которые были нужны для подготовки песочницы. Если воркер
+	// запущен от root, uid команды тоже 0, и без securebits она получила бы все capabilities в своём
+	// user namespace и смогла бы перемонтировать исходники на запись.
+	if err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0, 0, 0); err != nil {
+		return fmt.Errorf("unable to drop capabilities: %w", err)
+	}
+	if os.Getuid() == 0 {
+		if err := unix.Prctl(unix.PR_SET_SECUREBITS, secbitNoRoot|secbitNoRootLocked, 0, 0, 0); err != nil {
+			return fmt.Errorf("unable to set securebits: %w", err)
+		}
+	}
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("unable to set no_new_privs: %w", err)
+	}
+
+	if err := unix.Exec(path, argv, os.Environ()); err != nil {
+		return fmt.Errorf("exec %s: %w", path, err)
+	}
+	return nil
+}
+
+// setup собирает корневую файловую систему песочницы на tmpfs и переключается в неё.
+func (c *config) setup() error {
+	// Монтирования песочницы не должны протечь в mount namespace хоста.
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("unable to make mounts private: %w", err)
+	}
+
+	root, err := os.MkdirTemp(c.TempDir, "sandbox")
+	if err != nil {
+		return err
+	}
+	if err := unix.Mount("tmpfs", root, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=0755"); err != nil {
+		return fmt.Errorf("unable to mount sandbox root: %w", err)
+	}
+
+	// /tmp монтируется раньше директорий из конфига, чтобы не закрыть те из них, что лежат в /tmp хоста.
+	if err := mountAt(root, "/tmp", "tmpfs", "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=1777"); err != nil {
+		return err
+	}
+	for _, m := range c.mounts() {
+		if err := bind(root, m.path, m.readOnly); err != nil {
+			return err
+		}
+	}
+	if err := mountDev(root); err != nil {
+		return err
+	}
+	if err := mountAt(root, "/proc", "proc", "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+		return err
+	}
+
+	// Директорию, поверх которой смонтирован корень песочницы, можно удалить только после pivot,
+	// когда она перестанет быть точкой монтирования, а к тому времени хост уже не виден.
+	parent, err := os.Open(filepath.Dir(root))
+	if err != nil {
+		return err
+	}
+	defer parent.Close()
+
+	if err := pivot(root); err != nil {
+		return err
+	}
+	if err := unix.Unlinkat(int(parent.Fd()), filepath.Base(root), unix.AT_REMOVEDIR); err != nil {
+		return fmt.Errorf("unable to remove sandbox root mountpoint: %w", err)
+	}
+	if err := unix.Mount("", "/", "", unix.MS_REMOUNT|unix.MS_BIND|unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV, ""); err != nil {
+		return fmt.Errorf("unable to remount sandbox root read-only: %w", err)
+	}
+
+	if !c.Network {
+		if err := loopbackUp(); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Chdir(c.Dir); err != nil {
+		return fmt.Errorf("unable to change to working directory: %w", err)
+	}
+	return nil
+}
+
+type mount struct {
+	path     string
+	readOnly bool
+}
+
+// mounts возвращает директории из конфига в порядке монтирования: родительские директории раньше вложенных,
+// иначе вложенная директория окажется закрыта родительской.
+func (c *config) mounts() []mount {
+	var mounts []mount
+	for _, path := range c.ReadOnly {
+		mounts = append(mounts, mount{path: filepath.Clean(path), readOnly: true})
+	}
+	for _, path := range c.Writable {
+		mounts = append(mounts, mount{path: filepath.Clean(path)})
+	}
+	slices.SortStableFunc(mounts, func(a, b mount) int { return strings.Compare(a.path, b.path) })
+	return mounts
+}
+
+// bind монтирует path хоста в песочницу root по тому же пути. Несуществующие пути пропускаются.
+func bind(root, path string, readOnly bool) error {
+	fi, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	target := filepath.Join(root, path)
+	if err := mountpoint(target, fi.IsDir()); err != nil {
+		return err
+	}
+	if err := unix.Mount(path, target, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return fmt.Errorf("unable to bind %s: %w", path, err)
+	}
+	if !readOnly {
+		return nil
+	}
+
+	// В user namespace нельзя снять флаги nosuid, nodev и noexec, с которыми смонтирован источник,
+	// поэтому при перемонтировании их нужно сохранить.
+	var st unix.Statfs_t
+	if err := unix.Statfs(target, &st); err != nil {
+		return err
+	}
+	flags := uintptr(unix.MS_REMOUNT | unix.MS_BIND | unix.MS_RDONLY)
+	for _, f := range []struct{ st, ms uintptr }{
+		{unix.ST_NOSUID, unix.MS_NOSUID},
+		{unix.ST_NODEV, unix.MS_NODEV},
+		{unix.ST_NOEXEC, unix.MS_NOEXEC},
+		{unix.ST_NOATIME, unix.MS_NOATIME},
+		{unix.ST_NODIRATIME, unix.MS_NODIRATIME},
+		{unix.ST_RELATIME, unix.MS_RELATIME},
+	} {
+		if uintptr(st.Flags)&f.st != 0 {
+			flags |= f.ms
+		}
+	}
+	if err := unix.Mount("", target, "", flags, ""); err != nil {
+		return fmt.Errorf("unable to remount %s read-only: %w", path, err)
+	}
+	return nil
+}
+
+// mountpoint создаёт пустую директорию или файл, поверх которого можно смонтировать источник.
+func mountpoint(target string, dir bool) error {
+	if dir {
+		return os.MkdirAll(target, 0755)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func mountAt(root, path, source, fstype string, flags uintptr, data string) error {
+	target := filepath.Join(root, path)
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return err
+	}
+	if err := unix.Mount(source, target, fstype, flags, data); err != nil {
+		return fmt.Errorf("unable to mount %s: %w", path, err)
+	}
+	return nil
+}
+
+// mountDev собирает /dev песочницы из нескольких безопасных устройств хоста.
+func mountDev(root string) error {
+	if err := mountAt(root, "/dev", "tmpfs", "tmpfs", unix.MS_NOSUID|unix.MS_NOEXEC, "mode=0755"); err != nil {
+		return err
+	}
+
+	for _, name := range devices {
+		if err := bind(root, filepath.Join("/dev", name), false); err != nil {
+			return err
+		}
+	}
+	for name, target := range map[string]string{
+		"fd":     "/proc/self/fd",
+		"stdin":  "/proc/self/fd/0",
+		"stdout": "/proc/self/fd/1",
+		"stderr": "/proc/self/fd/2",
+	} {
+		if err := os.Symlink(target, filepath.Join(root, "dev", name)); err != nil {
+			return err
+		}
+	}
+	return mountAt(root, "/dev/shm", "tmpfs", "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=1777")
+}
+
+// pivot делает root корнем файловой системы и отмонтирует старый корень.
+func pivot(root string) error {
+	if err := os.Chdir(root); err != nil {
+		return err
+	}
+	if err := unix.PivotRoot(".", "."); err != nil {
+		return fmt.Errorf("unable to pivot root: %w", err)
+	}
+	if err := unix.Unmount(".", unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("unable to unmount host root: %w", err)
+	}
+	return os.Chdir("/")
+}
+
+// loopbackUp поднимает loopback в новом network namespace, чтобы команды могли слушать localhost.
+func loopbackUp() error {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	ifr, err := unix.NewIfreq("lo")
+	if err != nil {
+		return err
+	}
+	if err := unix.IoctlIfreq(fd, unix.SIOCGIFFLAGS, ifr); err != nil {
+		return fmt.Errorf("unable to get loopback flags: %w", err)
+	}
+	ifr.SetUint16(ifr.Uint16() | unix.IFF_UP)
+	if err := unix.IoctlIfreq(fd, unix.SIOCSIFFLAGS, ifr); err != nil {
+		return fmt.Errorf("unable to bring loopback up: %w", err)
+	}
+	return nil
+}
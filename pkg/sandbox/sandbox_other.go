@@ -0,0 +1,15 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"os/exec"
+)
+
+// Wrap запускает команды в песочнице только на Linux. На остальных системах возвращает ErrUnsupported.
+func (c *Config) Wrap(cmd *exec.Cmd) error {
+	return ErrUnsupported
+}
+
+// Init ничего не делает на системах без песочницы.
+func Init() {}
@@ -0,0 +1,94 @@
+//go:build linux
+
+package sandbox_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"distributed_build/pkg/sandbox"
+)
+
+func TestMain(m *testing.M) {
+	sandbox.Init()
+	os.Exit(m.Run())
+}
+
+// run выполняет script в песочнице c и возвращает stdout.
+func run(t *testing.T, c *sandbox.Config, script string) (string, error) {
+	cmd := exec.Command("/bin/sh", "-c", script)
+	require.NoError(t, c.WithSystemDirs().Wrap(cmd))
+
+	var stdout bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, os.Stderr
+	err := cmd.Run()
+	return strings.TrimSpace(stdout.String()), err
+}
+
+// requireNamespaces пропускает тест, если ядро не даёт непривилегированным процессам создавать namespace-ы.
+func requireNamespaces(t *testing.T) {
+	cmd := exec.Command("/bin/true")
+	require.NoError(t, (&sandbox.Config{}).WithSystemDirs().Wrap(cmd))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("namespaces are not available: %v %s", err, out)
+	}
+}
+
+func TestSandboxFilesystem(t *testing.T) {
+	requireNamespaces(t)
+
+	src, out, secret := t.TempDir(), t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, "a.txt"), []byte("foo"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(secret, "key"), []byte("bar"), 0644))
+
+	c := &sandbox.Config{ReadOnly: []string{src}, Writable: []string{out}}
+	_, err := run(t, c, "cat "+src+"/a.txt > "+out+"/b.txt && echo baz > /tmp/c.txt")
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(out, "b.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "foo", string(content))
+
+	_, err = run(t, c, "echo x > "+src+"/c.txt")
+	require.Error(t, err, "source dir must be read-only")
+	require.NoFileExists(t, filepath.Join(src, "c.txt"))
+
+	_, err = run(t, c, "cat "+secret+"/key")
+	require.Error(t, err, "other directories must be hidden")
+
+	_, err = run(t, c, "touch /etc/sandbox")
+	require.Error(t, err, "system dirs must be read-only")
+}
+
+func TestSandboxProcess(t *testing.T) {
+	requireNamespaces(t)
+
+	c := &sandbox.Config{}
+
+	pid, err := run(t, c, "echo $$")
+	require.NoError(t, err)
+	require.Equal(t, "1", pid, "command must run in its own PID namespace")
+
+	uid, err := run(t, c, "id -u")
+	require.NoError(t, err)
+	require.Equal(t, strconv.Itoa(os.Getuid()), uid)
+
+	caps, err := run(t, c, "grep CapEff /proc/self/status")
+	require.NoError(t, err)
+	require.Equal(t, "CapEff:\t0000000000000000", caps, "command must not get capabilities")
+}
+
+func TestSandboxNetwork(t *testing.T) {
+	requireNamespaces(t)
+
+	ifaces, err := run(t, &sandbox.Config{}, "tail -n +3 /proc/net/dev | cut -d: -f1 | tr -d ' '")
+	require.NoError(t, err)
+	require.Equal(t, "lo", ifaces, "only loopback must be available")
+}
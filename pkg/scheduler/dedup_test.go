@@ -0,0 +1,256 @@
+package scheduler_test
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+	"distributed_build/pkg/scheduler"
+)
+
+func TestDedup(t *testing.T) {
+	s, _ := newFakeClockScheduler(t, scheduler.Config{})
+
+	spec := &api.JobSpec{Job: build.Job{ID: build.ID{'a'}}}
+	first := s.ScheduleBuildJob(build.ID{1}, spec)
+	second := s.ScheduleBuildJob(build.ID{2}, spec)
+	require.NotSame(t, first, second)
+
+	require.Equal(t, first, s.TryPickJob("w0"))
+	require.Nil(t, s.TryPickJob("w1"), "identical job must run once")
+
+	third := s.ScheduleBuildJob(build.ID{3}, spec)
+	require.Nil(t, s.TryPickJob("w1"), "running job must not be scheduled again")
+
+	res := &api.JobResult{ID: spec.ID}
+	require.True(t, s.OnJobComplete("w0", spec.ID, res))
+	for _, job := range []*scheduler.PendingJob{first, second, third} {
+		<-job.Finished
+		require.Equal(t, res, job.Result)
+	}
+
+	// Завершившийся джоб больше не дедуплицируется, его результат нужно брать из кеша.
+	fourth := s.ScheduleBuildJob(build.ID{4}, spec)
+	require.Equal(t, fourth, s.TryPickJob("w0"))
+}
+
+func TestDedupPriority(t *testing.T) {
+	s, _ := newFakeClockScheduler(t, scheduler.Config{})
+
+	require.NoError(t, s.AdmitBuild(build.ID{1}, scheduler.BuildOptions{}))
+	require.NoError(t, s.AdmitBuild(build.ID{2}, scheduler.BuildOptions{Priority: 1}))
+
+	other := s.ScheduleBuildJob(build.ID{1}, &api.JobSpec{Job: build.Job{ID: build.ID{'b'}}})
+	shared := s.ScheduleBuildJob(build.ID{1}, &api.JobSpec{Job: build.Job{ID: build.ID{'a'}}})
+	s.ScheduleBuildJob(build.ID{2}, &api.JobSpec{Job: build.Job{ID: build.ID{'a'}}})
+
+	require.Equal(t, shared, s.TryPickJob("w0"), "shared job must inherit the highest priority")
+	require.Equal(t, other, s.TryPickJob("w0"))
+}
+
+func TestDedupCancelPriority(t *testing.T) {
+	s, _ := newFakeClockScheduler(t, scheduler.Config{})
+
+	require.NoError(t, s.AdmitBuild(build.ID{1}, scheduler.BuildOptions{}))
+	require.NoError(t, s.AdmitBuild(build.ID{2}, scheduler.BuildOptions{Priority: 1}))
+
+	other := s.ScheduleBuildJob(build.ID{1}, &api.JobSpec{Job: build.Job{ID: build.ID{'b'}}})
+	shared := s.ScheduleBuildJob(build.ID{1}, &api.JobSpec{Job: build.Job{ID: build.ID{'a'}}})
+	s.ScheduleBuildJob(build.ID{2}, &api.JobSpec{Job: build.Job{ID: build.ID{'a'}}})
+
+	// Сборка с высоким приоритетом отменена, общий джоб ждёт только сборка 1.
+	s.CancelBuild(build.ID{2}, "cancelled by user")
+
+	require.Equal(t, other, s.TryPickJob("w0"), "shared job must drop the priority of the cancelled build")
+	require.Equal(t, shared, s.TryPickJob("w0"))
+}
+
+func TestDedupTenantQuota(t *testing.T) {
+	s, _ := newFakeClockScheduler(t, scheduler.Config{DefaultTenant: scheduler.TenantConfig{MaxRunningJobs: 1}})
+
+	require.NoError(t, s.AdmitBuild(build.ID{'a'}, scheduler.BuildOptions{Tenant: "alice"}))
+	require.NoError(t, s.AdmitBuild(build.ID{'b'}, scheduler.BuildOptions{Tenant: "bob"}))
+
+	shared := &api.JobSpec{Job: build.Job{ID: build.ID{'s'}}}
+	sharedA := s.ScheduleBuildJob(build.ID{'a'}, shared)
+	sharedB := s.ScheduleBuildJob(build.ID{'b'}, shared)
+	own := s.ScheduleBuildJob(build.ID{'b'}, &api.JobSpec{Job: build.Job{ID: build.ID{'b', 1}}})
+	s.ScheduleBuildJob(build.ID{'a'}, &api.JobSpec{Job: build.Job{ID: build.ID{'a', 1}}})
+
+	require.Equal(t, sharedA, s.TryPickJob("w0"))
+	require.Equal(t, own, s.TryPickJob("w1"), "shared job must be charged only to the tenant of the first build")
+	require.Nil(t, s.TryPickJob("w2"), "shared job must be charged to alice")
+
+	// Сборка alice отменена, но джоб всё ещё ждёт bob: слот остаётся за alice.
+	s.CancelBuild(build.ID{'a'}, "cancelled by user")
+	require.True(t, s.OnJobComplete("w1", own.Job.ID, &api.JobResult{ID: own.Job.ID}))
+	next := s.ScheduleBuildJob(build.ID{'b'}, &api.JobSpec{Job: build.Job{ID: build.ID{'b', 2}}})
+	require.Equal(t, next, s.TryPickJob("w1"))
+
+	res := &api.JobResult{ID: shared.ID}
+	require.True(t, s.OnJobComplete("w0", shared.ID, res))
+	<-sharedB.Finished
+	require.Equal(t, res, sharedB.Result)
+}
+
+func TestDedupCancel(t *testing.T) {
+	s, _ := newFakeClockScheduler(t, scheduler.Config{})
+
+	queued := &api.JobSpec{Job: build.Job{ID: build.ID{'q'}}}
+	running := &api.JobSpec{Job: build.Job{ID: build.ID{'r'}}}
+
+	runningA := s.ScheduleBuildJob(build.ID{'a'}, running)
+	runningB := s.ScheduleBuildJob(build.ID{'b'}, running)
+	require.Equal(t, runningA, s.TryPickJob("w0"))
+
+	queuedA := s.ScheduleBuildJob(build.ID{'a'}, queued)
+	queuedB := s.ScheduleBuildJob(build.ID{'b'}, queued)
+
+	s.CancelBuild(build.ID{'a'}, "cancelled by user")
+	for _, job := range []*scheduler.PendingJob{runningA, queuedA} {
+		<-job.Finished
+		require.Equal(t, "cancelled by user", *job.Result.Error)
+	}
+	require.Empty(t, s.JobsToCancel("w0"), "job is still needed by build b")
+
+	require.Equal(t, queuedB, s.TryPickJob("w1"), "job is still needed by build b")
+
+	res := &api.JobResult{ID: running.ID}
+	require.True(t, s.OnJobComplete("w0", running.ID, res))
+	<-runningB.Finished
+	require.Equal(t, res, runningB.Result)
+
+	s.CancelBuild(build.ID{'b'}, "cancelled by user")
+	<-queuedB.Finished
+	require.Equal(t, []build.ID{queued.ID}, s.JobsToCancel("w1"), "nobody waits for the job anymore")
+}
+
+// TestDedupConcurrentBuilds запускает много сборок с общими подграфами одновременно, часть сборок
+// отменяется. Пока джоб стоит в очереди или выполняется, он не должен запускаться повторно, а каждая
+// неотменённая сборка должна получить результат каждого своего джоба.
+func TestDedupConcurrentBuilds(t *testing.T) {
+	const (
+		builds     = 20
+		sharedJobs = 50
+		ownJobs    = 10
+		workers    = 4
+	)
+
+	s, _ := newFakeClockScheduler(t, scheduler.Config{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type heldJob struct {
+		worker api.WorkerID
+		job    *scheduler.PendingJob
+	}
+
+	var (
+		mu       sync.Mutex
+		executed = map[build.ID]int{}
+		held     []heldJob
+		released bool
+	)
+
+	// Воркеры забирают джобы сразу, но завершают их только после того, как все сборки поставили
+	// свои джобы в очередь. Так каждый джоб всё это время остаётся незавершённым.
+	complete := func(w api.WorkerID, job *scheduler.PendingJob) {
+		// Джоб, от которого отписались все сборки, уже отменён, и его результат шедулеру не нужен.
+		s.OnJobComplete(w, job.Job.ID, &api.JobResult{ID: job.Job.ID})
+	}
+
+	var workersWG sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		workerID := api.WorkerID(fmt.Sprintf("w%d", i))
+
+		workersWG.Add(1)
+		go func() {
+			defer workersWG.Done()
+			for {
+				job := s.PickJob(ctx, workerID)
+				if job == nil {
+					return
+				}
+
+				mu.Lock()
+				executed[job.Job.ID]++
+				hold := !released
+				if hold {
+					held = append(held, heldJob{worker: workerID, job: job})
+				}
+				mu.Unlock()
+
+				if !hold {
+					complete(workerID, job)
+				}
+			}
+		}()
+	}
+
+	var buildsWG, scheduledWG sync.WaitGroup
+	results := make([][]*scheduler.PendingJob, builds)
+	for b := 0; b < builds; b++ {
+		buildsWG.Add(1)
+		scheduledWG.Add(1)
+		go func() {
+			defer buildsWG.Done()
+
+			var ids []build.ID
+			for i := 0; i < sharedJobs; i++ {
+				ids = append(ids, build.ID{'s', byte(i)})
+			}
+			for i := 0; i < ownJobs; i++ {
+				ids = append(ids, build.ID{'o', byte(b), byte(i)})
+			}
+			rand.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+
+			for _, id := range ids {
+				results[b] = append(results[b], s.ScheduleBuildJob(build.ID{'b', byte(b)}, &api.JobSpec{Job: build.Job{ID: id}}))
+			}
+			if b%5 == 0 {
+				s.CancelBuild(build.ID{'b', byte(b)}, "cancelled by user")
+			}
+			scheduledWG.Done()
+
+			for _, job := range results[b] {
+				<-job.Finished
+			}
+		}()
+	}
+
+	scheduledWG.Wait()
+
+	mu.Lock()
+	released = true
+	mu.Unlock()
+
+	for _, h := range held {
+		complete(h.worker, h.job)
+	}
+
+	buildsWG.Wait()
+	cancel()
+	workersWG.Wait()
+
+	for id, n := range executed {
+		assert.Equal(t, 1, n, "job %s executed more than once", id)
+	}
+	for b := range results {
+		require.Len(t, results[b], sharedJobs+ownJobs)
+		for _, job := range results[b] {
+			require.Equal(t, job.Job.ID, job.Result.ID)
+			if b%5 == 0 {
+				require.Equal(t, "cancelled by user", *job.Result.Error)
+			} else {
+				require.Nil(t, job.Result.Error)
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+//go:build !solution
+
+package scheduler
+
+import (
+	"go.uber.org/zap"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+)
+
+// Drain переводит воркера в режим вывода из кластера: новые джобы и копии отстающих джобов ему больше
+// не достаются, а бегущие продолжают выполняться. Кеш воркера по-прежнему возвращает LocateArtifact.
+//
+// Координатор вызывает Drain на каждый heartbeat с HeartbeatRequest.Draining.
+func (c *Scheduler) Drain(workerID api.WorkerID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := c.worker(workerID)
+	if w.draining {
+		return
+	}
+	w.draining = true
+
+	c.logger.Info("worker draining", zap.String("worker_id", workerID.String()))
+}
+
+// Drained возвращает true, если воркер, переведённый в Drain, может уйти: на нём не бежит ни одного джоба,
+// и ни один джоб в очереди, на другом воркере или ещё не запущенный джоб незавершённой сборки
+// (см. BuildOptions.Jobs) не ждёт артефакт, который есть только у этого воркера.
+func (c *Scheduler) Drained(workerID api.WorkerID) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w, ok := c.workers[workerID]
+	if !ok || !w.draining {
+		return false
+	}
+
+	for _, job := range c.running {
+		if job.worker == workerID || job.speculative == workerID || c.needs(&job.spec.Job, workerID) {
+			return false
+		}
+	}
+	for _, job := range c.queued {
+		if c.needs(&job.spec.Job, workerID) {
+			return false
+		}
+	}
+	for _, b := range c.builds {
+		for i := range b.opts.Jobs {
+			job := &b.opts.Jobs[i]
+			if len(c.jobCache[job.ID]) == 0 && c.needs(job, workerID) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// needs возвращает true, если джобу нужен артефакт, который есть в кеше только у воркера workerID.
+// Вызывается под c.mu.
+func (c *Scheduler) needs(job *build.Job, workerID api.WorkerID) bool {
+	for _, dep := range job.Deps {
+		if workers := c.jobCache[dep]; len(workers) == 1 && workers[0] == workerID {
+			return true
+		}
+	}
+	return false
+}
+
+// Deregister забывает воркера, который ушёл из кластера, так же как мёртвого воркера, но не ждёт его возвращения.
+// Джобы, которые ещё бежали на воркере, перезапускаются как после api.FailureInfra и расходуют MaxInfraRetries.
+//
+// Координатор вызывает Deregister на heartbeat с HeartbeatRequest.Leaving.
+func (c *Scheduler) Deregister(workerID api.WorkerID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w, ok := c.workers[workerID]
+	if !ok {
+		return
+	}
+
+	jobs := c.removeWorker(workerID, w, "worker left")
+	delete(c.lost, workerID)
+	delete(c.quarantined, workerID)
+
+	c.logger.Info("worker left",
+		zap.String("worker_id", workerID.String()),
+		zap.Stringers("requeued_jobs", jobs))
+	c.notify()
+}
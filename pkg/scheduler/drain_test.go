@@ -0,0 +1,84 @@
+package scheduler_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+	"distributed_build/pkg/scheduler"
+)
+
+func TestDrain(t *testing.T) {
+	s, _ := newFakeClockScheduler(t, scheduler.Config{}, "w0", "w1")
+
+	a, b, c := build.ID{'a'}, build.ID{'b'}, build.ID{'c'}
+	jobA := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: a}})
+	require.Equal(t, jobA, s.TryPickJob("w0"))
+
+	s.Drain("w0")
+	jobB := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: b}})
+	require.Nil(t, s.TryPickJob("w0"), "draining worker must not get new jobs")
+	require.Equal(t, jobB, s.TryPickJob("w1"))
+	require.False(t, s.Drained("w0"), "job is still running")
+
+	require.True(t, s.OnJobComplete("w0", a, &api.JobResult{ID: a}))
+	require.True(t, s.Drained("w0"))
+
+	jobC := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: c, Deps: []build.ID{a}}})
+	require.False(t, s.Drained("w0"), "queued job needs artifact of draining worker")
+	require.Equal(t, jobC, s.TryPickJob("w1"))
+	require.False(t, s.Drained("w0"), "running job needs artifact of draining worker")
+
+	s.OnArtifactsAdded("w1", []build.ID{a})
+	require.True(t, s.Drained("w0"), "artifact is downloaded")
+
+	s.Deregister("w0")
+	require.False(t, s.Drained("w0"))
+	w, ok := s.LocateArtifact(a)
+	require.True(t, ok)
+	require.Equal(t, api.WorkerID("w1"), w)
+	for _, info := range s.Workers() {
+		require.NotEqual(t, api.WorkerID("w0"), info.ID)
+	}
+}
+
+func TestDeregisterRunningJob(t *testing.T) {
+	s, _ := newFakeClockScheduler(t, scheduler.Config{Retry: scheduler.RetryConfig{MaxInfraRetries: 1}}, "w0", "w1", "w2")
+
+	jobID := build.ID{'a'}
+	job := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: jobID}})
+	require.Equal(t, job, s.TryPickJob("w0"))
+
+	s.Deregister("w0")
+	require.Equal(t, &api.JobRetry{ID: jobID, Attempt: 2, Worker: "w0", Failure: api.FailureInfra, Error: "worker left"}, <-job.Retried)
+	require.Equal(t, job, s.TryPickJob("w1"))
+	require.False(t, s.OnJobComplete("w0", jobID, &api.JobResult{ID: jobID}))
+
+	// Второй уход воркера исчерпывает MaxInfraRetries.
+	s.Deregister("w1")
+	<-job.Finished
+	require.Equal(t, api.FailureInfra, job.Result.Failure)
+	require.Equal(t, "worker left", *job.Result.Error)
+	require.Nil(t, s.TryPickJob("w2"))
+}
+
+func TestDrainUnscheduledJob(t *testing.T) {
+	s, _ := newFakeClockScheduler(t, scheduler.Config{}, "w0", "w1")
+
+	buildID := build.ID{'x'}
+	a, b := build.ID{'a'}, build.ID{'b'}
+	jobs := []build.Job{{ID: a}, {ID: b, Deps: []build.ID{a}}}
+	require.NoError(t, s.AdmitBuild(buildID, scheduler.BuildOptions{Jobs: jobs}))
+
+	jobA := s.ScheduleBuildJob(buildID, &api.JobSpec{Job: jobs[0]})
+	require.Equal(t, jobA, s.TryPickJob("w0"))
+	require.True(t, s.OnJobComplete("w0", a, &api.JobResult{ID: a}))
+
+	s.Drain("w0")
+	require.False(t, s.Drained("w0"), "unscheduled job of live build needs artifact of draining worker")
+
+	s.FinishBuild(buildID)
+	require.True(t, s.Drained("w0"))
+}
@@ -0,0 +1,11 @@
+//go:build !solution
+
+package scheduler
+
+// Tenants возвращает число владельцев, о которых помнит шедулер.
+func (c *Scheduler) Tenants() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.tenants)
+}
@@ -0,0 +1,238 @@
+//go:build !solution
+
+package scheduler
+
+import (
+	"math"
+	"slices"
+
+	"go.uber.org/zap"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+)
+
+// OnHeartbeat отмечает, что воркер жив. running - джобы, которые воркер сейчас выполняет.
+//
+// Координатор вызывает OnHeartbeat на каждый heartbeat воркера. Воркер, от которого за WorkerTimeout
+// не пришло ни одного heartbeat-а, считается мёртвым, см. README.
+//
+// Если мёртвый воркер вернулся, его джобы, которые ещё ждут в очереди, снова закрепляются за ним.
+// Джобы, которые уже забрал другой воркер или которые больше никому не нужны, воркер получит в JobsToCancel.
+//
+// Джобы, назначенные воркеру в ответ на прошлые heartbeat-ы, но которых нет в running, до воркера не дошли,
+// например оборвался long-poll. Такие джобы возвращаются в очередь. Поэтому running должен перечислять все
+// полученные воркером и ещё не завершённые джобы, а результаты завершённых координатор передаёт в OnJobComplete
+// до OnHeartbeat.
+func (c *Scheduler) OnHeartbeat(workerID api.WorkerID, running []build.ID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := c.worker(workerID)
+	w.beats++
+	w.beat = w.beats
+	c.onHeartbeat(workerID, running, w.beat-1)
+}
+
+// OnNumberedHeartbeat работает как OnHeartbeat для воркера, который нумерует свои heartbeat-ы, см.
+// HeartbeatRequest.Beat и HeartbeatRequest.Received. Такой воркер может слать heartbeat-ы, пока висит его
+// long-poll, поэтому RunningJobs не обязан перечислять джобы из ответов, которых воркер ещё не получил.
+//
+// Джоб, назначенный в ответ на heartbeat beat (см. TryPickJobFor), считается недошедшим, только если его
+// нет в running, а received не меньше beat. Первый heartbeat воркера (beat равен 1) проверяет все джобы:
+// воркер перезапустился, и ничего из назначенного раньше у него не бежит.
+func (c *Scheduler) OnNumberedHeartbeat(workerID api.WorkerID, running []build.ID, beat, received uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := c.worker(workerID)
+	w.beats++
+	if beat == 1 {
+		w.beat, received = beat, math.MaxUint64
+	} else {
+		w.beat = max(w.beat, beat)
+	}
+	c.onHeartbeat(workerID, running, received)
+}
+
+// onHeartbeat возвращает в очередь недошедшие до воркера джобы или, если воркер вернулся, снова закрепляет
+// за ним его джобы. Вызывается под c.mu.
+func (c *Scheduler) onHeartbeat(workerID api.WorkerID, running []build.ID, received uint64) {
+	if _, ok := c.lost[workerID]; !ok {
+		c.undelivered(workerID, received, running)
+		return
+	}
+
+	for _, jobID := range running {
+		if _, ok := c.lost[workerID][jobID]; !ok {
+			continue
+		}
+		if _, ok := c.adopt(workerID, jobID); !ok {
+			c.cancelled[workerID] = append(c.cancelled[workerID], jobID)
+		}
+	}
+	delete(c.lost, workerID)
+
+	c.logger.Info("worker returned", zap.String("worker_id", workerID.String()))
+	c.notify()
+}
+
+// undelivered возвращает в очередь джобы, которые назначены воркеру в ответ на heartbeat-ы с номером
+// не больше received, но которых нет в running. Вызывается под c.mu.
+func (c *Scheduler) undelivered(workerID api.WorkerID, received uint64, running []build.ID) {
+	var jobs []build.ID
+	for jobID, job := range c.running {
+		if slices.Contains(running, jobID) {
+			continue
+		}
+
+		switch {
+		case job.speculative == workerID && job.speculativeBeat <= received:
+			c.dropCopy(job, workerID)
+		case job.worker == workerID && job.beat <= received && job.speculative != "":
+			c.dropCopy(job, workerID)
+		case job.worker == workerID && job.beat <= received:
+			delete(c.running, jobID)
+			job.tenant.running--
+			// Джоб на воркере не запускался, поэтому это не перезапуск: воркер не исключается, а сборки
+			// не получают JobRetry.
+			c.resubmit(job, 0)
+		default:
+			continue
+		}
+		jobs = append(jobs, jobID)
+	}
+
+	if len(jobs) != 0 {
+		c.logger.Warn("jobs were not delivered to worker",
+			zap.String("worker_id", workerID.String()),
+			zap.Stringers("requeued_jobs", jobs))
+		c.notify()
+	}
+}
+
+// adopt снова закрепляет за вернувшимся воркером джоб jobID, который бежал на нём до того, как воркер
+// посчитали мёртвым. Возвращает false, если джоб уже не ждёт в очереди. Вызывается под c.mu.
+func (c *Scheduler) adopt(workerID api.WorkerID, jobID build.ID) (*inflightJob, bool) {
+	if _, ok := c.lost[workerID][jobID]; !ok {
+		return nil, false
+	}
+	delete(c.lost[workerID], jobID)
+
+	job, ok := c.queued[jobID]
+	if !ok {
+		return nil, false
+	}
+
+	c.dequeue(job)
+	c.start(job, workerID, c.worker(workerID).beat)
+	return job, true
+}
+
+// watchWorker объявляет воркера мёртвым, если за WorkerTimeout от него не пришло ни одного heartbeat-а.
+//
+// Таймаут отсчитывается окнами, поэтому воркер объявляется мёртвым не раньше чем через WorkerTimeout
+// и не позже чем через 2*WorkerTimeout после последнего heartbeat-а. Long-poll, который ждёт в WaitWork,
+// и его завершение считаются heartbeat-ами, поэтому PollTimeout может быть больше WorkerTimeout.
+func (c *Scheduler) watchWorker(workerID api.WorkerID, w *workerState) {
+	for {
+		c.mu.Lock()
+		beats := w.beats
+		c.mu.Unlock()
+
+		select {
+		case <-c.timeAfter(c.config.WorkerTimeout):
+		case <-c.stop:
+			return
+		}
+
+		c.mu.Lock()
+		if c.stopped || c.workers[workerID] != w {
+			// Воркер ушёл из кластера через Deregister.
+			c.mu.Unlock()
+			return
+		}
+		if w.beats == beats && w.polls == 0 {
+			c.workerLost(workerID, w)
+			c.mu.Unlock()
+			return
+		}
+		c.mu.Unlock()
+	}
+}
+
+// workerLost забывает мёртвого воркера: его кеш и локальные очереди, и возвращает в очередь
+// джобы, которые на нём бежали. Вызывается под c.mu.
+func (c *Scheduler) workerLost(workerID api.WorkerID, w *workerState) {
+	lost := c.lost[workerID]
+	if lost == nil {
+		lost = make(map[build.ID]struct{})
+		c.lost[workerID] = lost
+	}
+
+	// Джобы, которые воркер должен был убить, он получит, если вернётся.
+	for _, jobID := range c.cancelled[workerID] {
+		lost[jobID] = struct{}{}
+	}
+
+	jobs := c.removeWorker(workerID, w, "worker lost")
+	for _, jobID := range jobs {
+		lost[jobID] = struct{}{}
+	}
+
+	c.logger.Warn("worker lost",
+		zap.String("worker_id", workerID.String()),
+		zap.Stringers("requeued_jobs", jobs))
+	c.notify()
+}
+
+// removeWorker забывает воркера: его кеш, локальные очереди и непрочитанные JobsToCancel, - и перезапускает
+// джобы, которые на нём бежали, как упавшие с api.FailureInfra и ошибкой reason. Джобы, исчерпавшие
+// MaxInfraRetries, завершаются с этой ошибкой. Возвращает джобы, которые бежали на воркере.
+// Вызывается под c.mu.
+func (c *Scheduler) removeWorker(workerID api.WorkerID, w *workerState, reason string) []build.ID {
+	delete(c.workers, workerID)
+	delete(c.cancelled, workerID)
+
+	for _, queue := range []jobSet{w.cached, w.deps} {
+		for job := range queue {
+			job.local = slices.DeleteFunc(job.local, func(id api.WorkerID) bool { return id == workerID })
+		}
+	}
+
+	for _, cache := range []map[build.ID][]api.WorkerID{c.jobCache, c.unshared} {
+		for jobID, workers := range cache {
+			workers = slices.DeleteFunc(workers, func(id api.WorkerID) bool { return id == workerID })
+			if len(workers) == 0 {
+				delete(cache, jobID)
+			} else {
+				cache[jobID] = workers
+			}
+		}
+	}
+
+	var jobs []build.ID
+	for jobID, job := range c.running {
+		if job.speculative == workerID || (job.worker == workerID && job.speculative != "") {
+			// Джоб продолжает выполняться на воркере с другой копией.
+			c.dropCopy(job, workerID)
+			jobs = append(jobs, jobID)
+			continue
+		}
+		if job.worker != workerID {
+			continue
+		}
+
+		delete(c.running, jobID)
+		job.tenant.running--
+		jobs = append(jobs, jobID)
+
+		err := reason
+		res := &api.JobResult{ID: jobID, Error: &err, Failure: api.FailureInfra}
+		if !c.retry(job, res) {
+			job.finish(res)
+			c.pruneTenant(job.tenant)
+		}
+	}
+	return jobs
+}
@@ -0,0 +1,217 @@
+package scheduler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+	"distributed_build/pkg/scheduler"
+)
+
+const workerTimeout = 5 * time.Second
+
+var livenessConfig = scheduler.Config{
+	WorkerTimeout: workerTimeout,
+	Retry:         scheduler.RetryConfig{MaxInfraRetries: 1},
+}
+
+// loseWorker0 пропускает WorkerTimeout, за который heartbeat прислал только w1, и ждёт, пока у w1 не появится работа.
+func loseWorker0(t *testing.T, s *scheduler.Scheduler, clock *clockwork.FakeClock) {
+	s.OnHeartbeat("w1", nil)
+	clock.Advance(workerTimeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.True(t, s.WaitWork(ctx, "w1", 1), "jobs of lost worker must be requeued")
+}
+
+func TestWorkerLost(t *testing.T) {
+	s, clock := newFakeClockScheduler(t, livenessConfig, "w0", "w1")
+	blockUntil(t, clock, 2)
+
+	jobID, artifactID := build.ID{'a'}, build.ID{'x'}
+	s.OnArtifactsAdded("w0", []build.ID{artifactID})
+
+	job := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: jobID}})
+	require.Equal(t, job, s.TryPickJob("w0"))
+
+	loseWorker0(t, s, clock)
+	require.Equal(t, job, s.TryPickJob("w1"))
+	require.Equal(t, &api.JobRetry{ID: jobID, Attempt: 2, Worker: "w0", Failure: api.FailureInfra, Error: "worker lost"}, <-job.Retried)
+
+	_, ok := s.LocateArtifact(artifactID)
+	require.False(t, ok, "artifacts of lost worker must be forgotten")
+
+	res := &api.JobResult{ID: jobID}
+	require.False(t, s.OnJobComplete("w0", jobID, res), "job already runs on another worker")
+	require.True(t, s.OnJobComplete("w1", jobID, res))
+	<-job.Finished
+}
+
+func TestWorkerAlive(t *testing.T) {
+	s, clock := newFakeClockScheduler(t, livenessConfig, "w0", "w1")
+	blockUntil(t, clock, 2)
+
+	jobID := build.ID{'a'}
+	job := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: jobID}})
+	require.Equal(t, job, s.TryPickJob("w0"))
+
+	for i := 0; i < 3; i++ {
+		s.OnHeartbeat("w0", []build.ID{jobID})
+		s.OnHeartbeat("w1", nil)
+		clock.Advance(workerTimeout)
+		blockUntil(t, clock, 2)
+	}
+
+	require.Nil(t, s.TryPickJob("w1"))
+	require.True(t, s.OnJobComplete("w0", jobID, &api.JobResult{ID: jobID}))
+}
+
+func TestWorkerReturns(t *testing.T) {
+	s, clock := newFakeClockScheduler(t, livenessConfig, "w0", "w1")
+	blockUntil(t, clock, 2)
+
+	a, b := build.ID{'a'}, build.ID{'b'}
+	jobA := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: a}})
+	jobB := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: b}})
+	require.Equal(t, jobA, s.TryPickJob("w0"))
+	require.Equal(t, jobB, s.TryPickJob("w0"))
+
+	loseWorker0(t, s, clock)
+	require.Equal(t, jobA, s.TryPickJob("w1"))
+
+	s.OnHeartbeat("w0", []build.ID{a, b})
+	require.Equal(t, []build.ID{a}, s.JobsToCancel("w0"), "job taken by another worker must be cancelled")
+	require.Nil(t, s.TryPickJob("w1"), "queued job must return to the worker")
+
+	res := &api.JobResult{ID: b}
+	require.True(t, s.OnJobComplete("w0", b, res))
+	<-jobB.Finished
+	require.Equal(t, res, jobB.Result)
+}
+
+func TestWorkerReturnsWithResult(t *testing.T) {
+	s, clock := newFakeClockScheduler(t, livenessConfig, "w0", "w1")
+	blockUntil(t, clock, 2)
+
+	jobID := build.ID{'a'}
+	job := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: jobID}})
+	require.Equal(t, job, s.TryPickJob("w0"))
+
+	loseWorker0(t, s, clock)
+
+	require.True(t, s.OnJobComplete("w0", jobID, &api.JobResult{ID: jobID}))
+	<-job.Finished
+	require.Nil(t, s.TryPickJob("w1"))
+
+	w, ok := s.LocateArtifact(jobID)
+	require.True(t, ok)
+	require.Equal(t, api.WorkerID("w0"), w)
+}
+
+func TestUndeliveredJob(t *testing.T) {
+	s, clock := newFakeClockScheduler(t, livenessConfig, "w0", "w1")
+	blockUntil(t, clock, 2)
+
+	delivered, lost := build.ID{'a'}, build.ID{'b'}
+	deliveredJob := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: delivered}})
+	lostJob := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: lost}})
+	require.NotNil(t, s.TryPickJob("w0"))
+	require.NotNil(t, s.TryPickJob("w0"))
+
+	// Ответ с джобом lost до воркера не дошёл, и в следующем heartbeat-е его нет.
+	s.OnHeartbeat("w0", []build.ID{delivered})
+	require.Equal(t, lostJob, s.TryPickJob("w0"), "worker must not be excluded for undelivered job")
+
+	select {
+	case <-lostJob.Retried:
+		t.Fatal("undelivered job must not be reported as a retry")
+	default:
+	}
+
+	select {
+	case <-deliveredJob.Retried:
+		t.Fatal("delivered job must keep running")
+	default:
+	}
+	require.True(t, s.OnJobComplete("w0", delivered, &api.JobResult{ID: delivered}))
+}
+
+func TestUndeliveredJobOverlappingPoll(t *testing.T) {
+	s, clock := newFakeClockScheduler(t, livenessConfig, "w0", "w1")
+	blockUntil(t, clock, 2)
+
+	jobID := build.ID{'a'}
+	job := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: jobID}})
+
+	// Long-poll с номером 1 забирает джоб, но ответ ещё едет к воркеру, когда тот шлёт heartbeat 2 с результатом.
+	s.OnNumberedHeartbeat("w0", nil, 1, 0)
+	require.Equal(t, job, s.TryPickJobFor("w0", 1))
+	s.OnNumberedHeartbeat("w0", nil, 2, 0)
+	require.Nil(t, s.TryPickJob("w1"), "job sent in pending response must stay on worker")
+
+	// Воркер получил оба ответа, но джоба у него нет.
+	s.OnNumberedHeartbeat("w0", nil, 3, 2)
+	require.Equal(t, job, s.TryPickJob("w1"))
+}
+
+func TestWorkerAliveDuringLongPoll(t *testing.T) {
+	s, clock := newFakeClockScheduler(t, livenessConfig, "w0", "w1")
+	blockUntil(t, clock, 2)
+
+	artifactID := build.ID{'x'}
+	s.OnArtifactsAdded("w0", []build.ID{artifactID})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan bool)
+	go func() { done <- s.WaitWork(ctx, "w0", 1) }()
+	time.Sleep(10 * time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		s.OnHeartbeat("w1", nil)
+		clock.Advance(workerTimeout)
+		blockUntil(t, clock, 2)
+	}
+	cancel()
+	require.False(t, <-done)
+
+	_, ok := s.LocateArtifact(artifactID)
+	require.True(t, ok, "worker holding a long-poll must not be lost")
+}
+
+func TestWorkerLostRetryLimit(t *testing.T) {
+	s, clock := newFakeClockScheduler(t, livenessConfig, "w0", "w1", "w2")
+	blockUntil(t, clock, 3)
+
+	jobID := build.ID{'a'}
+	job := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: jobID}})
+	require.Equal(t, job, s.TryPickJob("w0"))
+
+	s.OnHeartbeat("w1", nil)
+	s.OnHeartbeat("w2", nil)
+	clock.Advance(workerTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.True(t, s.WaitWork(ctx, "w2", 1), "job of lost worker must be requeued")
+	require.Equal(t, job, s.TryPickJob("w1"))
+	require.Equal(t, api.FailureInfra, (<-job.Retried).Failure)
+
+	// Джоб теряет и второго воркера, а MaxInfraRetries уже исчерпан.
+	blockUntil(t, clock, 2)
+	s.OnHeartbeat("w2", nil)
+	clock.Advance(workerTimeout)
+
+	select {
+	case <-job.Finished:
+	case <-time.After(time.Second):
+		t.Fatal("job must fail after losing workers more than MaxInfraRetries times")
+	}
+	require.Equal(t, api.FailureInfra, job.Result.Failure)
+	require.Equal(t, "worker lost", *job.Result.Error)
+	require.Nil(t, s.TryPickJob("w2"))
+}
@@ -0,0 +1,71 @@
+//go:build !solution
+
+package scheduler
+
+import (
+	"slices"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+)
+
+// SetProtocol запоминает протокол, согласованный с воркером. Координатор вызывает его на каждый heartbeat
+// до OnHeartbeat.
+//
+// Джобы, которым нужны возможности из api.JobFeatures, достаются только воркерам с этими возможностями.
+// Результаты воркеров младше api.HermeticProtocolVersion не возвращает LocateCached, и по ним джоб
+// не попадает в первую локальную очередь воркера. Воркер, для которого SetProtocol не вызывался,
+// считается поддерживающим всё.
+func (c *Scheduler) SetProtocol(workerID api.WorkerID, p *api.Protocol) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.worker(workerID).protocol = p
+}
+
+// runs возвращает true, если воркер поддерживает все возможности, нужные джобу.
+func (w *workerState) runs(job *inflightJob) bool {
+	if w.protocol == nil {
+		return true
+	}
+	for _, f := range job.features {
+		if !w.protocol.Has(f) {
+			return false
+		}
+	}
+	return true
+}
+
+// hermetic возвращает true, если результаты воркера не зависят от его окружения и их можно переиспользовать
+// в других сборках.
+func (w *workerState) hermetic() bool {
+	return w.protocol == nil || w.protocol.Version >= api.HermeticProtocolVersion
+}
+
+// skips возвращает true, если джоб из глобальной очереди не стоит отдавать воркеру workerID: воркер
+// не поддерживает нужные джобу возможности или джоб уже падал на нём, см. excludes. Вызывается под c.mu.
+func (c *Scheduler) skips(job *inflightJob, workerID api.WorkerID) bool {
+	return !c.worker(workerID).runs(job) || c.excludes(job, workerID)
+}
+
+// shared возвращает true, если копию артефакта jobID в кеше воркера workerID можно переиспользовать
+// в других сборках. Вызывается под c.mu.
+func (c *Scheduler) shared(workerID api.WorkerID, jobID build.ID) bool {
+	return !slices.Contains(c.unshared[jobID], workerID)
+}
+
+// LocateCached работает как LocateArtifact, но возвращает только копии, которые собраны в окружении
+// из build.Cmd. По ним координатор решает, что джоб новой сборки запускать не нужно. Копии от воркеров
+// младше api.HermeticProtocolVersion годятся только для скачивания зависимостей, см. LocateArtifact.
+func (c *Scheduler) LocateCached(id build.ID) (api.WorkerID, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var workers []api.WorkerID
+	for _, w := range c.jobCache[id] {
+		if c.shared(w, id) {
+			workers = append(workers, w)
+		}
+	}
+	return pickWorker(workers)
+}
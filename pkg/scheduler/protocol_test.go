@@ -0,0 +1,52 @@
+package scheduler_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+	"distributed_build/pkg/scheduler"
+)
+
+func TestJobFeatures(t *testing.T) {
+	s, _ := newFakeClockScheduler(t, scheduler.Config{}, "w0", "w1", "w2")
+	s.SetProtocol("w0", &api.Protocol{Version: 1})
+	s.SetProtocol("w1", api.LocalProtocol())
+
+	limited := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: build.ID{'a'}, Resources: build.Resources{Memory: 1 << 20}}})
+	plain := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: build.ID{'b'}}})
+
+	require.Equal(t, plain, s.TryPickJob("w0"), "old worker must skip job with resource limits")
+	require.Nil(t, s.TryPickJob("w0"))
+	require.Equal(t, limited, s.TryPickJob("w1"))
+}
+
+func TestLegacyWorkerCache(t *testing.T) {
+	s, _ := newFakeClockScheduler(t, scheduler.Config{}, "w0", "w1", "w2")
+	s.SetProtocol("w0", &api.Protocol{Version: 2})
+
+	jobID := build.ID{'a'}
+	s.OnJobComplete("w0", jobID, &api.JobResult{ID: jobID})
+
+	worker, ok := s.LocateArtifact(jobID)
+	require.True(t, ok)
+	require.Equal(t, api.WorkerID("w0"), worker, "dependents still download the artifact")
+	_, ok = s.LocateCached(jobID)
+	require.False(t, ok, "artifact of an old worker must not be reused by other builds")
+
+	// Копия, скачанная у старого воркера, тоже собрана в его окружении.
+	s.OnArtifactsAdded("w1", []build.ID{jobID})
+	_, ok = s.LocateCached(jobID)
+	require.False(t, ok)
+
+	// Джоб не ждёт в первой локальной очереди старого воркера и сразу достаётся любому.
+	job := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: jobID}})
+	require.Equal(t, job, s.TryPickJob("w2"))
+	require.True(t, s.OnJobComplete("w2", jobID, &api.JobResult{ID: jobID}))
+
+	worker, ok = s.LocateCached(jobID)
+	require.True(t, ok)
+	require.Equal(t, api.WorkerID("w2"), worker)
+}
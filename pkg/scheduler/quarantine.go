@@ -0,0 +1,164 @@
+//go:build !solution
+
+package scheduler
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"distributed_build/pkg/api"
+)
+
+// QuarantineConfig задаёт автоматический карантин воркеров, на которых джобы падают из-за инфраструктурных
+// ошибок. Нулевое значение отключает автоматический карантин, ручной работает всегда.
+type QuarantineConfig struct {
+	// Window задаёт, сколько последних завершённых на воркере джобов учитывается.
+	Window int
+	// MaxInfraFailures задаёт число ошибок api.FailureInfra среди последних Window джобов,
+	// после которого воркер уходит в карантин.
+	MaxInfraFailures int
+	// Cooldown задаёт длительность автоматического карантина.
+	Cooldown time.Duration
+}
+
+func (c *QuarantineConfig) enabled() bool {
+	return c.Window > 0 && c.MaxInfraFailures > 0
+}
+
+// recordResult запоминает результат джоба, завершившегося на воркере, и отправляет воркера в карантин,
+// если среди последних джобов слишком много инфраструктурных ошибок. Вызывается под c.mu.
+func (c *Scheduler) recordResult(workerID api.WorkerID, res *api.JobResult) {
+	config := &c.config.Quarantine
+	if !config.enabled() {
+		return
+	}
+
+	w := c.worker(workerID)
+	w.recent = append(w.recent, res.Error != nil && res.Failure == api.FailureInfra)
+	if len(w.recent) > config.Window {
+		w.recent = w.recent[len(w.recent)-config.Window:]
+	}
+
+	failures := w.infraFailures()
+	if failures < config.MaxInfraFailures {
+		return
+	}
+	if _, ok := c.quarantined[workerID]; ok {
+		return
+	}
+
+	now := c.now()
+	q := &api.Quarantine{
+		Reason: fmt.Sprintf("%d of last %d jobs failed with infrastructure errors", failures, len(w.recent)),
+		Since:  now,
+		Until:  now.Add(config.Cooldown),
+	}
+	c.setQuarantine(workerID, q)
+	go c.releaseAfter(workerID, q, config.Cooldown)
+}
+
+// infraFailures возвращает число инфраструктурных ошибок среди последних джобов воркера.
+func (w *workerState) infraFailures() int {
+	n := 0
+	for _, failed := range w.recent {
+		if failed {
+			n++
+		}
+	}
+	return n
+}
+
+// setQuarantine отправляет воркера в карантин q. Вызывается под c.mu.
+func (c *Scheduler) setQuarantine(workerID api.WorkerID, q *api.Quarantine) {
+	c.quarantined[workerID] = q
+	c.logger.Warn("worker quarantined",
+		zap.String("worker_id", workerID.String()),
+		zap.String("reason", q.Reason),
+		zap.Bool("manual", q.Manual))
+}
+
+// release снимает карантин с воркера и забывает его последние джобы. Вызывается под c.mu.
+func (c *Scheduler) release(workerID api.WorkerID) {
+	delete(c.quarantined, workerID)
+	if w, ok := c.workers[workerID]; ok {
+		w.recent = nil
+	}
+
+	c.logger.Info("worker released from quarantine", zap.String("worker_id", workerID.String()))
+	c.notify()
+}
+
+// releaseAfter снимает автоматический карантин q через cooldown, если его не заменили ручным.
+func (c *Scheduler) releaseAfter(workerID api.WorkerID, q *api.Quarantine, cooldown time.Duration) {
+	select {
+	case <-c.timeAfter(cooldown):
+	case <-c.stop:
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.quarantined[workerID] == q {
+		c.release(workerID)
+	}
+}
+
+// Quarantine вручную отправляет воркера в карантин: шедулер не отдаёт ему новые джобы, пока карантин
+// не снимут через Unquarantine. Бегущие на воркере джобы продолжают выполняться.
+func (c *Scheduler) Quarantine(workerID api.WorkerID, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.setQuarantine(workerID, &api.Quarantine{Reason: reason, Since: c.now(), Manual: true})
+}
+
+// Unquarantine снимает с воркера ручной или автоматический карантин. Если воркер не в карантине,
+// возвращает ошибку, обёрнутую в api.ErrWorkerNotFound.
+func (c *Scheduler) Unquarantine(workerID api.WorkerID) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.quarantined[workerID]; !ok {
+		return fmt.Errorf("%w: worker %q is not quarantined", api.ErrWorkerNotFound, workerID)
+	}
+	c.release(workerID)
+	return nil
+}
+
+// Workers возвращает состояние всех воркеров, известных шедулеру, включая мёртвые воркеры в карантине.
+func (c *Scheduler) Workers() []api.WorkerInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	infos := make(map[api.WorkerID]*api.WorkerInfo)
+	for workerID, w := range c.workers {
+		infos[workerID] = &api.WorkerInfo{
+			ID:                  workerID,
+			Alive:               true,
+			RecentJobs:          len(w.recent),
+			RecentInfraFailures: w.infraFailures(),
+			Draining:            w.draining,
+		}
+	}
+	for workerID, q := range c.quarantined {
+		info, ok := infos[workerID]
+		if !ok {
+			info = &api.WorkerInfo{ID: workerID}
+			infos[workerID] = info
+		}
+		quarantine := *q
+		info.Quarantine = &quarantine
+	}
+
+	workers := make([]api.WorkerInfo, 0, len(infos))
+	for _, info := range infos {
+		workers = append(workers, *info)
+	}
+	slices.SortFunc(workers, func(a, b api.WorkerInfo) int { return strings.Compare(string(a.ID), string(b.ID)) })
+	return workers
+}
@@ -0,0 +1,93 @@
+package scheduler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+	"distributed_build/pkg/scheduler"
+)
+
+// runJob выполняет на воркере новый джоб с результатом res.
+func runJob(t *testing.T, s *scheduler.Scheduler, workerID api.WorkerID, id build.ID, res *api.JobResult) {
+	job := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: id}})
+	require.Equal(t, job, s.TryPickJob(workerID))
+	require.True(t, s.OnJobComplete(workerID, id, res))
+}
+
+func TestAutoQuarantine(t *testing.T) {
+	s, clock := newFakeClockScheduler(t, scheduler.Config{Quarantine: scheduler.QuarantineConfig{Window: 3, MaxInfraFailures: 2, Cooldown: time.Minute}}, "w0", "w1")
+
+	runJob(t, s, "w0", build.ID{1}, failure(build.ID{1}, api.FailureInfra, 0))
+	runJob(t, s, "w0", build.ID{2}, &api.JobResult{ID: build.ID{2}})
+	runJob(t, s, "w0", build.ID{3}, failure(build.ID{3}, api.FailureJob, 1))
+	runJob(t, s, "w0", build.ID{4}, failure(build.ID{4}, api.FailureInfra, 0))
+	require.Nil(t, s.Workers()[0].Quarantine, "first failure left the window")
+
+	runJob(t, s, "w0", build.ID{5}, failure(build.ID{5}, api.FailureInfra, 0))
+
+	w0 := s.Workers()[0]
+	require.Equal(t, api.WorkerID("w0"), w0.ID)
+	require.Equal(t, 2, w0.RecentInfraFailures)
+	require.NotNil(t, w0.Quarantine)
+	require.False(t, w0.Quarantine.Manual)
+	require.Equal(t, clock.Now(), w0.Quarantine.Since, "quarantine uses the scheduler clock")
+	require.Equal(t, time.Minute, w0.Quarantine.Until.Sub(w0.Quarantine.Since))
+
+	job := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: build.ID{6}}})
+	require.Nil(t, s.TryPickJob("w0"), "quarantined worker must not get jobs")
+
+	blockUntil(t, clock, 1)
+	clock.Advance(time.Minute)
+	requireWork(t, s, "w0", job)
+
+	w0 = s.Workers()[0]
+	require.Nil(t, w0.Quarantine)
+	require.Zero(t, w0.RecentJobs, "released worker starts from scratch")
+}
+
+func TestManualQuarantine(t *testing.T) {
+	s, _ := newFakeClockScheduler(t, scheduler.Config{Quarantine: scheduler.QuarantineConfig{}}, "w0", "w1")
+
+	s.Quarantine("w0", "broken toolchain")
+	s.Quarantine("w2", "not connected yet")
+
+	workers := s.Workers()
+	require.Len(t, workers, 3)
+	require.Equal(t, "broken toolchain", workers[0].Quarantine.Reason)
+	require.True(t, workers[0].Quarantine.Manual)
+	require.Nil(t, workers[1].Quarantine)
+	require.False(t, workers[2].Alive)
+
+	job := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: build.ID{1}}})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.False(t, s.WaitWork(ctx, "w0", 1))
+
+	require.NoError(t, s.Unquarantine("w0"))
+	require.Equal(t, job, s.TryPickJob("w0"))
+	require.ErrorIs(t, s.Unquarantine("w0"), api.ErrWorkerNotFound)
+}
+
+func TestRetrySkipsQuarantinedWorker(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	config := scheduler.Config{Retry: scheduler.RetryConfig{MaxInfraRetries: 1}}
+	s := scheduler.NewScheduler(zaptest.NewLogger(t), config, clock.After)
+	s.SetNow(clock.Now)
+	t.Cleanup(s.Stop)
+	s.RegisterWorker("w1")
+
+	jobID := build.ID{'a'}
+	job := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: jobID}})
+	require.Equal(t, job, s.TryPickJob("w0"))
+	require.True(t, s.OnJobComplete("w0", jobID, failure(jobID, api.FailureInfra, 0)))
+
+	s.Quarantine("w1", "disk full")
+	require.Equal(t, job, s.TryPickJob("w0"), "the only other worker is quarantined")
+}
@@ -0,0 +1,330 @@
+//go:build !solution
+
+package scheduler
+
+import (
+	"container/heap"
+	"slices"
+	"time"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+)
+
+// stage определяет, в каких очередях стоит джоб, см. README.
+type stage int
+
+const (
+	// stageCached - джоб стоит только в первых локальных очередях воркеров, у которых он есть в кеше.
+	stageCached stage = iota
+	// stageDeps - джоб стоит ещё и во вторых локальных очередях воркеров, у которых есть его зависимости.
+	stageDeps
+	// stageGlobal - джоб стоит ещё и в глобальной очереди своего владельца.
+	stageGlobal
+)
+
+// inflightJob - джоб, который стоит в очередях или выполняется на воркере.
+//
+// Одинаковые джобы разных сборок делят один inflightJob и выполняются один раз. Каждая сборка
+// получает свой PendingJob, подписанный на inflightJob.
+type inflightJob struct {
+	spec        *api.JobSpec
+	subscribers []*PendingJob
+	// features - возможности, которые нужны воркеру для джоба, см. SetProtocol.
+	features []api.Feature
+
+	worker api.WorkerID
+	// beat - номер heartbeat-а воркера, в ответ на который джоб ему назначен, см. undelivered и TryPickJobFor.
+	beat     uint64
+	tenant   *tenant
+	seq      uint64
+	priority int
+	path     time.Duration
+	started  time.Time
+
+	stage stage
+	// index задаёт позицию джоба в глобальной очереди владельца или -1, если его там нет.
+	index int
+	// local перечисляет воркеров, в локальных очередях которых стоит джоб.
+	local []api.WorkerID
+	// dequeued закрывается, когда джоб убран из очередей.
+	dequeued chan struct{}
+
+	// attempt - номер текущего запуска джоба, начиная с 1.
+	attempt       int
+	infraFailures int
+	jobFailures   int
+	// excluded перечисляет воркеров, на которых джоб упал из-за инфраструктурной ошибки.
+	excluded []api.WorkerID
+
+	// speculative - воркер, на котором бежит копия отстающего джоба, см. SpeculationConfig.
+	speculative        api.WorkerID
+	speculativeStarted time.Time
+	speculativeBeat    uint64
+	// speculated запрещает запускать вторую копию того же запуска джоба.
+	speculated bool
+}
+
+// subscribe подписывает на джоб ещё одну сборку. Джоб запускается с наибольшим из приоритетов
+// подписанных сборок. Вызывается под c.mu.
+func (c *Scheduler) subscribe(job *inflightJob, pendingJob *PendingJob, priority int, path time.Duration) {
+	job.subscribers = append(job.subscribers, pendingJob)
+	pendingJob.job = job
+	pendingJob.priority, pendingJob.path = priority, path
+
+	if priority > job.priority || (priority == job.priority && path > job.path) {
+		job.priority, job.path = priority, path
+		if job.index >= 0 {
+			heap.Fix(&job.tenant.queue, job.index)
+		}
+	}
+}
+
+// reprioritize пересчитывает приоритет джоба по подписанным сборкам, когда одна из них отписалась:
+// джоб, который ждут только сборки с низким приоритетом, не должен обгонять их джобы. Вызывается под c.mu.
+func (c *Scheduler) reprioritize(job *inflightJob) {
+	job.priority, job.path = job.subscribers[0].priority, job.subscribers[0].path
+	for _, pendingJob := range job.subscribers[1:] {
+		if pendingJob.priority > job.priority || (pendingJob.priority == job.priority && pendingJob.path > job.path) {
+			job.priority, job.path = pendingJob.priority, pendingJob.path
+		}
+	}
+	if job.index >= 0 {
+		heap.Fix(&job.tenant.queue, job.index)
+	}
+}
+
+// finish завершает всех подписчиков джоба с результатом res. Вызывается под c.mu.
+func (j *inflightJob) finish(res *api.JobResult) {
+	for _, pendingJob := range j.subscribers {
+		pendingJob.finish(res)
+	}
+	j.subscribers = nil
+}
+
+// before задаёт порядок запуска джобов одного владельца: сначала джобы сборок с большим приоритетом,
+// внутри сборки - джобы с самым длинным оставшимся путём до конца графа, при равенстве - в порядке
+// постановки в очередь.
+func (j *inflightJob) before(other *inflightJob) bool {
+	if j.priority != other.priority {
+		return j.priority > other.priority
+	}
+	if j.path != other.path {
+		return j.path > other.path
+	}
+	return j.seq < other.seq
+}
+
+// better сравнивает джобы так же, как глобальные очереди в Scheduler.next. Приоритеты и критические пути
+// задаются внутри владельца, поэтому джобы разных владельцев с равной долей слотов идут в порядке постановки
+// в очередь.
+func (j *inflightJob) better(other *inflightJob) bool {
+	if j.tenant == other.tenant {
+		return j.before(other)
+	}
+	if cmp := j.tenant.fairer(other.tenant); cmp != 0 {
+		return cmp < 0
+	}
+	return j.seq < other.seq
+}
+
+// jobQueue - глобальная очередь джобов владельца, упорядоченная по inflightJob.before. Реализует heap.Interface.
+type jobQueue []*inflightJob
+
+func (q jobQueue) Len() int           { return len(q) }
+func (q jobQueue) Less(i, j int) bool { return q[i].before(q[j]) }
+
+func (q jobQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *jobQueue) Push(x any) {
+	job := x.(*inflightJob)
+	job.index = len(*q)
+	*q = append(*q, job)
+}
+
+func (q *jobQueue) Pop() any {
+	old := *q
+	job := old[len(old)-1]
+	old[len(old)-1] = nil
+	job.index = -1
+	*q = old[:len(old)-1]
+	return job
+}
+
+type jobSet map[*inflightJob]struct{}
+
+// best возвращает самый срочный джоб, который можно запустить, не нарушая квоты владельцев.
+func (s jobSet) best() *inflightJob {
+	var best *inflightJob
+	for job := range s {
+		if job.tenant.canRun() && (best == nil || job.better(best)) {
+			best = job
+		}
+	}
+	return best
+}
+
+// workerState - локальные очереди воркера и его heartbeat-ы.
+type workerState struct {
+	// cached - первая локальная очередь: джобы, результат которых уже есть в кеше воркера.
+	cached jobSet
+	// deps - вторая локальная очередь: джобы, часть зависимостей которых есть в кеше воркера.
+	deps jobSet
+	// beats считает heartbeat-ы воркера, см. watchWorker.
+	beats uint64
+	// beat - наибольший номер heartbeat-а воркера, см. OnNumberedHeartbeat. Воркеры, которые не нумеруют
+	// heartbeat-ы, получают номера по порядку.
+	beat uint64
+	// polls считает long-poll-ы воркера, которые сейчас ждут в WaitWork.
+	polls int
+	// recent хранит, какие из последних джобов воркера упали из-за инфраструктурных ошибок, см. recordResult.
+	recent []bool
+	// draining запрещает отдавать воркеру новые джобы, см. Drain.
+	draining bool
+	// protocol - протокол, согласованный с воркером, или nil, см. SetProtocol.
+	protocol *api.Protocol
+}
+
+// worker возвращает состояние воркера, регистрируя его при первом обращении. Вызывается под c.mu.
+func (c *Scheduler) worker(workerID api.WorkerID) *workerState {
+	q, ok := c.workers[workerID]
+	if !ok {
+		q = &workerState{cached: make(jobSet), deps: make(jobSet)}
+		c.workers[workerID] = q
+		if c.config.WorkerTimeout > 0 && !c.stopped {
+			go c.watchWorker(workerID, q)
+		}
+	}
+	return q
+}
+
+// addLocal ставит джоб в первую (cached) или вторую локальную очередь воркера. Джоб из первой
+// очереди во вторую не попадает. В очереди воркера, который выводится из кластера или не поддерживает
+// возможности джоба, джобы не попадают. Вызывается под c.mu.
+func (c *Scheduler) addLocal(workerID api.WorkerID, job *inflightJob, cached bool) {
+	q := c.worker(workerID)
+	if slices.Contains(job.excluded, workerID) || q.draining || !q.runs(job) {
+		return
+	}
+
+	if _, ok := q.cached[job]; ok {
+		return
+	}
+
+	if cached {
+		delete(q.deps, job)
+		q.cached[job] = struct{}{}
+	} else if _, ok := q.deps[job]; !ok {
+		q.deps[job] = struct{}{}
+	} else {
+		return
+	}
+	job.local = append(job.local, workerID)
+}
+
+// enqueue ставит новый джоб в очереди по правилам из README. Вызывается под c.mu.
+//
+// Возвращает true, если джобу нужно дождаться CacheTimeout перед попаданием во вторые локальные очереди.
+func (c *Scheduler) enqueue(job *inflightJob) (waitCache bool) {
+	job.stage = stageCached
+	c.queued[job.spec.ID] = job
+	for _, dep := range job.spec.Deps {
+		if c.dependents[dep] == nil {
+			c.dependents[dep] = make(jobSet)
+		}
+		c.dependents[dep][job] = struct{}{}
+	}
+
+	for _, workerID := range c.jobCache[job.spec.ID] {
+		if c.shared(workerID, job.spec.ID) {
+			c.addLocal(workerID, job, true)
+		}
+	}
+	if len(job.local) != 0 {
+		return true
+	}
+
+	c.toDeps(job)
+	if len(job.local) == 0 {
+		// Ни у одного воркера нет ни джоба, ни его зависимостей: ждать DepsTimeout незачем.
+		c.toGlobal(job)
+	}
+	return false
+}
+
+// toDeps ставит джоб во вторые локальные очереди воркеров, у которых есть его зависимости. Вызывается под c.mu.
+func (c *Scheduler) toDeps(job *inflightJob) {
+	job.stage = stageDeps
+	for _, dep := range job.spec.Deps {
+		for _, workerID := range c.jobCache[dep] {
+			c.addLocal(workerID, job, false)
+		}
+	}
+}
+
+// toGlobal ставит джоб в глобальную очередь владельца. Вызывается под c.mu.
+func (c *Scheduler) toGlobal(job *inflightJob) {
+	job.stage = stageGlobal
+	heap.Push(&job.tenant.queue, job)
+}
+
+// dequeue убирает джоб из всех очередей. Вызывается под c.mu.
+func (c *Scheduler) dequeue(job *inflightJob) {
+	delete(c.queued, job.spec.ID)
+
+	for _, dep := range job.spec.Deps {
+		delete(c.dependents[dep], job)
+		if len(c.dependents[dep]) == 0 {
+			delete(c.dependents, dep)
+		}
+	}
+
+	for _, workerID := range job.local {
+		q := c.workers[workerID]
+		delete(q.cached, job)
+		delete(q.deps, job)
+	}
+	job.local = nil
+
+	if job.index >= 0 {
+		heap.Remove(&job.tenant.queue, job.index)
+	}
+	close(job.dequeued)
+}
+
+// onLocation обновляет локальные очереди, когда в кеше воркера появился артефакт jobID. По копии, которая
+// не shared, джоб в первую локальную очередь не попадает. Вызывается под c.mu.
+func (c *Scheduler) onLocation(workerID api.WorkerID, jobID build.ID, shared bool) {
+	if job, ok := c.queued[jobID]; ok && shared {
+		c.addLocal(workerID, job, true)
+	}
+	for job := range c.dependents[jobID] {
+		if job.stage >= stageDeps {
+			c.addLocal(workerID, job, false)
+		}
+	}
+}
+
+// candidate выбирает для воркера самый срочный джоб из первой локальной, второй локальной
+// и глобальной очередей. Вызывается под c.mu.
+//
+// Воркеру в карантине и воркеру, который выводится из кластера, джобы не достаются.
+func (c *Scheduler) candidate(workerID api.WorkerID) *inflightJob {
+	q := c.worker(workerID)
+	if _, ok := c.quarantined[workerID]; ok || q.draining {
+		return nil
+	}
+
+	best := q.cached.best()
+	if job := q.deps.best(); job != nil && (best == nil || job.better(best)) {
+		best = job
+	}
+	if job := c.next(workerID); job != nil && (best == nil || job.better(best)) {
+		best = job
+	}
+	return best
+}
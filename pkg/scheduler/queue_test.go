@@ -0,0 +1,174 @@
+package scheduler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+	"distributed_build/pkg/scheduler"
+)
+
+const (
+	cacheTimeout = time.Second
+	depsTimeout  = 10 * time.Second
+)
+
+// newFakeClockScheduler создаёт шедулер с config на фейковых часах и регистрирует воркеров workers.
+// Нулевые CacheTimeout и DepsTimeout заменяются на cacheTimeout и depsTimeout.
+func newFakeClockScheduler(t *testing.T, config scheduler.Config, workers ...api.WorkerID) (*scheduler.Scheduler, *clockwork.FakeClock) {
+	if config.CacheTimeout == 0 {
+		config.CacheTimeout = cacheTimeout
+	}
+	if config.DepsTimeout == 0 {
+		config.DepsTimeout = depsTimeout
+	}
+
+	clock := clockwork.NewFakeClock()
+	s := scheduler.NewScheduler(zaptest.NewLogger(t), config, clock.After)
+	s.SetNow(clock.Now)
+	t.Cleanup(s.Stop)
+
+	for _, w := range workers {
+		s.RegisterWorker(w)
+	}
+	return s, clock
+}
+
+// blockUntil ждёт, пока n горутин шедулера не начнут ждать таймаута.
+func blockUntil(t *testing.T, clock *clockwork.FakeClock, n int) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, clock.BlockUntilContext(ctx, n))
+}
+
+func requireWork(t *testing.T, s *scheduler.Scheduler, workerID api.WorkerID, job *scheduler.PendingJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.True(t, s.WaitWork(ctx, workerID, 1))
+	require.Equal(t, job, s.TryPickJob(workerID))
+}
+
+func TestCachedJob(t *testing.T) {
+	s, clock := newFakeClockScheduler(t, scheduler.Config{}, "w0", "w1", "w2")
+
+	jobID, depID := build.ID{'a'}, build.ID{'d'}
+	s.OnJobComplete("w0", jobID, &api.JobResult{ID: jobID})
+	s.OnArtifactsAdded("w1", []build.ID{depID})
+
+	job := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: jobID, Deps: []build.ID{depID}}})
+	blockUntil(t, clock, 1)
+
+	require.Nil(t, s.TryPickJob("w1"), "job must wait CacheTimeout before going to deps queues")
+	require.Nil(t, s.TryPickJob("w2"))
+
+	clock.Advance(cacheTimeout)
+	blockUntil(t, clock, 1)
+	require.Nil(t, s.TryPickJob("w2"), "job must wait DepsTimeout before going to global queue")
+
+	clock.Advance(depsTimeout)
+	requireWork(t, s, "w2", job)
+}
+
+func TestCachedJobFirstLocalQueue(t *testing.T) {
+	s, clock := newFakeClockScheduler(t, scheduler.Config{}, "w0", "w1", "w2")
+
+	jobID := build.ID{'a'}
+	s.OnJobComplete("w0", jobID, &api.JobResult{ID: jobID})
+
+	job := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: jobID}})
+	blockUntil(t, clock, 1)
+
+	require.Nil(t, s.TryPickJob("w1"))
+	require.Equal(t, job, s.TryPickJob("w0"))
+}
+
+func TestUncachedJobDepsQueue(t *testing.T) {
+	s, clock := newFakeClockScheduler(t, scheduler.Config{}, "w0", "w1", "w2")
+
+	depID := build.ID{'d'}
+	s.OnArtifactsAdded("w1", []build.ID{depID})
+
+	job := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: build.ID{'a'}, Deps: []build.ID{depID}}})
+	blockUntil(t, clock, 1)
+
+	require.Nil(t, s.TryPickJob("w0"), "job without cache goes to deps queues without waiting CacheTimeout")
+	require.Equal(t, job, s.TryPickJob("w1"))
+}
+
+func TestJobWithoutLocality(t *testing.T) {
+	s, _ := newFakeClockScheduler(t, scheduler.Config{}, "w0", "w1", "w2")
+
+	job := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: build.ID{'a'}, Deps: []build.ID{{'d'}}}})
+	require.Equal(t, job, s.TryPickJob("w2"), "job nobody has anything for goes straight to global queue")
+}
+
+func TestLateCacheLocation(t *testing.T) {
+	s, clock := newFakeClockScheduler(t, scheduler.Config{}, "w0", "w1", "w2")
+
+	jobID, depID := build.ID{'a'}, build.ID{'d'}
+	s.OnArtifactsAdded("w1", []build.ID{depID})
+
+	job := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: jobID, Deps: []build.ID{depID}}})
+	blockUntil(t, clock, 1)
+	require.Nil(t, s.TryPickJob("w0"))
+
+	s.OnArtifactsAdded("w0", []build.ID{jobID})
+	require.Equal(t, job, s.TryPickJob("w0"), "job must move to first local queue of w0")
+
+	require.True(t, s.OnJobComplete("w0", jobID, &api.JobResult{ID: jobID}))
+	<-job.Finished
+
+	clock.Advance(depsTimeout)
+	require.Nil(t, s.TryPickJob("w2"), "picked job must leave all queues")
+}
+
+func TestLateDepsLocation(t *testing.T) {
+	s, clock := newFakeClockScheduler(t, scheduler.Config{}, "w0", "w1", "w2")
+
+	depID := build.ID{'d'}
+	s.OnArtifactsAdded("w1", []build.ID{depID})
+
+	job := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: build.ID{'a'}, Deps: []build.ID{depID}}})
+	blockUntil(t, clock, 1)
+
+	s.OnArtifactsAdded("w2", []build.ID{depID})
+	require.Equal(t, job, s.TryPickJob("w2"))
+}
+
+func TestCancelLocalJob(t *testing.T) {
+	s, clock := newFakeClockScheduler(t, scheduler.Config{}, "w0", "w1", "w2")
+
+	jobID := build.ID{'a'}
+	s.OnJobComplete("w0", jobID, &api.JobResult{ID: jobID})
+
+	job := s.ScheduleBuildJob(build.ID{'b'}, &api.JobSpec{Job: build.Job{ID: jobID}})
+	blockUntil(t, clock, 1)
+
+	s.CancelBuild(build.ID{'b'}, "cancelled by user")
+	<-job.Finished
+	require.Nil(t, s.TryPickJob("w0"))
+}
+
+func TestLocateArtifact(t *testing.T) {
+	s, _ := newFakeClockScheduler(t, scheduler.Config{}, "w0", "w1", "w2")
+
+	_, ok := s.LocateArtifact(build.ID{'a'})
+	require.False(t, ok)
+
+	s.OnArtifactsAdded("w0", []build.ID{{'a'}})
+	s.OnArtifactsAdded("w1", []build.ID{{'a'}})
+
+	seen := map[api.WorkerID]bool{}
+	for i := 0; i < 100; i++ {
+		w, ok := s.LocateArtifact(build.ID{'a'})
+		require.True(t, ok)
+		seen[w] = true
+	}
+	require.Equal(t, map[api.WorkerID]bool{"w0": true, "w1": true}, seen)
+}
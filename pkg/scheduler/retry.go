@@ -0,0 +1,168 @@
+//go:build !solution
+
+package scheduler
+
+import (
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+
+	"distributed_build/pkg/api"
+)
+
+// retryBacklog ограничивает число непрочитанных обновлений в PendingJob.Retried. Если координатор
+// не успевает их читать, лишние обновления теряются.
+const retryBacklog = 16
+
+// RetryConfig задаёт перезапуски джобов, завершившихся с ошибкой. Нулевое значение отключает перезапуски.
+type RetryConfig struct {
+	// MaxInfraRetries ограничивает число перезапусков джоба после ошибок api.FailureInfra.
+	// Такой джоб перезапускается на другом воркере, если он есть.
+	MaxInfraRetries int
+	// MaxJobRetries ограничивает число перезапусков джоба с build.Job.Retryable после ошибок api.FailureJob.
+	MaxJobRetries int
+
+	// Backoff задаёт паузу перед первым перезапуском. Каждая следующая пауза вдвое длиннее,
+	// но не длиннее MaxBackoff, если он задан.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// backoff возвращает паузу перед перезапуском после failures неудачных запусков.
+func (c *RetryConfig) backoff(failures int) time.Duration {
+	d := c.Backoff
+	for i := 1; i < failures && (c.MaxBackoff <= 0 || d < c.MaxBackoff); i++ {
+		d *= 2
+	}
+	if c.MaxBackoff > 0 && d > c.MaxBackoff {
+		d = c.MaxBackoff
+	}
+	return d
+}
+
+// retry решает, нужно ли перезапустить джоб, завершившийся с ошибкой res, и если нужно, возвращает
+// его в очередь. Джоб уже снят с воркера. Вызывается под c.mu.
+func (c *Scheduler) retry(job *inflightJob, res *api.JobResult) bool {
+	failure := res.Failure
+	if failure == "" {
+		failure = api.FailureJob
+	}
+
+	config := &c.config.Retry
+	switch {
+	case failure == api.FailureInfra && job.infraFailures < config.MaxInfraRetries:
+		job.infraFailures++
+	case failure == api.FailureJob && job.spec.Retryable && job.jobFailures < config.MaxJobRetries:
+		job.jobFailures++
+	default:
+		return false
+	}
+
+	c.logger.Info("job retried",
+		zap.String("job_id", job.spec.ID.String()),
+		zap.String("worker_id", job.worker.String()),
+		zap.String("failure", string(failure)),
+		zap.String("error", *res.Error))
+
+	c.requeue(job, failure, *res.Error, config.backoff(job.infraFailures+job.jobFailures))
+	return true
+}
+
+// requeue возвращает в очереди джоб, запуск которого на job.worker не удался, и сообщает подписчикам
+// о перезапуске. В очереди джоб попадает через backoff. Вызывается под c.mu.
+func (c *Scheduler) requeue(job *inflightJob, failure api.FailureKind, reason string, backoff time.Duration) {
+	if failure == api.FailureInfra {
+		job.excluded = append(job.excluded, job.worker)
+	}
+
+	job.attempt++
+	retry := &api.JobRetry{
+		ID:      job.spec.ID,
+		Attempt: job.attempt,
+		Worker:  job.worker,
+		Failure: failure,
+		Error:   reason,
+	}
+	for _, pendingJob := range job.subscribers {
+		select {
+		case pendingJob.Retried <- retry:
+		default:
+		}
+	}
+
+	c.resubmit(job, backoff)
+}
+
+// resubmit снимает джоб с воркера и через backoff снова ставит его в очереди. Номер запуска, лимиты
+// и исключённые воркеры resubmit не трогает. Вызывается под c.mu.
+func (c *Scheduler) resubmit(job *inflightJob, backoff time.Duration) {
+	job.worker = ""
+	job.speculated = false
+	job.dequeued = make(chan struct{})
+	if backoff <= 0 {
+		c.schedule(job)
+		return
+	}
+
+	// Пока идёт пауза, джоб не стоит ни в одной очереди, но новые сборки подписываются на него,
+	// а CancelBuild может его отменить.
+	c.queued[job.spec.ID] = job
+	go c.retryAfter(job, job.dequeued, backoff)
+}
+
+// retryAfter ставит джоб в очереди через backoff, если его не отменили раньше.
+func (c *Scheduler) retryAfter(job *inflightJob, dequeued <-chan struct{}, backoff time.Duration) {
+	select {
+	case <-c.timeAfter(backoff):
+	case <-dequeued:
+		return
+	case <-c.stop:
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	select {
+	case <-dequeued:
+		return
+	default:
+	}
+
+	c.schedule(job)
+	c.notify()
+}
+
+// head возвращает самый срочный джоб глобальной очереди владельца t, который можно отдать воркеру workerID.
+// Вызывается под c.mu.
+func (c *Scheduler) head(t *tenant, workerID api.WorkerID) *inflightJob {
+	if len(t.queue) == 0 {
+		return nil
+	}
+	if !c.skips(t.queue[0], workerID) {
+		return t.queue[0]
+	}
+
+	var best *inflightJob
+	for _, job := range t.queue[1:] {
+		if !c.skips(job, workerID) && (best == nil || job.before(best)) {
+			best = job
+		}
+	}
+	return best
+}
+
+// excludes возвращает true, если джоб не стоит отдавать воркеру workerID: джоб уже падал на нём
+// из-за инфраструктурной ошибки, а у шедулера есть другие воркеры не в карантине. Вызывается под c.mu.
+func (c *Scheduler) excludes(job *inflightJob, workerID api.WorkerID) bool {
+	if !slices.Contains(job.excluded, workerID) {
+		return false
+	}
+	for w := range c.workers {
+		if _, ok := c.quarantined[w]; !ok && !slices.Contains(job.excluded, w) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+	"distributed_build/pkg/scheduler"
+)
+
+func failure(id build.ID, kind api.FailureKind, exitCode int) *api.JobResult {
+	err := "failed"
+	return &api.JobResult{ID: id, ExitCode: exitCode, Error: &err, Failure: kind}
+}
+
+func requireNotFinished(t *testing.T, job *scheduler.PendingJob) {
+	select {
+	case <-job.Finished:
+		t.Fatal("job must be retried")
+	default:
+	}
+}
+
+func TestRetryInfraFailure(t *testing.T) {
+	s, clock := newFakeClockScheduler(t, scheduler.Config{Retry: scheduler.RetryConfig{MaxInfraRetries: 1, Backoff: time.Second}}, "w0", "w1")
+
+	jobID := build.ID{'a'}
+	job := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: jobID}})
+	require.Equal(t, job, s.TryPickJob("w0"))
+
+	require.True(t, s.OnJobComplete("w0", jobID, failure(jobID, api.FailureInfra, 0)))
+	requireNotFinished(t, job)
+	require.Equal(t, &api.JobRetry{ID: jobID, Attempt: 2, Worker: "w0", Failure: api.FailureInfra, Error: "failed"}, <-job.Retried)
+
+	require.Nil(t, s.TryPickJob("w1"), "job must wait for backoff")
+	blockUntil(t, clock, 1)
+	clock.Advance(time.Second)
+	requireWork(t, s, "w1", job)
+
+	res := failure(jobID, api.FailureInfra, 0)
+	require.True(t, s.OnJobComplete("w1", jobID, res))
+	<-job.Finished
+	require.Equal(t, res, job.Result, "retries are exhausted")
+}
+
+func TestRetryDifferentWorker(t *testing.T) {
+	s, _ := newFakeClockScheduler(t, scheduler.Config{Retry: scheduler.RetryConfig{MaxInfraRetries: 2}}, "w0", "w1")
+
+	jobID := build.ID{'a'}
+	job := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: jobID}})
+	require.Equal(t, job, s.TryPickJob("w0"))
+	require.True(t, s.OnJobComplete("w0", jobID, failure(jobID, api.FailureInfra, 0)))
+
+	require.Nil(t, s.TryPickJob("w0"), "job must be retried on a different worker")
+	require.Equal(t, job, s.TryPickJob("w1"))
+	require.True(t, s.OnJobComplete("w1", jobID, failure(jobID, api.FailureInfra, 0)))
+
+	require.Equal(t, job, s.TryPickJob("w0"), "all workers failed the job, any of them may retry it")
+}
+
+func TestRetryJobFailure(t *testing.T) {
+	s, _ := newFakeClockScheduler(t, scheduler.Config{Retry: scheduler.RetryConfig{MaxJobRetries: 1}}, "w0")
+
+	a, b := build.ID{'a'}, build.ID{'b'}
+	jobA := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: a}})
+	jobB := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: b, Retryable: true}})
+	require.Equal(t, jobA, s.TryPickJob("w0"))
+	require.Equal(t, jobB, s.TryPickJob("w0"))
+
+	res := failure(a, "", 1)
+	require.True(t, s.OnJobComplete("w0", a, res))
+	<-jobA.Finished
+	require.Equal(t, res, jobA.Result, "job failures are final unless the job is retryable")
+
+	require.True(t, s.OnJobComplete("w0", b, failure(b, api.FailureJob, 1)))
+	requireNotFinished(t, jobB)
+	require.Equal(t, jobB, s.TryPickJob("w0"), "flaky job may be retried on the same worker")
+
+	require.True(t, s.OnJobComplete("w0", b, &api.JobResult{ID: b}))
+	<-jobB.Finished
+	require.Nil(t, jobB.Result.Error)
+}
+
+func TestRetryOOM(t *testing.T) {
+	s, _ := newFakeClockScheduler(t, scheduler.Config{Retry: scheduler.RetryConfig{MaxJobRetries: 1, MaxInfraRetries: 1}}, "w0", "w1")
+
+	jobID := build.ID{'a'}
+	job := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: jobID, Retryable: true}})
+	require.Equal(t, job, s.TryPickJob("w0"))
+
+	res := failure(jobID, api.FailureOOM, 0)
+	require.True(t, s.OnJobComplete("w0", jobID, res))
+	<-job.Finished
+	require.Equal(t, res, job.Result, "OOM is final even for retryable jobs")
+	require.Empty(t, job.Retried)
+	require.Nil(t, s.TryPickJob("w1"))
+}
+
+func TestRetryBackoff(t *testing.T) {
+	retry := scheduler.RetryConfig{MaxInfraRetries: 3, Backoff: time.Second, MaxBackoff: 3 * time.Second}
+	s, clock := newFakeClockScheduler(t, scheduler.Config{Retry: retry}, "w0")
+
+	jobID := build.ID{'a'}
+	job := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: jobID}})
+
+	require.Equal(t, job, s.TryPickJob("w0"))
+	for i, backoff := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		require.True(t, s.OnJobComplete("w0", jobID, failure(jobID, api.FailureInfra, 0)))
+		require.Equal(t, i+2, (<-job.Retried).Attempt)
+
+		blockUntil(t, clock, 1)
+		clock.Advance(backoff - time.Millisecond)
+		require.Nil(t, s.TryPickJob("w0"))
+
+		clock.Advance(time.Millisecond)
+		requireWork(t, s, "w0", job)
+	}
+
+	require.True(t, s.OnJobComplete("w0", jobID, &api.JobResult{ID: jobID}))
+	<-job.Finished
+}
+
+func TestRetryCancel(t *testing.T) {
+	s, clock := newFakeClockScheduler(t, scheduler.Config{Retry: scheduler.RetryConfig{MaxInfraRetries: 1, Backoff: time.Second}}, "w0")
+
+	jobID := build.ID{'a'}
+	job := s.ScheduleBuildJob(build.ID{1}, &api.JobSpec{Job: build.Job{ID: jobID}})
+	require.Equal(t, job, s.TryPickJob("w0"))
+	require.True(t, s.OnJobComplete("w0", jobID, failure(jobID, api.FailureInfra, 0)))
+
+	s.CancelBuild(build.ID{1}, "cancelled by user")
+	<-job.Finished
+
+	clock.Advance(time.Second)
+	require.Nil(t, s.TryPickJob("w0"), "cancelled job must not return from backoff")
+}
@@ -4,6 +4,10 @@ package scheduler
 
 import (
 	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -12,86 +16,649 @@ import (
 	"distributed_build/pkg/build"
 )
 
+// PendingJob - джоб, который ждёт сборка.
+//
+// Если несколько сборок ставят в очередь джоб с одним ID, джоб выполняется один раз, а каждая сборка
+// получает свой PendingJob. Finished закрывается, когда джоб завершился или сборка была отменена.
+//
+// Перед каждым перезапуском джоба в Retried приходит описание неудавшегося запуска, см. RetryConfig.
 type PendingJob struct {
 	Job      *api.JobSpec
 	Finished chan struct{}
 	Result   *api.JobResult
+	Retried  chan *api.JobRetry
+
+	buildID build.ID
+	job     *inflightJob
+	// priority и path - приоритет сборки и критический путь джоба в ней, см. subscribe.
+	priority int
+	path     time.Duration
+}
+
+func (p *PendingJob) finish(res *api.JobResult) {
+	p.Result = res
+	close(p.Finished)
 }
 
 type Config struct {
 	CacheTimeout time.Duration
 	DepsTimeout  time.Duration
+
+	// WorkerTimeout задаёт, сколько шедулер ждёт heartbeat от воркера, прежде чем объявить его мёртвым.
+	// Ноль отключает отслеживание воркеров.
+	WorkerTimeout time.Duration
+
+	// Tenants задаёт квоты отдельных владельцев сборок. Остальные владельцы получают DefaultTenant.
+	Tenants       map[string]TenantConfig
+	DefaultTenant TenantConfig
+
+	Retry       RetryConfig
+	Quarantine  QuarantineConfig
+	Speculation SpeculationConfig
 }
 
 type Scheduler struct {
 	logger    *zap.Logger
 	timeAfter func(d time.Duration) <-chan time.Time
-	queue     chan *PendingJob
+	now       func() time.Time
+	config    Config
 
-	config        Config
-	jobCache      map[build.ID][]api.WorkerID
-	workerPending map[api.WorkerID]*PendingJob
+	mu        sync.Mutex
+	tenants   map[string]*tenant
+	builds    map[build.ID]*buildState
+	durations map[string]time.Duration
+	usage     map[string]api.ResourceUsage
+	seq       uint64
+	wakeup    chan struct{}
+	stop      chan struct{}
+	stopped   bool
+	jobCache  map[build.ID][]api.WorkerID
+	// unshared перечисляет копии из jobCache, собранные не в окружении из build.Cmd, см. LocateCached.
+	unshared  map[build.ID][]api.WorkerID
+	running   map[build.ID]*inflightJob
+	cancelled map[api.WorkerID][]build.ID
+
+	workers    map[api.WorkerID]*workerState
+	lost       map[api.WorkerID]map[build.ID]struct{}
+	queued     map[build.ID]*inflightJob
+	dependents map[build.ID]jobSet
+
+	quarantined map[api.WorkerID]*api.Quarantine
 }
 
 func NewScheduler(l *zap.Logger, config Config, timeAfter func(d time.Duration) <-chan time.Time) *Scheduler {
 	return &Scheduler{
-		logger:        l,
-		config:        config,
-		timeAfter:     timeAfter,
-		queue:         make(chan *PendingJob),
-		workerPending: make(map[api.WorkerID]*PendingJob),
-		jobCache:      make(map[build.ID][]api.WorkerID),
+		logger:     l,
+		config:     config,
+		timeAfter:  timeAfter,
+		now:        time.Now,
+		wakeup:     make(chan struct{}),
+		stop:       make(chan struct{}),
+		tenants:    make(map[string]*tenant),
+		builds:     make(map[build.ID]*buildState),
+		durations:  make(map[string]time.Duration),
+		usage:      make(map[string]api.ResourceUsage),
+		jobCache:   make(map[build.ID][]api.WorkerID),
+		unshared:   make(map[build.ID][]api.WorkerID),
+		running:    make(map[build.ID]*inflightJob),
+		cancelled:  make(map[api.WorkerID][]build.ID),
+		workers:    make(map[api.WorkerID]*workerState),
+		lost:       make(map[api.WorkerID]map[build.ID]struct{}),
+		queued:     make(map[build.ID]*inflightJob),
+		dependents: make(map[build.ID]jobSet),
+
+		quarantined: make(map[api.WorkerID]*api.Quarantine),
+	}
+}
+
+// SetNow задаёт часы шедулера, если timeAfter в NewScheduler идёт не по time.Now, например в тестах.
+// По ним шедулер ставит время карантина и измеряет длительность джобов. Вызывается до первого джоба.
+func (c *Scheduler) SetNow(now func() time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = now
+}
+
+// RegisterWorker заводит локальные очереди воркера. Воркер, который пришёл за джобом
+// или сообщил о новых артефактах, регистрируется автоматически.
+func (c *Scheduler) RegisterWorker(workerID api.WorkerID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.worker(workerID)
+}
+
+// notify будит все горутины, ждущие в PickJob и WaitWork. Вызывается под c.mu.
+func (c *Scheduler) notify() {
+	close(c.wakeup)
+	c.wakeup = make(chan struct{})
+}
+
+// tenant возвращает состояние владельца name, создавая его при первом обращении. Вызывается под c.mu.
+func (c *Scheduler) tenant(name string) *tenant {
+	t, ok := c.tenants[name]
+	if !ok {
+		t = &tenant{name: name, config: c.config.tenant(name)}
+		c.tenants[name] = t
+	}
+	return t
+}
+
+// pruneTenant забывает владельца t, если у него не осталось ни сборок, ни джобов: имена владельцев
+// приходят от клиентов, и без этого c.tenants рос бы бесконечно. Вызывается под c.mu.
+func (c *Scheduler) pruneTenant(t *tenant) {
+	if t.builds > 0 || t.running > 0 || t.queue.Len() > 0 {
+		return
+	}
+	for _, jobs := range []map[build.ID]*inflightJob{c.queued, c.running} {
+		for _, job := range jobs {
+			if job.tenant == t {
+				return
+			}
+		}
+	}
+	delete(c.tenants, t.name)
+}
+
+// AdmitBuild регистрирует сборку buildID. Джобы сборки, поставленные через ScheduleBuildJob,
+// делят слоты воркеров с другими владельцами пропорционально весам и упорядочиваются по opts.
+//
+// Если у владельца уже MaxQueuedBuilds незавершённых сборок, возвращает ошибку, обёрнутую в
+// api.ErrQuotaExceeded. Координатор вызывает AdmitBuild из StartBuild и FinishBuild после завершения сборки.
+func (c *Scheduler) AdmitBuild(buildID build.ID, opts BuildOptions) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := c.tenant(opts.Tenant)
+	if limit := t.config.MaxQueuedBuilds; limit > 0 && t.builds >= limit {
+		c.logger.Info("build rejected", zap.String("tenant", opts.Tenant), zap.String("build_id", buildID.String()))
+		return fmt.Errorf("%w: tenant %q already has %d unfinished builds", api.ErrQuotaExceeded, opts.Tenant, t.builds)
+	}
+
+	t.builds++
+	c.builds[buildID] = &buildState{tenant: t, opts: opts}
+	return nil
+}
+
+// FinishBuild освобождает квоту сборки, зарегистрированной через AdmitBuild.
+func (c *Scheduler) FinishBuild(buildID build.ID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if b, ok := c.builds[buildID]; ok {
+		delete(c.builds, buildID)
+		b.tenant.builds--
+		c.pruneTenant(b.tenant)
+	}
+}
+
+// JobDuration возвращает, сколько выполнялся последний завершённый джоб с именем job.Name.
+//
+// Годится как cost для build.CriticalPath: для джобов, которые ещё не запускались, возвращает fallback.
+func (c *Scheduler) JobDuration(job *build.Job, fallback time.Duration) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if d, ok := c.durations[job.Name]; ok {
+		return d
 	}
+	return fallback
 }
 
+// JobUsage возвращает ресурсы, которые потратил последний успешный запуск джоба с именем job.Name.
+// Возвращает false, если такой джоб ещё не завершался или воркер не смог посчитать его ресурсы.
+func (c *Scheduler) JobUsage(job *build.Job) (api.ResourceUsage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	u, ok := c.usage[job.Name]
+	return u, ok
+}
+
+// LocateArtifact возвращает воркера, у которого в кеше есть артефакт id. Если таких воркеров несколько,
+// выбирает случайного, чтобы раздача артефакта не легла на одного воркера.
 func (c *Scheduler) LocateArtifact(id build.ID) (api.WorkerID, bool) {
-	worker, ok := c.jobCache[id]
-	if !ok || len(worker) == 0 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return pickWorker(c.jobCache[id])
+}
+
+func pickWorker(workers []api.WorkerID) (api.WorkerID, bool) {
+	if len(workers) == 0 {
 		return "", false
 	}
-	return worker[0], true
+	return workers[rand.IntN(len(workers))], true
 }
 
-func (c *Scheduler) OnJobComplete(workerID api.WorkerID, jobID build.ID, res *api.JobResult) bool {
+// addLocation запоминает, что артефакт jobID есть в кеше воркера workerID. Копию, которая не shared,
+// можно только скачать как зависимость, см. LocateCached. Вызывается под c.mu.
+func (c *Scheduler) addLocation(workerID api.WorkerID, jobID build.ID, shared bool) {
+	if shared && !c.shared(workerID, jobID) {
+		c.unshared[jobID] = slices.DeleteFunc(c.unshared[jobID], func(id api.WorkerID) bool { return id == workerID })
+		if len(c.unshared[jobID]) == 0 {
+			delete(c.unshared, jobID)
+		}
+		c.onLocation(workerID, jobID, true)
+		return
+	}
+	if slices.Contains(c.jobCache[jobID], workerID) {
+		return
+	}
+
 	c.jobCache[jobID] = append(c.jobCache[jobID], workerID)
-	_, ok := c.workerPending[workerID]
+	if !shared {
+		c.unshared[jobID] = append(c.unshared[jobID], workerID)
+	}
+	c.onLocation(workerID, jobID, shared)
+}
+
+// OnArtifactsAdded запоминает артефакты, которые появились в кеше воркера, например скачанные у других
+// воркеров. Координатор вызывает его для HeartbeatRequest.AddedArtifacts.
+func (c *Scheduler) OnArtifactsAdded(workerID api.WorkerID, artifacts []build.ID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := c.worker(workerID)
+	for _, id := range artifacts {
+		// Скачанная копия годится для других сборок, только если её источник годился.
+		shared := w.hermetic()
+		for _, source := range c.jobCache[id] {
+			if source != workerID {
+				shared = c.shared(source, id)
+				if shared {
+					break
+				}
+			}
+		}
+		c.addLocation(workerID, id, shared)
+	}
+	c.notify()
+}
+
+func (c *Scheduler) OnJobComplete(workerID api.WorkerID, jobID build.ID, res *api.JobResult) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if res.Error == nil {
+		// Упавший или отменённый джоб, например проигравшая копия отстающего джоба, артефакта не оставляет.
+		c.addLocation(workerID, jobID, c.worker(workerID).hermetic())
+	}
+
+	job, ok := c.running[jobID]
 	if !ok {
+		// Воркер, которого посчитали мёртвым, успел доделать джоб, пока тот ждал в очереди.
+		job, ok = c.adopt(workerID, jobID)
+	}
+	if !ok || (job.worker != workerID && job.speculative != workerID) {
+		c.notify()
 		return false
 	}
-	c.workerPending[workerID].Result = res
-	go func() {
-		c.workerPending[workerID].Finished <- struct{}{}
-	}()
+	if job.speculative != "" && !c.onSpeculativeComplete(job, workerID, res) {
+		c.recordResult(workerID, res)
+		c.notify()
+		return true
+	}
+	delete(c.running, jobID)
+	job.tenant.running--
+	c.recordResult(workerID, res)
+	if res.Error == nil {
+		c.durations[job.spec.Name] = c.now().Sub(job.started)
+		if res.Usage != nil {
+			c.usage[job.spec.Name] = *res.Usage
+		}
+	} else if c.retry(job, res) {
+		c.notify()
+		return true
+	}
+	job.finish(res)
+	c.pruneTenant(job.tenant)
 
+	// Освободился слот владельца, который мог упираться в MaxRunningJobs, и у воркера
+	// мог появиться джоб в локальной очереди.
+	c.notify()
 	return true
 }
 
 func (c *Scheduler) ScheduleJob(job *api.JobSpec) *PendingJob {
+	return c.ScheduleBuildJob(build.ID{}, job)
+}
 
+// ScheduleBuildJob ставит в очереди джоб сборки buildID по правилам из README.
+//
+// Сборка нужна, чтобы отменить все её джобы через CancelBuild и поставить джоб в очередь её владельца
+// (см. AdmitBuild). Джобы сборок, не прошедших через AdmitBuild, принадлежат владельцу по умолчанию.
+//
+// Если джоб с тем же ID уже стоит в очереди или выполняется, новый джоб не ставится: сборка
+// подписывается на существующий и получит его результат. Квоты владельца этой сборки джоб не расходует.
+func (c *Scheduler) ScheduleBuildJob(buildID build.ID, spec *api.JobSpec) *PendingJob {
 	pendingJob := &PendingJob{
-		Job:      job,
+		Job:      spec,
 		Finished: make(chan struct{}),
 		Result:   nil,
+		Retried:  make(chan *api.JobRetry, retryBacklog),
+		buildID:  buildID,
 	}
-	go func() {
-		c.queue <- pendingJob
-	}()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var t *tenant
+	priority, path := 0, time.Duration(0)
+	if b, ok := c.builds[buildID]; ok {
+		t, priority, path = b.tenant, b.opts.Priority, b.opts.CriticalPath[spec.ID]
+	}
+
+	job, ok := c.queued[spec.ID]
+	if !ok {
+		job, ok = c.running[spec.ID]
+	}
+	if ok {
+		c.subscribe(job, pendingJob, priority, path)
+		c.logger.Debug("job deduplicated", zap.String("job_id", spec.ID.String()), zap.String("build_id", buildID.String()))
+		return pendingJob
+	}
+
+	if t == nil {
+		t = c.tenant("")
+	}
+
+	c.seq++
+	job = &inflightJob{
+		spec:     spec,
+		features: api.JobFeatures(&spec.Job),
+		tenant:   t,
+		seq:      c.seq,
+		index:    -1,
+		attempt:  1,
+		dequeued: make(chan struct{}),
+	}
+	c.subscribe(job, pendingJob, priority, path)
+
+	c.schedule(job)
+	c.notify()
 	return pendingJob
 }
 
-func (c *Scheduler) PickJob(ctx context.Context, workerID api.WorkerID) *PendingJob {
+// schedule ставит джоб в очереди и запускает его продвижение по очередям. Вызывается под c.mu.
+func (c *Scheduler) schedule(job *inflightJob) {
+	if waitCache := c.enqueue(job); waitCache || job.stage != stageGlobal {
+		go c.promote(job, job.dequeued, waitCache)
+	}
+}
+
+// promote переносит джоб во вторые локальные очереди через CacheTimeout, если waitCache,
+// и затем в глобальную очередь через DepsTimeout.
+//
+// dequeued - канал job.dequeued на момент постановки в очередь: джоб, который вернули в очередь
+// после потери воркера, продвигает уже новая горутина.
+func (c *Scheduler) promote(job *inflightJob, dequeued <-chan struct{}, waitCache bool) {
+	if waitCache {
+		select {
+		case <-c.timeAfter(c.config.CacheTimeout):
+		case <-dequeued:
+			return
+		case <-c.stop:
+			return
+		}
+
+		if !c.promoteTo(job, dequeued, c.toDeps) {
+			return
+		}
+	}
+
 	select {
-	case job, ok := <-c.queue:
-		if !ok {
-			return nil
+	case <-c.timeAfter(c.config.DepsTimeout):
+	case <-dequeued:
+		return
+	case <-c.stop:
+		return
+	}
+	c.promoteTo(job, dequeued, c.toGlobal)
+}
+
+// promoteTo применяет to к джобу, если он ещё стоит в очередях.
+func (c *Scheduler) promoteTo(job *inflightJob, dequeued <-chan struct{}, to func(job *inflightJob)) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	select {
+	case <-dequeued:
+		return false
+	default:
+	}
+
+	to(job)
+	c.notify()
+	return true
+}
+
+// next возвращает самый срочный джоб из глобальных очередей, который может взять воркер workerID:
+// джоб владельца с наименьшим числом бегущих джобов в пересчёте на вес, а при равенстве - более
+// срочный джоб. Возвращает nil, если запускать нечего. Вызывается под c.mu.
+func (c *Scheduler) next(workerID api.WorkerID) *inflightJob {
+	var best *inflightJob
+	for _, t := range c.tenants {
+		if !t.canRun() {
+			continue
+		}
+		if job := c.head(t, workerID); job != nil && (best == nil || job.better(best)) {
+			best = job
+		}
+	}
+	return best
+}
+
+// pick забирает для воркера workerID джоб, выбранный candidate. Если в очередях для воркера ничего нет,
+// отдаёт ему копию отстающего джоба, см. SpeculationConfig. Вызывается под c.mu.
+//
+// Возвращает PendingJob первой из подписанных на джоб сборок.
+//
+// beat - номер heartbeat-а, в ответ на который воркер получит джоб, или ноль для последнего heartbeat-а.
+func (c *Scheduler) pick(workerID api.WorkerID, beat uint64) *PendingJob {
+	if c.stopped {
+		return nil
+	}
+	if beat == 0 {
+		beat = c.worker(workerID).beat
+	}
+
+	job := c.candidate(workerID)
+	if job == nil {
+		if job = c.straggler(workerID); job != nil {
+			c.speculate(job, workerID, beat)
+			return job.subscribers[0]
 		}
-		c.workerPending[workerID] = job
-		return job
-	case <-ctx.Done():
 		return nil
 	}
+
+	c.dequeue(job)
+	c.start(job, workerID, beat)
+	return job.subscribers[0]
+}
+
+// start отмечает джоб, убранный из очередей, бегущим на воркере workerID, которому он ушёл в ответ
+// на heartbeat beat. Вызывается под c.mu.
+func (c *Scheduler) start(job *inflightJob, workerID api.WorkerID, beat uint64) {
+	job.tenant.running++
+	job.worker = workerID
+	job.beat = beat
+	job.started = c.now()
+	c.running[job.spec.ID] = job
+
+	if threshold, ok := c.threshold(job); ok && !job.speculated && !c.stopped {
+		go c.watchStraggler(threshold)
+	}
+}
+
+func (c *Scheduler) PickJob(ctx context.Context, workerID api.WorkerID) *PendingJob {
+	for {
+		c.mu.Lock()
+		if c.stopped {
+			c.mu.Unlock()
+			return nil
+		}
+
+		if job := c.pick(workerID, 0); job != nil {
+			c.mu.Unlock()
+			return job
+		}
+
+		wakeup := c.wakeup
+		c.mu.Unlock()
+
+		select {
+		case <-wakeup:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// TryPickJob работает как PickJob, но не ждёт: если очередь пуста, сразу возвращает nil.
+func (c *Scheduler) TryPickJob(workerID api.WorkerID) *PendingJob {
+	return c.TryPickJobFor(workerID, 0)
+}
+
+// TryPickJobFor работает как TryPickJob для воркера, который получит джоб в ответ на heartbeat с номером beat,
+// см. OnNumberedHeartbeat. Ноль в beat означает последний heartbeat воркера.
+func (c *Scheduler) TryPickJobFor(workerID api.WorkerID, beat uint64) *PendingJob {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.pick(workerID, beat)
+}
+
+// WaitWork ждёт, пока для воркера не появится работа: джоб в очереди или отстающий джоб, если у воркера
+// есть свободные слоты, или джоб, который воркер должен убить.
+//
+// Возвращает false, если работа так и не появилась до отмены ctx или остановки шедулера.
+// WaitWork ничего не забирает, поэтому после него работу нужно забрать через TryPickJob и JobsToCancel.
+// Если её успел забрать кто-то другой, их результат будет пустым.
+//
+// Пока WaitWork ждёт, воркер считается живым, см. watchWorker.
+func (c *Scheduler) WaitWork(ctx context.Context, workerID api.WorkerID, freeSlots int) bool {
+	c.mu.Lock()
+	w := c.worker(workerID)
+	w.polls++
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		w.polls--
+		w.beats++
+		c.mu.Unlock()
+	}()
+
+	for {
+		c.mu.Lock()
+		if c.stopped {
+			c.mu.Unlock()
+			return false
+		}
+
+		if len(c.cancelled[workerID]) != 0 || (freeSlots > 0 && (c.candidate(workerID) != nil || c.straggler(workerID) != nil)) {
+			c.mu.Unlock()
+			return true
+		}
+
+		wakeup := c.wakeup
+		c.mu.Unlock()
+
+		select {
+		case <-wakeup:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// CancelBuild отписывает сборку buildID от всех её джобов. PendingJob сборки завершаются с ошибкой reason.
+//
+// Джобы, на которые больше не подписана ни одна сборка, убираются из очереди, а бегущие помечаются
+// к отмене. Воркеры узнают о бегущих джобах, которые нужно убить, из JobsToCancel. Джобы, которые
+// ждут другие сборки, продолжают выполняться.
+func (c *Scheduler) CancelBuild(buildID build.ID, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	affected := make(map[*tenant]struct{})
+	if b, ok := c.builds[buildID]; ok {
+		affected[b.tenant] = struct{}{}
+	}
+
+	for _, job := range c.queued {
+		if c.unsubscribe(job, buildID, reason) {
+			affected[job.tenant] = struct{}{}
+			c.dequeue(job)
+		}
+	}
+
+	for jobID, job := range c.running {
+		if !c.unsubscribe(job, buildID, reason) {
+			continue
+		}
+
+		affected[job.tenant] = struct{}{}
+		delete(c.running, jobID)
+		job.tenant.running--
+		c.cancelCopies(job, jobID)
+	}
+
+	for t := range affected {
+		c.pruneTenant(t)
+	}
+
+	c.notify()
+	c.logger.Info("build cancelled", zap.String("build_id", buildID.String()), zap.String("reason", reason))
+}
+
+// unsubscribe завершает PendingJob сборки buildID с ошибкой reason и отписывает их от джоба.
+// Приоритет джоба, на который остались подписаны другие сборки, пересчитывается по ним.
+// Возвращает true, если на джоб больше никто не подписан. Вызывается под c.mu.
+func (c *Scheduler) unsubscribe(job *inflightJob, buildID build.ID, reason string) bool {
+	subscribers := job.subscribers[:0]
+	for _, pendingJob := range job.subscribers {
+		if pendingJob.buildID != buildID {
+			subscribers = append(subscribers, pendingJob)
+			continue
+		}
+		pendingJob.finish(&api.JobResult{ID: job.spec.ID, Error: &reason})
+	}
+	if len(subscribers) == len(job.subscribers) {
+		return false
+	}
+
+	for i := len(subscribers); i < len(job.subscribers); i++ {
+		job.subscribers[i] = nil
+	}
+	job.subscribers = subscribers
+	if len(subscribers) == 0 {
+		return true
+	}
+
+	c.reprioritize(job)
+	return false
+}
+
+// JobsToCancel возвращает джобы, которые воркер должен убить. Каждый джоб возвращается один раз.
+func (c *Scheduler) JobsToCancel(workerID api.WorkerID) []build.ID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	jobs := c.cancelled[workerID]
+	delete(c.cancelled, workerID)
+	return jobs
 }
 
 func (c *Scheduler) Stop() {
-	close(c.queue)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.stopped {
+		c.stopped = true
+		close(c.stop)
+	}
+	c.notify()
 }
@@ -2,6 +2,7 @@ package scheduler_test
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -59,3 +60,339 @@ func TestOnJobComplete(t *testing.T) {
 	}
 
 }
+
+func TestCancelBuild(t *testing.T) {
+	s, teardown := setupScheduler()
+	defer teardown()
+
+	buildA, buildB := build.ID{'a'}, build.ID{'b'}
+
+	runningJob := s.ScheduleBuildJob(buildA, &api.JobSpec{Job: build.Job{ID: build.ID{'a', 1}}})
+	queuedJob := s.ScheduleBuildJob(buildA, &api.JobSpec{Job: build.Job{ID: build.ID{'a', 2}}})
+	otherJob := s.ScheduleBuildJob(buildB, &api.JobSpec{Job: build.Job{ID: build.ID{'b', 1}}})
+
+	require.Equal(t, runningJob, s.PickJob(context.Background(), "worker1"))
+
+	s.CancelBuild(buildA, "cancelled by user")
+
+	for _, job := range []*scheduler.PendingJob{runningJob, queuedJob} {
+		select {
+		case <-job.Finished:
+			require.Equal(t, "cancelled by user", *job.Result.Error)
+		default:
+			t.Fatalf("cancelled job is not finished")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.Equal(t, otherJob, s.PickJob(ctx, "worker2"))
+
+	require.Equal(t, []build.ID{runningJob.Job.ID}, s.JobsToCancel("worker1"))
+	require.Empty(t, s.JobsToCancel("worker1"))
+	require.Empty(t, s.JobsToCancel("worker2"))
+
+	require.False(t, s.OnJobComplete("worker1", runningJob.Job.ID, &api.JobResult{ID: runningJob.Job.ID}))
+	require.True(t, s.OnJobComplete("worker2", otherJob.Job.ID, &api.JobResult{ID: otherJob.Job.ID}))
+}
+
+func TestWaitWork(t *testing.T) {
+	s, teardown := setupScheduler()
+	defer teardown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.False(t, s.WaitWork(ctx, "worker1", 1), "no work must time out")
+	require.Nil(t, s.TryPickJob("worker1"))
+
+	woken := make(chan bool)
+	go func() {
+		woken <- s.WaitWork(context.Background(), "worker1", 1)
+	}()
+
+	job := s.ScheduleBuildJob(build.ID{'a'}, &api.JobSpec{Job: build.Job{ID: build.ID{'a', 1}}})
+	require.True(t, <-woken)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.False(t, s.WaitWork(ctx, "worker2", 0), "worker without free slots must not wake up for jobs")
+
+	require.Equal(t, job, s.TryPickJob("worker1"))
+
+	go func() {
+		woken <- s.WaitWork(context.Background(), "worker1", 0)
+	}()
+
+	s.CancelBuild(build.ID{'a'}, "cancelled by user")
+	require.True(t, <-woken)
+	require.Equal(t, []build.ID{job.Job.ID}, s.JobsToCancel("worker1"))
+}
+
+func scheduleJobs(t *testing.T, s *scheduler.Scheduler, tenant string, buildID build.ID, n int) {
+	require.NoError(t, s.AdmitBuild(buildID, scheduler.BuildOptions{Tenant: tenant}))
+	for i := 0; i < n; i++ {
+		jobID := buildID
+		jobID[1] = byte(i)
+		s.ScheduleBuildJob(buildID, &api.JobSpec{Job: build.Job{ID: jobID, Name: fmt.Sprintf("%s-%d", tenant, i)}})
+	}
+}
+
+func TestFairShare(t *testing.T) {
+	s, _ := newFakeClockScheduler(t, scheduler.Config{Tenants: map[string]scheduler.TenantConfig{
+		"alice": {Weight: 2},
+	}})
+
+	// Большая сборка alice пришла первой, но не должна занять все слоты.
+	scheduleJobs(t, s, "alice", build.ID{'a'}, 100)
+	scheduleJobs(t, s, "bob", build.ID{'b'}, 100)
+
+	picked := map[build.ID]int{}
+	for i := 0; i < 30; i++ {
+		job := s.TryPickJob(api.WorkerID(fmt.Sprintf("worker%d", i)))
+		require.NotNil(t, job)
+		picked[build.ID{job.Job.ID[0]}]++
+	}
+	require.Equal(t, 20, picked[build.ID{'a'}])
+	require.Equal(t, 10, picked[build.ID{'b'}])
+}
+
+func TestFairShareIdleTenant(t *testing.T) {
+	s, _ := newFakeClockScheduler(t, scheduler.Config{})
+
+	scheduleJobs(t, s, "alice", build.ID{'a'}, 3)
+
+	// Пока bob ничего не запускает, alice может занять все слоты.
+	for i := 0; i < 3; i++ {
+		require.NotNil(t, s.TryPickJob("worker0"))
+	}
+
+	scheduleJobs(t, s, "alice", build.ID{'c'}, 3)
+	scheduleJobs(t, s, "bob", build.ID{'b'}, 3)
+
+	job := s.TryPickJob("worker1")
+	require.Equal(t, byte('b'), job.Job.ID[0], "tenant with fewer running jobs goes first")
+}
+
+func TestTenantQuotas(t *testing.T) {
+	s, _ := newFakeClockScheduler(t, scheduler.Config{Tenants: map[string]scheduler.TenantConfig{
+		"alice": {MaxRunningJobs: 1, MaxQueuedBuilds: 1},
+	}})
+
+	scheduleJobs(t, s, "alice", build.ID{'a'}, 2)
+
+	err := s.AdmitBuild(build.ID{'c'}, scheduler.BuildOptions{Tenant: "alice"})
+	require.ErrorIs(t, err, api.ErrQuotaExceeded)
+	require.Contains(t, err.Error(), "alice")
+	require.NoError(t, s.AdmitBuild(build.ID{'b'}, scheduler.BuildOptions{Tenant: "bob"}), "quota of alice must not affect bob")
+
+	first := s.TryPickJob("worker0")
+	require.NotNil(t, first)
+	require.Nil(t, s.TryPickJob("worker1"), "alice must not run more than MaxRunningJobs jobs")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.False(t, s.WaitWork(ctx, "worker1", 1))
+
+	woken := make(chan bool)
+	go func() {
+		woken <- s.WaitWork(context.Background(), "worker1", 1)
+	}()
+
+	require.True(t, s.OnJobComplete("worker0", first.Job.ID, &api.JobResult{ID: first.Job.ID}))
+	require.True(t, <-woken, "finished job must free a slot of alice")
+	require.NotNil(t, s.TryPickJob("worker1"))
+
+	s.FinishBuild(build.ID{'a'})
+	require.NoError(t, s.AdmitBuild(build.ID{'c'}, scheduler.BuildOptions{Tenant: "alice"}))
+}
+
+func TestFairShareTie(t *testing.T) {
+	s, _ := newFakeClockScheduler(t, scheduler.Config{})
+
+	require.NoError(t, s.AdmitBuild(build.ID{'b'}, scheduler.BuildOptions{Tenant: "bob"}))
+	require.NoError(t, s.AdmitBuild(build.ID{'a'}, scheduler.BuildOptions{Tenant: "alice", Priority: 10}))
+
+	// Приоритет alice не даёт её джобам обгонять джобы bob: при равной доле слотов побеждает
+	// тот, кто раньше встал в очередь.
+	first := s.ScheduleBuildJob(build.ID{'b'}, &api.JobSpec{Job: build.Job{ID: build.ID{'b', 1}}})
+	second := s.ScheduleBuildJob(build.ID{'a'}, &api.JobSpec{Job: build.Job{ID: build.ID{'a', 1}}})
+
+	require.Equal(t, first, s.TryPickJob("worker0"))
+	require.Equal(t, second, s.TryPickJob("worker1"))
+}
+
+func TestTenantPruned(t *testing.T) {
+	s, _ := newFakeClockScheduler(t, scheduler.Config{})
+
+	scheduleJobs(t, s, "alice", build.ID{'a'}, 1)
+	job := s.TryPickJob("worker0")
+	require.NotNil(t, job)
+
+	// Сборка завершилась, но её джоб ещё бежит.
+	s.FinishBuild(build.ID{'a'})
+	require.Equal(t, 1, s.Tenants())
+
+	require.True(t, s.OnJobComplete("worker0", job.Job.ID, &api.JobResult{ID: job.Job.ID}))
+	require.Equal(t, 0, s.Tenants())
+
+	// Сборка завершилась раньше, чем её отменили: владельца забывает CancelBuild.
+	scheduleJobs(t, s, "bob", build.ID{'b'}, 1)
+	s.FinishBuild(build.ID{'b'})
+	require.Equal(t, 1, s.Tenants())
+
+	s.CancelBuild(build.ID{'b'}, "cancelled by user")
+	require.Equal(t, 0, s.Tenants())
+
+	for i := 0; i < 10; i++ {
+		buildID := build.ID{'b', byte(i)}
+		require.NoError(t, s.AdmitBuild(buildID, scheduler.BuildOptions{Tenant: fmt.Sprintf("tenant%d", i)}))
+		s.FinishBuild(buildID)
+	}
+	require.Equal(t, 0, s.Tenants())
+}
+
+func TestBuildPriority(t *testing.T) {
+	s, _ := newFakeClockScheduler(t, scheduler.Config{})
+
+	require.NoError(t, s.AdmitBuild(build.ID{'a'}, scheduler.BuildOptions{Tenant: "alice"}))
+	require.NoError(t, s.AdmitBuild(build.ID{'b'}, scheduler.BuildOptions{Tenant: "alice", Priority: 1}))
+
+	low := s.ScheduleBuildJob(build.ID{'a'}, &api.JobSpec{Job: build.Job{ID: build.ID{'a', 1}}})
+	high := s.ScheduleBuildJob(build.ID{'b'}, &api.JobSpec{Job: build.Job{ID: build.ID{'b', 1}}})
+
+	require.Equal(t, high, s.TryPickJob("worker0"))
+	require.Equal(t, low, s.TryPickJob("worker0"))
+}
+
+// simulate выполняет граф на workers воркерах в дискретном времени и возвращает время окончания сборки.
+//
+// Джоб с именем name выполняется cost[name] тиков. Как и координатор, simulate ставит джоб в очередь,
+// когда завершились все его зависимости.
+func simulate(t *testing.T, jobs []build.Job, workers int, cost map[string]int, opts scheduler.BuildOptions) int {
+	s, _ := newFakeClockScheduler(t, scheduler.Config{})
+	buildID := build.ID{'s'}
+	require.NoError(t, s.AdmitBuild(buildID, opts))
+
+	waiting := map[build.ID]int{}
+	dependents := map[build.ID][]*build.Job{}
+	for i := range jobs {
+		waiting[jobs[i].ID] = len(jobs[i].Deps)
+		for _, dep := range jobs[i].Deps {
+			dependents[dep] = append(dependents[dep], &jobs[i])
+		}
+	}
+	for i := range jobs {
+		if len(jobs[i].Deps) == 0 {
+			s.ScheduleBuildJob(buildID, &api.JobSpec{Job: jobs[i]})
+		}
+	}
+
+	running := make([]*scheduler.PendingJob, workers)
+	doneAt := make([]int, workers)
+	finished := 0
+	for now := 0; ; now++ {
+		for w, job := range running {
+			if job == nil || doneAt[w] != now {
+				continue
+			}
+
+			running[w] = nil
+			finished++
+			require.True(t, s.OnJobComplete(api.WorkerID(fmt.Sprint(w)), job.Job.ID, &api.JobResult{ID: job.Job.ID}))
+			for _, next := range dependents[job.Job.ID] {
+				if waiting[next.ID]--; waiting[next.ID] == 0 {
+					s.ScheduleBuildJob(buildID, &api.JobSpec{Job: *next})
+				}
+			}
+		}
+
+		if finished == len(jobs) {
+			return now
+		}
+
+		for w := range running {
+			if running[w] != nil {
+				continue
+			}
+			if job := s.TryPickJob(api.WorkerID(fmt.Sprint(w))); job != nil {
+				running[w] = job
+				doneAt[w] = now + cost[job.Job.Name]
+			}
+		}
+	}
+}
+
+func TestCriticalPathMakespan(t *testing.T) {
+	// 20 независимых джобов компиляции и цепочка из 6 джобов линковки, которая приходит в очередь последней.
+	var jobs []build.Job
+	for i := 0; i < 20; i++ {
+		jobs = append(jobs, build.Job{ID: build.ID{'c', byte(i)}, Name: "compile"})
+	}
+	for i := 0; i < 6; i++ {
+		job := build.Job{ID: build.ID{'l', byte(i)}, Name: "link"}
+		if i != 0 {
+			job.Deps = []build.ID{{'l', byte(i - 1)}}
+		}
+		jobs = append(jobs, job)
+	}
+	cost := map[string]int{"compile": 1, "link": 1, "codegen": 8}
+
+	fifo := simulate(t, jobs, 2, cost, scheduler.BuildOptions{})
+	criticalPath := simulate(t, jobs, 2, cost, scheduler.BuildOptions{CriticalPath: build.CriticalPath(jobs, nil)})
+	t.Logf("deep chain: fifo makespan %d, critical path makespan %d", fifo, criticalPath)
+	require.Less(t, criticalPath, fifo)
+
+	// Один длинный джоб кодогенерации. По числу джобов его путь короче цепочки линковки,
+	// поэтому без истории длительностей он запускается слишком поздно.
+	jobs = append(jobs, build.Job{ID: build.ID{'g'}, Name: "codegen"})
+	durations := func(job *build.Job) time.Duration { return time.Duration(cost[job.Name]) * time.Second }
+
+	fifo = simulate(t, jobs, 2, cost, scheduler.BuildOptions{})
+	weighted := simulate(t, jobs, 2, cost, scheduler.BuildOptions{CriticalPath: build.CriticalPath(jobs, durations)})
+	t.Logf("long job: fifo makespan %d, weighted critical path makespan %d", fifo, weighted)
+	require.Less(t, weighted, fifo)
+}
+
+func TestJobDuration(t *testing.T) {
+	s, clock := newFakeClockScheduler(t, scheduler.Config{})
+
+	job := build.Job{ID: build.ID{'a'}, Name: "compile"}
+	require.Equal(t, time.Minute, s.JobDuration(&job, time.Minute))
+
+	s.ScheduleJob(&api.JobSpec{Job: job})
+	require.NotNil(t, s.TryPickJob("worker0"))
+	clock.Advance(10 * time.Millisecond)
+	require.True(t, s.OnJobComplete("worker0", job.ID, &api.JobResult{ID: job.ID}))
+
+	d := s.JobDuration(&build.Job{ID: build.ID{'b'}, Name: "compile"}, time.Minute)
+	require.GreaterOrEqual(t, d, 10*time.Millisecond)
+	require.Less(t, d, time.Minute)
+}
+
+func TestJobUsage(t *testing.T) {
+	s, _ := newFakeClockScheduler(t, scheduler.Config{})
+
+	job := build.Job{ID: build.ID{'a'}, Name: "link"}
+	_, ok := s.JobUsage(&job)
+	require.False(t, ok)
+
+	usage := &api.ResourceUsage{PeakMemory: 1 << 30, CPUTime: time.Minute, ReadBytes: 10, WriteBytes: 20}
+	s.ScheduleJob(&api.JobSpec{Job: job})
+	require.NotNil(t, s.TryPickJob("worker0"))
+	require.True(t, s.OnJobComplete("worker0", job.ID, &api.JobResult{ID: job.ID, Usage: usage}))
+
+	u, ok := s.JobUsage(&build.Job{ID: build.ID{'b'}, Name: "link"})
+	require.True(t, ok)
+	require.Equal(t, *usage, u)
+
+	oom := "killed"
+	job = build.Job{ID: build.ID{'c'}, Name: "link"}
+	s.ScheduleJob(&api.JobSpec{Job: job})
+	require.NotNil(t, s.TryPickJob("worker0"))
+	require.True(t, s.OnJobComplete("worker0", job.ID, &api.JobResult{
+		ID: job.ID, Error: &oom, Failure: api.FailureOOM, Usage: &api.ResourceUsage{PeakMemory: 1 << 20},
+	}))
+
+	u, _ = s.JobUsage(&job)
+	require.Equal(t, *usage, u)
+}
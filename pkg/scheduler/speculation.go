@@ -0,0 +1,148 @@
+//go:build !solution
+
+package scheduler
+
+import (
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+)
+
+// SpeculationConfig задаёт спекулятивный запуск копий джобов, которые выполняются слишком долго.
+// Нулевое значение отключает спекулятивный запуск.
+type SpeculationConfig struct {
+	// Factor задаёт, во сколько раз джоб должен превысить длительность последнего запуска джоба с тем же именем,
+	// чтобы его копия запустилась на свободном воркере.
+	Factor float64
+	// MinRuntime задаёт, сколько джоб должен выполняться, прежде чем его можно будет скопировать.
+	// Короткие джобы копировать невыгодно: копия не успеет обогнать оригинал.
+	MinRuntime time.Duration
+}
+
+func (c *SpeculationConfig) enabled() bool {
+	return c.Factor > 0
+}
+
+// threshold возвращает, сколько должен выполняться джоб, чтобы шедулер запустил его копию.
+// Возвращает false, если джоб с таким именем ещё ни разу не завершался. Вызывается под c.mu.
+func (c *Scheduler) threshold(job *inflightJob) (time.Duration, bool) {
+	config := &c.config.Speculation
+	if !config.enabled() {
+		return 0, false
+	}
+
+	expected, ok := c.durations[job.spec.Name]
+	if !ok {
+		return 0, false
+	}
+	return max(time.Duration(float64(expected)*config.Factor), config.MinRuntime), true
+}
+
+// watchStraggler будит воркеров через d, когда только что запущенный джоб начинает считаться отстающим.
+// Без этого свободный воркер, ждущий в WaitWork, узнал бы об отстающем джобе только на следующем heartbeat-е.
+func (c *Scheduler) watchStraggler(d time.Duration) {
+	select {
+	case <-c.timeAfter(d):
+	case <-c.stop:
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.notify()
+}
+
+// straggler выбирает бегущий джоб, копию которого стоит запустить на воркере workerID: джоб дольше всех
+// превышает свой порог, и его копия ещё не запускалась. Вызывается под c.mu.
+func (c *Scheduler) straggler(workerID api.WorkerID) *inflightJob {
+	if !c.config.Speculation.enabled() {
+		return nil
+	}
+	w := c.worker(workerID)
+	if _, ok := c.quarantined[workerID]; ok || w.draining {
+		return nil
+	}
+
+	var best *inflightJob
+	var bestOverrun time.Duration
+	for _, job := range c.running {
+		if job.speculated || job.worker == workerID || slices.Contains(job.excluded, workerID) || !w.runs(job) {
+			continue
+		}
+
+		threshold, ok := c.threshold(job)
+		if !ok {
+			continue
+		}
+		if overrun := c.now().Sub(job.started) - threshold; overrun >= 0 && (best == nil || overrun > bestOverrun) {
+			best, bestOverrun = job, overrun
+		}
+	}
+	return best
+}
+
+// speculate запускает копию бегущего джоба на воркере workerID, которому она уйдёт в ответ на heartbeat beat.
+// Копия не занимает слот владельца. Вызывается под c.mu.
+func (c *Scheduler) speculate(job *inflightJob, workerID api.WorkerID, beat uint64) {
+	job.speculated = true
+	job.speculative = workerID
+	job.speculativeStarted = c.now()
+	job.speculativeBeat = beat
+
+	c.logger.Info("job speculated",
+		zap.String("job_id", job.spec.ID.String()),
+		zap.String("worker_id", job.worker.String()),
+		zap.String("speculative_worker_id", workerID.String()),
+		zap.Duration("runtime", c.now().Sub(job.started)))
+}
+
+// dropCopy снимает с джоба копию, которая бежала на воркере workerID. Если это была основная копия,
+// основной становится спекулятивная. Вызывается под c.mu.
+func (c *Scheduler) dropCopy(job *inflightJob, workerID api.WorkerID) {
+	if workerID == job.worker {
+		job.worker, job.started, job.beat = job.speculative, job.speculativeStarted, job.speculativeBeat
+	}
+	job.speculative = ""
+}
+
+// onSpeculativeComplete обрабатывает результат одной из двух копий джоба. Возвращает true, если
+// результат res завершает джоб, иначе джоб продолжает ждать другую копию. Вызывается под c.mu.
+//
+// Успешный результат завершает джоб, а проигравшая копия попадает в JobsToCancel своего воркера.
+// Ошибку одной копии шедулер не перезапускает, пока бежит другая.
+func (c *Scheduler) onSpeculativeComplete(job *inflightJob, workerID api.WorkerID, res *api.JobResult) bool {
+	loser := job.speculative
+	if workerID == job.speculative {
+		loser = job.worker
+	}
+
+	if res.Error != nil {
+		c.dropCopy(job, workerID)
+		c.logger.Info("job copy failed",
+			zap.String("job_id", job.spec.ID.String()),
+			zap.String("worker_id", workerID.String()),
+			zap.String("remaining_worker_id", loser.String()))
+		return false
+	}
+
+	c.dropCopy(job, loser)
+	c.cancelled[loser] = append(c.cancelled[loser], job.spec.ID)
+	c.logger.Info("job copy won",
+		zap.String("job_id", job.spec.ID.String()),
+		zap.String("worker_id", workerID.String()),
+		zap.String("cancelled_worker_id", loser.String()))
+	return true
+}
+
+// cancelCopies помечает к отмене все копии джоба jobID. Вызывается под c.mu.
+func (c *Scheduler) cancelCopies(job *inflightJob, jobID build.ID) {
+	c.cancelled[job.worker] = append(c.cancelled[job.worker], jobID)
+	if job.speculative != "" {
+		c.cancelled[job.speculative] = append(c.cancelled[job.speculative], jobID)
+		job.speculative = ""
+	}
+}
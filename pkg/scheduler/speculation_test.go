@@ -0,0 +1,108 @@
+package scheduler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+	"distributed_build/pkg/scheduler"
+)
+
+const minRuntime = 50 * time.Millisecond
+
+// newSpeculationScheduler возвращает шедулер, в истории которого уже есть быстрый джоб с именем "cc".
+// Спекулятивный запуск сравнивает время выполнения джобов с реальным временем, поэтому тесты работают без clockwork.
+func newSpeculationScheduler(t *testing.T) *scheduler.Scheduler {
+	config := scheduler.Config{
+		CacheTimeout: cacheTimeout,
+		DepsTimeout:  depsTimeout,
+		Speculation:  scheduler.SpeculationConfig{Factor: 2, MinRuntime: minRuntime},
+	}
+	s := scheduler.NewScheduler(zaptest.NewLogger(t), config, time.After)
+	t.Cleanup(s.Stop)
+
+	s.RegisterWorker("w0")
+	s.RegisterWorker("w1")
+
+	historyID := build.ID{'h'}
+	history := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: historyID, Name: "cc"}})
+	require.Equal(t, history, s.TryPickJob("w0"))
+	require.True(t, s.OnJobComplete("w0", historyID, &api.JobResult{ID: historyID}))
+	return s
+}
+
+// speculate запускает джоб на w0 и ждёт, пока w1 не получит его копию.
+func speculate(t *testing.T, s *scheduler.Scheduler, buildID, jobID build.ID) *scheduler.PendingJob {
+	job := s.ScheduleBuildJob(buildID, &api.JobSpec{Job: build.Job{ID: jobID, Name: "cc"}})
+	require.Equal(t, job, s.TryPickJob("w0"))
+	require.Nil(t, s.TryPickJob("w1"), "job is not a straggler yet")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.True(t, s.WaitWork(ctx, "w1", 1), "straggler must wake up idle worker")
+	require.Equal(t, job, s.TryPickJob("w1"))
+	require.Nil(t, s.TryPickJob("w1"), "job must be speculated once")
+	return job
+}
+
+func TestSpeculation(t *testing.T) {
+	s := newSpeculationScheduler(t)
+
+	jobID := build.ID{'a'}
+	job := speculate(t, s, build.ID{}, jobID)
+
+	res := &api.JobResult{ID: jobID}
+	require.True(t, s.OnJobComplete("w1", jobID, res))
+	<-job.Finished
+	require.Equal(t, res, job.Result)
+	require.Equal(t, []build.ID{jobID}, s.JobsToCancel("w0"), "losing copy must be cancelled")
+
+	reason := "cancelled"
+	require.False(t, s.OnJobComplete("w0", jobID, &api.JobResult{ID: jobID, Error: &reason}))
+	w, ok := s.LocateArtifact(jobID)
+	require.True(t, ok)
+	require.Equal(t, api.WorkerID("w1"), w, "cancelled copy leaves no artifact")
+}
+
+func TestSpeculationCopyFails(t *testing.T) {
+	s := newSpeculationScheduler(t)
+
+	jobID := build.ID{'a'}
+	job := speculate(t, s, build.ID{}, jobID)
+
+	require.True(t, s.OnJobComplete("w0", jobID, failure(jobID, api.FailureInfra, 0)))
+	requireNotFinished(t, job)
+
+	res := &api.JobResult{ID: jobID}
+	require.True(t, s.OnJobComplete("w1", jobID, res))
+	<-job.Finished
+	require.Equal(t, res, job.Result)
+	require.Empty(t, s.JobsToCancel("w0"))
+}
+
+func TestSpeculationCancelBuild(t *testing.T) {
+	s := newSpeculationScheduler(t)
+
+	buildID, jobID := build.ID{'b'}, build.ID{'a'}
+	job := speculate(t, s, buildID, jobID)
+
+	s.CancelBuild(buildID, "cancelled")
+	<-job.Finished
+	require.Equal(t, []build.ID{jobID}, s.JobsToCancel("w0"))
+	require.Equal(t, []build.ID{jobID}, s.JobsToCancel("w1"))
+}
+
+func TestNoSpeculationWithoutHistory(t *testing.T) {
+	s := newSpeculationScheduler(t)
+
+	job := s.ScheduleJob(&api.JobSpec{Job: build.Job{ID: build.ID{'a'}, Name: "link"}})
+	require.Equal(t, job, s.TryPickJob("w0"))
+
+	time.Sleep(2 * minRuntime)
+	require.Nil(t, s.TryPickJob("w1"))
+}
@@ -0,0 +1,80 @@
+//go:build !solution
+
+package scheduler
+
+import (
+	"time"
+
+	"distributed_build/pkg/build"
+)
+
+// TenantConfig задаёт вес и квоты владельца сборок. Нулевые значения означают вес 1 и отсутствие квот.
+type TenantConfig struct {
+	// Weight задаёт долю слотов воркеров, которую получает владелец, когда кластер занят
+	// несколькими владельцами.
+	Weight int
+	// MaxRunningJobs ограничивает число одновременно бегущих джобов владельца. Общий джоб нескольких
+	// сборок считается только джобом владельца первой сборки, см. ScheduleBuildJob.
+	MaxRunningJobs int
+	// MaxQueuedBuilds ограничивает число незавершённых сборок владельца, см. AdmitBuild.
+	MaxQueuedBuilds int
+}
+
+func (c *Config) tenant(name string) TenantConfig {
+	config, ok := c.Tenants[name]
+	if !ok {
+		config = c.DefaultTenant
+	}
+	if config.Weight <= 0 {
+		config.Weight = 1
+	}
+	return config
+}
+
+// tenant хранит глобальную очередь и счётчики одного владельца сборок.
+type tenant struct {
+	name    string
+	config  TenantConfig
+	queue   jobQueue
+	running int
+	builds  int
+}
+
+// canRun возвращает true, если владелец может запустить ещё один джоб, не нарушая квоту.
+func (t *tenant) canRun() bool {
+	return t.config.MaxRunningJobs <= 0 || t.running < t.config.MaxRunningJobs
+}
+
+// fairer сравнивает владельцев по числу бегущих джобов в пересчёте на вес. Возвращает -1, если
+// следующий слот нужно отдать t, 1 - если other, и 0, если они равны.
+func (t *tenant) fairer(other *tenant) int {
+	l, r := t.running*other.config.Weight, other.running*t.config.Weight
+	switch {
+	case l < r:
+		return -1
+	case l > r:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// BuildOptions задаёт параметры сборки, с которыми её регистрирует AdmitBuild.
+type BuildOptions struct {
+	// Tenant задаёт владельца сборки, см. api.TenantFromContext.
+	Tenant string
+	// Priority задаёт приоритет сборки среди сборок владельца.
+	Priority int
+	// CriticalPath задаёт для джобов сборки длину оставшегося пути до конца графа, см. build.CriticalPath.
+	// Джобы с более длинным путём запускаются раньше.
+	CriticalPath map[build.ID]time.Duration
+	// Jobs перечисляет джобы графа сборки. Пока сборка не завершена, Drained не отпускает воркера,
+	// у которого есть единственная копия зависимости ещё не выполненного джоба, даже если джоб ещё
+	// не поставлен в очередь.
+	Jobs []build.Job
+}
+
+type buildState struct {
+	tenant *tenant
+	opts   BuildOptions
+}
@@ -0,0 +1,144 @@
+package worker
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/auth"
+)
+
+// DefaultDrainTimeout ограничивает, сколько воркер после начала вывода из кластера ждёт, пока
+// координатор не ответит Drained.
+const DefaultDrainTimeout = 5 * time.Minute
+
+// drainState - режим вывода воркера из кластера, см. Worker.Drain.
+//
+// Воркер в этом режиме не берёт новые джобы, доделывает бегущие и раздаёт артефакты, пока координатор
+// не ответит Drained или не выйдет дедлайн.
+type drainState struct {
+	mu       sync.Mutex
+	deadline time.Time
+	started  chan struct{}
+}
+
+func newDrainState() *drainState {
+	return &drainState{started: make(chan struct{})}
+}
+
+// start включает режим вывода из кластера с дедлайном через timeout. Возвращает false, если режим уже включён:
+// повторный вызов не сдвигает дедлайн.
+func (d *drainState) start(timeout time.Duration) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	select {
+	case <-d.started:
+		return false
+	default:
+	}
+
+	d.deadline = time.Now().Add(timeout)
+	close(d.started)
+	return true
+}
+
+// Started закрывается, когда воркер начал выводиться из кластера.
+func (d *drainState) Started() <-chan struct{} {
+	return d.started
+}
+
+func (d *drainState) draining() bool {
+	select {
+	case <-d.started:
+		return true
+	default:
+		return false
+	}
+}
+
+// prepare помечает heartbeat воркера, который выводится из кластера: свободных слотов у него нет.
+func (d *drainState) prepare(req *api.HeartbeatRequest) {
+	if d.draining() {
+		req.FreeSlots = 0
+		req.Draining = true
+	}
+}
+
+// done возвращает true, если воркер может уйти: координатор ответил Drained или вышел дедлайн.
+// Координатор без api.FeatureDrain никогда не отвечает Drained, и такой воркер уходит по дедлайну.
+func (d *drainState) done(rsp *api.HeartbeatResponse, now time.Time) bool {
+	if !d.draining() {
+		return false
+	}
+	if rsp != nil && rsp.Drained {
+		return true
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return !now.Before(d.deadline)
+}
+
+// drainHandler отдаёт POST /admin/drain, который выводит воркера из кластера.
+//
+// Необязательный параметр timeout задаёт дедлайн в формате time.ParseDuration, по умолчанию DefaultDrainTimeout.
+type drainHandler struct {
+	logger *zap.Logger
+	auth   auth.Authenticator
+	drain  func(timeout time.Duration)
+}
+
+func (h *drainHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/admin/drain", func(w http.ResponseWriter, r *http.Request) {
+		r, ok := auth.Check(h.logger, h.auth, w, r, auth.RoleAdmin)
+		if !ok {
+			return
+		}
+
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		timeout := DefaultDrainTimeout
+		if text := r.URL.Query().Get("timeout"); text != "" {
+			var err error
+			if timeout, err = time.ParseDuration(text); err != nil {
+				errorMessage := "unable to parse drain timeout " + err.Error()
+				h.logger.Error(errorMessage)
+				http.Error(w, errorMessage, http.StatusBadRequest)
+				return
+			}
+		}
+
+		h.logger.Info("drain requested", zap.Duration("timeout", timeout))
+		h.drain(timeout)
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// DrainOnSignal вызывает drain, когда процесс получит один из signals, например SIGTERM.
+// Перестаёт слушать сигналы после первого сигнала или отмены ctx.
+//
+// Бинарник воркера передаёт сюда Worker.Drain, чтобы при раскатке новой версии воркер доделал свои джобы.
+func DrainOnSignal(ctx context.Context, drain func(), signals ...os.Signal) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+
+	go func() {
+		defer signal.Stop(ch)
+
+		select {
+		case <-ch:
+			drain()
+		case <-ctx.Done():
+		}
+	}()
+}
@@ -0,0 +1,74 @@
+package worker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"distributed_build/pkg/api"
+)
+
+func TestDrainState(t *testing.T) {
+	d := newDrainState()
+
+	req := &api.HeartbeatRequest{FreeSlots: 2}
+	d.prepare(req)
+	require.Equal(t, &api.HeartbeatRequest{FreeSlots: 2}, req)
+	require.False(t, d.done(&api.HeartbeatResponse{Drained: true}, time.Now()), "worker is not draining")
+
+	require.True(t, d.start(time.Minute))
+	require.False(t, d.start(time.Hour), "second drain must not move deadline")
+	<-d.Started()
+
+	d.prepare(req)
+	require.Equal(t, &api.HeartbeatRequest{Draining: true}, req)
+
+	now := time.Now()
+	require.False(t, d.done(&api.HeartbeatResponse{}, now))
+	require.True(t, d.done(&api.HeartbeatResponse{Drained: true}, now))
+	require.True(t, d.done(nil, now.Add(time.Minute)), "deadline passed")
+}
+
+func TestDrainHandler(t *testing.T) {
+	drained := make(chan time.Duration, 1)
+	h := &drainHandler{logger: zaptest.NewLogger(t), drain: func(timeout time.Duration) { drained <- timeout }}
+	mux := http.NewServeMux()
+	h.Register(mux)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	rsp, err := http.Get(server.URL + "/admin/drain")
+	require.NoError(t, err)
+	rsp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, rsp.StatusCode)
+
+	rsp, err = http.Post(server.URL+"/admin/drain?timeout=1m", "", nil)
+	require.NoError(t, err)
+	rsp.Body.Close()
+	require.Equal(t, http.StatusAccepted, rsp.StatusCode)
+	require.Equal(t, time.Minute, <-drained)
+
+	rsp, err = http.Post(server.URL+"/admin/drain?timeout=soon", "", nil)
+	require.NoError(t, err)
+	rsp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+}
+
+func TestDrainOnSignal(t *testing.T) {
+	drained := make(chan struct{})
+	DrainOnSignal(context.Background(), func() { close(drained) }, syscall.SIGUSR1)
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	select {
+	case <-drained:
+	case <-time.After(time.Second):
+		t.Fatal("signal must start drain")
+	}
+}
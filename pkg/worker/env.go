@@ -0,0 +1,129 @@
+package worker
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"distributed_build/pkg/build"
+)
+
+// DefaultToolchain - директории, из которых по умолчанию собирается PATH команд джобов.
+var DefaultToolchain = []string{"/usr/local/bin", "/usr/bin", "/bin"}
+
+// EnvConfig задаёт окружение, в котором воркер выполняет команды джобов.
+//
+// Воркер не передаёт командам своё окружение: результат джоба кешируется по его ID и не должен зависеть
+// от того, на каком воркере и под каким пользователем он выполнялся.
+type EnvConfig struct {
+	// Toolchain перечисляет директории с компиляторами и утилитами в порядке поиска. Из них собирается PATH.
+	// По умолчанию DefaultToolchain.
+	Toolchain []string
+
+	// ScratchDir - директория, в которой воркер создаёт временные директории джобов. По умолчанию os.TempDir().
+	ScratchDir string
+}
+
+// toolchain возвращает директории тулчейна с учётом DefaultToolchain.
+func (c *EnvConfig) toolchain() []string {
+	if len(c.Toolchain) == 0 {
+		return DefaultToolchain
+	}
+	return c.Toolchain
+}
+
+func (c *EnvConfig) path() string {
+	return strings.Join(c.toolchain(), string(os.PathListSeparator))
+}
+
+// scratch создаёт временную директорию запуска джоба с поддиректориями home и tmp. Удаляет её вызывающий.
+func (c *EnvConfig) scratch(jobID build.ID) (string, error) {
+	root := c.ScratchDir
+	if root == "" {
+		root = os.TempDir()
+	}
+
+	dir, err := os.MkdirTemp(root, "job-"+jobID.String()+"-")
+	if err != nil {
+		return "", infra(err)
+	}
+	for _, sub := range []string{"home", "tmp"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0755); err != nil {
+			_ = os.RemoveAll(dir)
+			return "", infra(err)
+		}
+	}
+	return dir, nil
+}
+
+// environ строит окружение отрендеренной команды cmd. Сначала идут PATH из тулчейна и HOME и TMPDIR
+// во временной директории джоба scratch, затем переменные окружения воркера host, перечисленные
+// в cmd.PassEnv, затем cmd.Environ. Более поздние значения переопределяют более ранние.
+func (c *EnvConfig) environ(cmd *build.Cmd, scratch string, host []string) []string {
+	env := []string{
+		"PATH=" + c.path(),
+		"HOME=" + filepath.Join(scratch, "home"),
+		"TMPDIR=" + filepath.Join(scratch, "tmp"),
+	}
+
+	for _, kv := range host {
+		name, _, _ := strings.Cut(kv, "=")
+		if slices.Contains(cmd.PassEnv, name) {
+			env = append(env, kv)
+		}
+	}
+	env = append(env, cmd.Environ...)
+
+	return dedupEnv(env)
+}
+
+// dedupEnv оставляет для каждой переменной последнее значение на месте её первого появления.
+func dedupEnv(env []string) []string {
+	index := make(map[string]int, len(env))
+	out := make([]string, 0, len(env))
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		if i, ok := index[name]; ok {
+			out[i] = kv
+			continue
+		}
+		index[name] = len(out)
+		out = append(out, kv)
+	}
+	return out
+}
+
+// lookPath ищет исполняемый файл file в директориях PATH команды, а не воркера.
+func lookPath(file string, env []string) (string, error) {
+	if strings.Contains(file, "/") {
+		return file, nil
+	}
+
+	var path string
+	for _, kv := range env {
+		if value, ok := strings.CutPrefix(kv, "PATH="); ok {
+			path = value
+		}
+	}
+
+	for _, dir := range filepath.SplitList(path) {
+		candidate := filepath.Join(dir, file)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+			return candidate, nil
+		}
+	}
+	return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+}
+
+// warnHostEnv предупреждает о переменных в окружении джоба, которые похожи на специфичные для машины клиента.
+func warnHostEnv(l *zap.Logger, job *build.Job) {
+	if names := build.HostSpecificJobEnv(job); len(names) != 0 {
+		l.Warn("job environment contains host-specific variables",
+			zap.String("job_id", job.ID.String()),
+			zap.Strings("vars", names))
+	}
+}
@@ -0,0 +1,64 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+)
+
+func TestEnviron(t *testing.T) {
+	config := &EnvConfig{Toolchain: []string{"/opt/go/bin", "/usr/bin"}}
+	cmd := &build.Cmd{
+		Environ: []string{"GOOS=linux", "HOME=/w/home"},
+		PassEnv: []string{"HTTP_PROXY", "MISSING"},
+	}
+	host := []string{"USER=alice", "HTTP_PROXY=http://proxy:3128", "PATH=/home/alice/bin"}
+
+	env := config.environ(cmd, "/tmp/job", host)
+	require.Equal(t, []string{
+		"PATH=/opt/go/bin:/usr/bin",
+		"HOME=/w/home",
+		"TMPDIR=/tmp/job/tmp",
+		"HTTP_PROXY=http://proxy:3128",
+		"GOOS=linux",
+	}, env)
+
+	env = (&EnvConfig{}).environ(&build.Cmd{}, "/tmp/job", host)
+	require.Equal(t, []string{"PATH=/usr/local/bin:/usr/bin:/bin", "HOME=/tmp/job/home", "TMPDIR=/tmp/job/tmp"}, env)
+}
+
+func TestScratch(t *testing.T) {
+	config := &EnvConfig{ScratchDir: t.TempDir()}
+
+	dir, err := config.scratch(build.ID{'a'})
+	require.NoError(t, err)
+	require.Equal(t, config.ScratchDir, filepath.Dir(dir))
+	require.DirExists(t, filepath.Join(dir, "home"))
+	require.DirExists(t, filepath.Join(dir, "tmp"))
+
+	_, err = (&EnvConfig{ScratchDir: filepath.Join(config.ScratchDir, "missing")}).scratch(build.ID{'a'})
+	require.Equal(t, api.FailureInfra, classify(err))
+}
+
+func TestLookPath(t *testing.T) {
+	dir := t.TempDir()
+	tool := filepath.Join(dir, "tool")
+	require.NoError(t, os.WriteFile(tool, []byte("#!/bin/sh\n"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "data"), nil, 0644))
+
+	path, err := lookPath("tool", []string{"PATH=/nonexistent:" + dir})
+	require.NoError(t, err)
+	require.Equal(t, tool, path)
+
+	_, err = lookPath("data", []string{"PATH=" + dir})
+	require.Error(t, err)
+
+	path, err = lookPath("./tool", nil)
+	require.NoError(t, err)
+	require.Equal(t, "./tool", path)
+}
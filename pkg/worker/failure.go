@@ -0,0 +1,56 @@
+package worker
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+)
+
+// infraError помечает ошибки воркера, а не джоба: не скачались исходники или артефакты,
+// не удалось подготовить директории джоба.
+type infraError struct {
+	err error
+}
+
+func (e *infraError) Error() string { return e.err.Error() }
+func (e *infraError) Unwrap() error { return e.err }
+
+// infra оборачивает ошибку воркера, чтобы failedResult отнёс её к api.FailureInfra.
+func infra(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &infraError{err: err}
+}
+
+// classify определяет, кто виноват в ошибке err: джоб или воркер.
+//
+// Ненулевой код выхода команды - ошибка джоба. Ошибки, помеченные infra, и нехватка места
+// на диске - ошибки воркера. Остальные ошибки, например в шаблонах команд, считаются ошибками джоба.
+func classify(err error) api.FailureKind {
+	var exitErr *exec.ExitError
+	var infraErr *infraError
+	switch {
+	case errors.As(err, &exitErr):
+		return api.FailureJob
+	case errors.As(err, &infraErr), errors.Is(err, syscall.ENOSPC), errors.Is(err, syscall.EDQUOT):
+		return api.FailureInfra
+	default:
+		return api.FailureJob
+	}
+}
+
+// failedResult возвращает результат джоба id, который завершился ошибкой err.
+func failedResult(id build.ID, err error) *api.JobResult {
+	msg := err.Error()
+	res := &api.JobResult{ID: id, Error: &msg, Failure: classify(err)}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		res.ExitCode = exitErr.ExitCode()
+	}
+	return res
+}
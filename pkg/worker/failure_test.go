@@ -0,0 +1,34 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+)
+
+func TestFailedResult(t *testing.T) {
+	id := build.ID{'a'}
+
+	err := exec.Command("sh", "-c", "exit 3").Run()
+	res := failedResult(id, fmt.Errorf("cmd 0: %w", err))
+	require.Equal(t, api.FailureJob, res.Failure)
+	require.Equal(t, 3, res.ExitCode)
+
+	res = failedResult(id, infra(fmt.Errorf("download artifact: %w", errors.New("connection refused"))))
+	require.Equal(t, api.FailureInfra, res.Failure)
+	require.Equal(t, "download artifact: connection refused", *res.Error)
+
+	res = failedResult(id, &os.PathError{Op: "write", Path: "out", Err: syscall.ENOSPC})
+	require.Equal(t, api.FailureInfra, res.Failure)
+
+	res = failedResult(id, errors.New("template: unknown variable"))
+	require.Equal(t, api.FailureJob, res.Failure)
+}
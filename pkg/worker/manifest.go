@@ -0,0 +1,88 @@
+package worker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+)
+
+// errSpecialFile означает, что джоб оставил в выходной директории файл, который нельзя сохранить
+// в артефакте: FIFO, сокет или устройство. Это ошибка джоба.
+var errSpecialFile = errors.New("unsupported file type in job outputs")
+
+// checkOutputs строит манифест выходной директории джоба dir и проверяет его по job.Outputs и job.StrictOutputs.
+//
+// Манифест возвращается и вместе с ошибкой проверки: он попадает в api.JobResult.Outputs, чтобы было видно,
+// что джоб создал вместо объявленного. Ошибка проверки - ошибка джоба, после неё артефакт джоба не сохраняется.
+// Файл, который нельзя сохранить в артефакте, тоже ошибка джоба, остальные ошибки чтения директории - ошибки воркера.
+func checkOutputs(job *build.Job, dir string) ([]api.OutputFile, error) {
+	files, err := manifest(dir)
+	if errors.Is(err, errSpecialFile) {
+		return nil, err
+	} else if err != nil {
+		return nil, infra(err)
+	}
+
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	return files, job.CheckOutputs(paths)
+}
+
+// manifest описывает файлы в директории dir в лексикографическом порядке путей. Директории в манифест
+// не попадают. Кроме директорий допускаются только обычные файлы и символические ссылки, на остальных
+// manifest возвращает errSpecialFile, не открывая их: чтение FIFO заблокировалось бы навсегда.
+func manifest(dir string) ([]api.OutputFile, error) {
+	var files []api.OutputFile
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		f := api.OutputFile{Path: filepath.ToSlash(rel)}
+
+		switch {
+		case d.Type()&fs.ModeSymlink != 0:
+			f.Symlink, err = os.Readlink(path)
+		case d.Type().IsRegular():
+			f.Size, f.Digest, err = digest(path)
+		default:
+			err = fmt.Errorf("%w: %s is %v", errSpecialFile, f.Path, d.Type())
+		}
+		if err != nil {
+			return err
+		}
+
+		files = append(files, f)
+		return nil
+	})
+	return files, err
+}
+
+func digest(path string) (int64, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, file)
+	if err != nil {
+		return 0, "", err
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
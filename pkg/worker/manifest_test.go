@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+)
+
+func TestCheckOutputs(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "gen"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lib.a"), []byte("foo"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "gen", "a.go"), nil, 0644))
+	require.NoError(t, os.Symlink("lib.a", filepath.Join(dir, "latest.a")))
+
+	job := &build.Job{Outputs: []string{"lib.a", "gen/*.go"}}
+	files, err := checkOutputs(job, dir)
+	require.NoError(t, err)
+	require.Equal(t, []api.OutputFile{
+		{Path: "gen/a.go", Digest: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{Path: "latest.a", Symlink: "lib.a"},
+		{Path: "lib.a", Size: 3, Digest: "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"},
+	}, files)
+
+	job.StrictOutputs = true
+	files, err = checkOutputs(job, dir)
+	require.Len(t, files, 3)
+	require.Equal(t, &build.OutputError{Undeclared: []string{"latest.a"}}, err)
+	require.Equal(t, api.FailureJob, classify(err))
+
+	_, err = checkOutputs(job, filepath.Join(dir, "missing"))
+	require.Equal(t, api.FailureInfra, classify(err))
+}
+
+func TestCheckOutputsSpecialFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lib.a"), []byte("foo"), 0644))
+	// Чтение FIFO без писателя заблокировалось бы навсегда.
+	require.NoError(t, syscall.Mkfifo(filepath.Join(dir, "pipe"), 0644))
+
+	_, err := checkOutputs(&build.Job{}, dir)
+	require.ErrorIs(t, err, errSpecialFile)
+	require.ErrorContains(t, err, "pipe")
+	require.Equal(t, api.FailureJob, classify(err))
+}
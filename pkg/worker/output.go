@@ -0,0 +1,256 @@
+package worker
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/artifact"
+	"distributed_build/pkg/build"
+)
+
+// DefaultOutputLimit - сколько байт stdout и сколько байт stderr джоба по умолчанию попадает в api.JobResult.
+const DefaultOutputLimit = 1 << 20
+
+// MaxLogSize - сколько байт stdout и сколько байт stderr джоба попадает в лог-артефакт. Остальной вывод
+// отбрасывается, а api.OutputLog сообщает полный размер потока.
+const MaxLogSize = 256 << 20
+
+// LogRetention - сколько воркер хранит лог-артефакты. Более старые логи удаляются перед записью нового.
+const LogRetention = 24 * time.Hour
+
+// jobOutput собирает stdout и stderr бегущего джоба.
+//
+// Если limit > 0, в памяти хранится не больше limit байт каждого потока: первая половина лимита уходит
+// на начало вывода, вторая - на конец. Полный вывод пишется в лог-артефакт, если его включил spill.
+//
+// Новые куски вывода забираются методом flush и уходят координатору в ближайшем heartbeat-е. Лимит
+// на поток не действует: между двумя вызовами flush копится не больше limit байт каждого потока, а вывод
+// сверх этого отбрасывается с пометкой о пропуске. После завершения джоба fill кладёт в api.JobResult
+// весь вывод или, если он не поместился в limit, начало и конец с пометкой об обрезке.
+type jobOutput struct {
+	id       build.ID
+	limit    int
+	logLimit int64
+
+	mu     sync.Mutex
+	stdout stream
+	stderr stream
+
+	logDir      string
+	commit      func() error
+	abort       func() error
+	logFailed   error
+	logArtifact build.ID
+}
+
+// stream - один поток вывода джоба.
+type stream struct {
+	head []byte
+	tail []byte
+	size int64
+	file *os.File
+
+	// pending - вывод, который ещё не забрал flush, skipped - сколько байт после него не поместилось в limit.
+	pending []byte
+	skipped int64
+}
+
+func newJobOutput(id build.ID, limit int) *jobOutput {
+	return &jobOutput{id: id, limit: limit, logLimit: MaxLogSize}
+}
+
+// headLimit и tailLimit - сколько байт начала и конца потока хранится в памяти. Ноль означает отсутствие лимита.
+func (o *jobOutput) headLimit() int { return o.limit / 2 }
+func (o *jobOutput) tailLimit() int { return o.limit - o.headLimit() }
+
+// spill включает запись полного вывода, но не больше MaxLogSize байт каждого потока, в лог-артефакт
+// api.LogArtifactID в кеше артефактов воркера. Лог от прошлого запуска джоба на этом воркере и логи старше
+// LogRetention удаляются. Вызывается до запуска команд джоба.
+func (o *jobOutput) spill(cache *artifact.Cache) error {
+	if err := cache.ExpireLogs(time.Now().Add(-LogRetention)); err != nil {
+		return fmt.Errorf("unable to remove expired job logs: %w", err)
+	}
+
+	id := api.LogArtifactID(o.id)
+	if err := cache.Remove(id); err != nil {
+		return fmt.Errorf("unable to remove previous job log: %w", err)
+	}
+
+	dir, commit, abort, err := cache.CreateLog(id)
+	if err != nil {
+		return fmt.Errorf("unable to create job log: %w", err)
+	}
+
+	stdout, err := os.Create(filepath.Join(dir, api.LogStdout))
+	if err != nil {
+		_ = abort()
+		return err
+	}
+	stderr, err := os.Create(filepath.Join(dir, api.LogStderr))
+	if err != nil {
+		_ = stdout.Close()
+		_ = abort()
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.stdout.file, o.stderr.file = stdout, stderr
+	o.logDir, o.commit, o.abort, o.logArtifact = dir, commit, abort, id
+	return nil
+}
+
+type outputWriter struct {
+	o *jobOutput
+	s *stream
+}
+
+// Write никогда не возвращает ошибку: если не удалось записать лог-артефакт, джоб продолжает выполняться,
+// а в api.JobResult не будет ссылки на лог.
+func (w outputWriter) Write(p []byte) (int, error) {
+	w.o.mu.Lock()
+	defer w.o.mu.Unlock()
+
+	w.o.write(w.s, p)
+	return len(p), nil
+}
+
+// write дописывает p в поток s. Вызывается под o.mu.
+func (o *jobOutput) write(s *stream, p []byte) {
+	if s.file != nil && o.logFailed == nil && s.size < o.logLimit {
+		n := min(int64(len(p)), o.logLimit-s.size)
+		if _, err := s.file.Write(p[:n]); err != nil {
+			o.logFailed = err
+		}
+	}
+	s.size += int64(len(p))
+
+	if o.limit <= 0 {
+		s.pending = append(s.pending, p...)
+		s.head = append(s.head, p...)
+		return
+	}
+
+	n := min(max(o.limit-len(s.pending), 0), len(p))
+	if s.skipped == 0 {
+		s.pending = append(s.pending, p[:n]...)
+		s.skipped += int64(len(p) - n)
+	} else {
+		// После пропуска новый вывод в этот кусок не попадает, чтобы пропуск был одним.
+		s.skipped += int64(len(p))
+	}
+
+	if free := o.headLimit() - len(s.head); free > 0 {
+		n := min(free, len(p))
+		s.head = append(s.head, p[:n]...)
+		p = p[n:]
+	}
+
+	// Конец потока копится до двух лимитов, чтобы не сдвигать буфер на каждой записи.
+	limit := o.tailLimit()
+	switch {
+	case len(p) >= limit:
+		s.tail = append(s.tail[:0], p[len(p)-limit:]...)
+	case len(s.tail)+len(p) > 2*limit:
+		s.tail = append(s.tail[:0], s.tail[len(s.tail)+len(p)-limit:]...)
+		s.tail = append(s.tail, p...)
+	default:
+		s.tail = append(s.tail, p...)
+	}
+}
+
+func (o *jobOutput) Stdout() outputWriter {
+	return outputWriter{o: o, s: &o.stdout}
+}
+
+func (o *jobOutput) Stderr() outputWriter {
+	return outputWriter{o: o, s: &o.stderr}
+}
+
+// flush возвращает вывод, появившийся с прошлого вызова, или nil, если нового вывода нет.
+func (o *jobOutput) flush() *api.JobOutput {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.stdout.pending) == 0 && len(o.stderr.pending) == 0 {
+		return nil
+	}
+
+	return &api.JobOutput{
+		ID:     o.id,
+		Stdout: unsent(&o.stdout),
+		Stderr: unsent(&o.stderr),
+	}
+}
+
+// unsent забирает из потока вывод для flush. Вызывается под o.mu.
+func unsent(s *stream) []byte {
+	if len(s.pending) == 0 {
+		return nil
+	}
+
+	out := s.pending
+	if s.skipped > 0 {
+		out = append(out, fmt.Sprintf("\n... [%d bytes skipped] ...\n", s.skipped)...)
+	}
+	s.pending, s.skipped = nil, 0
+	return out
+}
+
+// truncated возвращает true, если поток не поместился в лимит. Вызывается под o.mu.
+func (o *jobOutput) truncated(s *stream) bool {
+	return o.limit > 0 && s.size > int64(o.limit)
+}
+
+// result возвращает вывод потока для api.JobResult. Вызывается под o.mu.
+func (o *jobOutput) result(s *stream) []byte {
+	if !o.truncated(s) {
+		return append(bytes.Clone(s.head), s.tail...)
+	}
+
+	tail := s.tail[len(s.tail)-o.tailLimit():]
+	marker := fmt.Sprintf("\n... [%d bytes truncated] ...\n", s.size-int64(len(s.head)+len(tail)))
+
+	out := make([]byte, 0, len(s.head)+len(marker)+len(tail))
+	out = append(out, s.head...)
+	out = append(out, marker...)
+	return append(out, tail...)
+}
+
+// fill записывает вывод джоба в результат. Если вывод обрезан и включён spill, сохраняет лог-артефакт
+// и ссылку на него в res.Log, иначе удаляет лог-артефакт.
+//
+// Ошибка означает только, что лог-артефакт не сохранился: вывод в res записан в любом случае.
+func (o *jobOutput) fill(res *api.JobResult, workerID api.WorkerID) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	res.Stdout = o.result(&o.stdout)
+	res.Stderr = o.result(&o.stderr)
+	if o.logDir == "" {
+		return nil
+	}
+
+	err := errors.Join(o.logFailed, o.stdout.file.Close(), o.stderr.file.Close())
+	if err != nil || (!o.truncated(&o.stdout) && !o.truncated(&o.stderr)) {
+		return errors.Join(err, o.abort())
+	}
+	if err := o.commit(); err != nil {
+		return err
+	}
+
+	res.Log = &api.OutputLog{
+		Worker:     workerID,
+		ArtifactID: o.logArtifact,
+		StdoutSize: o.stdout.size,
+		StderrSize: o.stderr.size,
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/artifact"
+	"distributed_build/pkg/build"
+)
+
+func TestJobOutput(t *testing.T) {
+	id := build.ID{'a'}
+	o := newJobOutput(id, 0)
+
+	require.Nil(t, o.flush())
+
+	_, _ = fmt.Fprint(o.Stdout(), "foo")
+	_, _ = fmt.Fprint(o.Stderr(), "bar")
+	require.Equal(t, &api.JobOutput{ID: id, Stdout: []byte("foo"), Stderr: []byte("bar")}, o.flush())
+	require.Nil(t, o.flush())
+
+	_, _ = fmt.Fprint(o.Stdout(), "baz")
+	require.Equal(t, &api.JobOutput{ID: id, Stdout: []byte("baz")}, o.flush())
+
+	var res api.JobResult
+	require.NoError(t, o.fill(&res, "worker0"))
+	require.Equal(t, []byte("foobaz"), res.Stdout)
+	require.Equal(t, []byte("bar"), res.Stderr)
+	require.Nil(t, res.Log)
+}
+
+func TestJobOutputTruncate(t *testing.T) {
+	id := build.ID{'a'}
+	o := newJobOutput(id, 8)
+
+	_, _ = fmt.Fprint(o.Stdout(), "0123")
+	_, _ = fmt.Fprint(o.Stdout(), "45")
+	require.Equal(t, &api.JobOutput{ID: id, Stdout: []byte("012345")}, o.flush())
+
+	for i := 0; i < 100; i++ {
+		_, _ = fmt.Fprint(o.Stdout(), "x")
+	}
+	_, _ = fmt.Fprint(o.Stdout(), "abcd")
+	_, _ = fmt.Fprint(o.Stderr(), "short")
+	require.Equal(t, &api.JobOutput{ID: id, Stdout: []byte("xxxxxxxx\n... [96 bytes skipped] ...\n"), Stderr: []byte("short")}, o.flush())
+
+	var res api.JobResult
+	require.NoError(t, o.fill(&res, "worker0"))
+	require.Equal(t, "0123\n... [102 bytes truncated] ...\nabcd", string(res.Stdout))
+	require.Equal(t, "short", string(res.Stderr))
+	require.Nil(t, res.Log)
+}
+
+func TestJobOutputStreamsPastLimit(t *testing.T) {
+	id := build.ID{'a'}
+	o := newJobOutput(id, 8)
+
+	var streamed []byte
+	for i := 0; i < 10; i++ {
+		line := fmt.Sprintf("line%d\n", i)
+		_, _ = fmt.Fprint(o.Stdout(), line)
+
+		chunk := o.flush()
+		require.NotNil(t, chunk, "output past the limit must keep streaming")
+		streamed = append(streamed, chunk.Stdout...)
+	}
+	require.Equal(t, "line0\nline1\nline2\nline3\nline4\nline5\nline6\nline7\nline8\nline9\n", string(streamed))
+
+	var res api.JobResult
+	require.NoError(t, o.fill(&res, "worker0"))
+	require.Equal(t, "line\n... [52 bytes truncated] ...\nne9\n", string(res.Stdout))
+}
+
+func TestJobOutputLog(t *testing.T) {
+	cache, err := artifact.NewCache(t.TempDir())
+	require.NoError(t, err)
+
+	id := build.ID{'a'}
+	o := newJobOutput(id, 4)
+	require.NoError(t, o.spill(cache))
+
+	_, _ = fmt.Fprint(o.Stdout(), strings.Repeat("x", 1000))
+	_, _ = fmt.Fprint(o.Stderr(), "err")
+
+	var res api.JobResult
+	require.NoError(t, o.fill(&res, "worker0"))
+	require.Equal(t, &api.OutputLog{Worker: "worker0", ArtifactID: api.LogArtifactID(id), StdoutSize: 1000, StderrSize: 3}, res.Log)
+
+	dir, unlock, err := cache.Get(res.Log.ArtifactID)
+	require.NoError(t, err)
+	stdout, err := os.ReadFile(filepath.Join(dir, api.LogStdout))
+	require.NoError(t, err)
+	require.Len(t, stdout, 1000)
+	unlock()
+
+	// Повторный запуск с коротким выводом удаляет старый лог.
+	o = newJobOutput(id, 4)
+	require.NoError(t, o.spill(cache))
+	_, _ = fmt.Fprint(o.Stdout(), "OK")
+
+	res = api.JobResult{}
+	require.NoError(t, o.fill(&res, "worker0"))
+	require.Equal(t, []byte("OK"), res.Stdout)
+	require.Nil(t, res.Log)
+
+	_, _, err = cache.Get(api.LogArtifactID(id))
+	require.ErrorIs(t, err, artifact.ErrNotFound)
+}
+
+func TestJobOutputLogLimit(t *testing.T) {
+	cache, err := artifact.NewCache(t.TempDir())
+	require.NoError(t, err)
+
+	id := build.ID{'a'}
+	o := newJobOutput(id, 4)
+	o.logLimit = 10
+	require.NoError(t, o.spill(cache))
+
+	_, _ = fmt.Fprint(o.Stdout(), strings.Repeat("x", 8))
+	_, _ = fmt.Fprint(o.Stdout(), strings.Repeat("y", 8))
+
+	var res api.JobResult
+	require.NoError(t, o.fill(&res, "worker0"))
+	require.Equal(t, int64(16), res.Log.StdoutSize)
+
+	dir, unlock, err := cache.Get(res.Log.ArtifactID)
+	require.NoError(t, err)
+	stdout, err := os.ReadFile(filepath.Join(dir, api.LogStdout))
+	require.NoError(t, err)
+	require.Equal(t, "xxxxxxxxyy", string(stdout))
+	unlock()
+
+	// Лог - не артефакт: воркер не объявляет его координатору.
+	require.NoError(t, cache.Range(func(id build.ID) error {
+		t.Errorf("unexpected artifact %v", id)
+		return nil
+	}))
+}
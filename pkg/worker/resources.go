@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"go.uber.org/zap"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+	"distributed_build/pkg/cgroup"
+)
+
+// newCgroups включает лимиты ресурсов джобов, если воркеру делегирована cgroup v2. Иначе пишет в лог,
+// почему лимитов не будет, и возвращает nil: джобы выполняются как раньше.
+func newCgroups(l *zap.Logger) *cgroup.Manager {
+	m, err := cgroup.Detect()
+	if err != nil {
+		l.Warn("job resource limits are disabled", zap.Error(err))
+		return nil
+	}
+	return m
+}
+
+// jobGroup создаёт cgroup для запуска джоба job. Если cgroup недоступны, возвращает nil.
+func jobGroup(m *cgroup.Manager, job *build.Job) (*cgroup.Group, error) {
+	if m == nil {
+		return nil, nil
+	}
+
+	g, err := m.Create("job-"+job.ID.String(), job.Resources)
+	if err != nil {
+		return nil, infra(err)
+	}
+	return g, nil
+}
+
+// account дописывает в результат джоба ресурсы, которые он потратил в cgroup g, и помечает
+// джоб, убитый за превышение лимита памяти, как api.FailureOOM.
+func account(l *zap.Logger, res *api.JobResult, g *cgroup.Group) {
+	if g == nil {
+		return
+	}
+
+	usage, err := g.Usage()
+	if err != nil {
+		l.Warn("unable to read job resource usage", zap.String("job_id", res.ID.String()), zap.Error(err))
+	}
+	res.Usage = usage
+
+	if res.Error != nil && g.OOMKilled() {
+		res.Failure = api.FailureOOM
+	}
+}
@@ -0,0 +1,49 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"distributed_build/pkg/api"
+	"distributed_build/pkg/build"
+	"distributed_build/pkg/cgroup"
+)
+
+func TestAccountWithoutCgroups(t *testing.T) {
+	g, err := jobGroup(nil, &build.Job{ID: build.ID{'a'}})
+	require.NoError(t, err)
+	require.Nil(t, g)
+
+	res := &api.JobResult{ID: build.ID{'a'}}
+	account(zaptest.NewLogger(t), res, g)
+	require.Nil(t, res.Usage)
+}
+
+func TestAccountOOM(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cgroup.controllers"), []byte("cpu memory"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cgroup.procs"), nil, 0644))
+	m, err := cgroup.New(dir)
+	require.NoError(t, err)
+
+	job := &build.Job{ID: build.ID{'a'}, Resources: build.Resources{Memory: 1 << 20}}
+	g, err := jobGroup(m, job)
+	require.NoError(t, err)
+	defer g.Close()
+
+	group := g.Dir()
+	require.Equal(t, dir, filepath.Dir(group))
+	require.NoError(t, os.WriteFile(filepath.Join(group, "cpu.stat"), []byte("usage_usec 2000000\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(group, "memory.events"), []byte("oom 1\noom_kill 1\n"), 0644))
+
+	errorMessage := "signal: killed"
+	res := &api.JobResult{ID: job.ID, Error: &errorMessage, Failure: api.FailureJob}
+	account(zaptest.NewLogger(t), res, g)
+	require.Equal(t, api.FailureOOM, res.Failure)
+	require.Equal(t, 2*time.Second, res.Usage.CPUTime)
+}
@@ -0,0 +1,56 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+
+	"distributed_build/pkg/build"
+	"distributed_build/pkg/sandbox"
+)
+
+// jobSandbox возвращает песочницу для команд джоба: исходники, артефакты зависимостей и директории тулчейна
+// toolchain доступны на чтение, выходная и временная директория scratch - на запись. base задаёт общие для всех
+// джобов настройки воркера, например Network. toolchain - EnvConfig.toolchain: без него команды из PATH
+// вне системных директорий в песочнице не найдутся.
+func jobSandbox(base *sandbox.Config, jobCtx build.JobContext, scratch string, toolchain []string) *sandbox.Config {
+	c := &sandbox.Config{
+		ReadOnly: append([]string(nil), base.ReadOnly...),
+		Writable: append([]string(nil), base.Writable...),
+		Network:  base.Network,
+	}
+
+	c.ReadOnly = append(c.ReadOnly, toolchain...)
+	c.ReadOnly = append(c.ReadOnly, jobCtx.SourceDir)
+	for _, dir := range jobCtx.Deps {
+		c.ReadOnly = append(c.ReadOnly, dir)
+	}
+	c.Writable = append(c.Writable, jobCtx.OutputDir, scratch)
+	return c.WithSystemDirs()
+}
+
+// command готовит к запуску отрендеренную команду джоба с окружением env, см. EnvConfig.environ.
+// Исполняемый файл ищется в PATH из env. Если sb != nil, команда выполняется в песочнице sb.
+func command(ctx context.Context, cmd *build.Cmd, env []string, sb *sandbox.Config) (*exec.Cmd, error) {
+	if len(cmd.Exec) == 0 {
+		return nil, errors.New("empty command")
+	}
+
+	path, err := lookPath(cmd.Exec[0], env)
+	if err != nil {
+		return nil, err
+	}
+
+	c := exec.CommandContext(ctx, path, cmd.Exec[1:]...)
+	c.Args[0] = cmd.Exec[0]
+	c.Env = env
+	c.Dir = cmd.WorkingDirectory
+	if sb == nil {
+		return c, nil
+	}
+
+	if err := sb.Wrap(c); err != nil {
+		return nil, infra(err)
+	}
+	return c, nil
+}
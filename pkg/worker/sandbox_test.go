@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"distributed_build/pkg/build"
+	"distributed_build/pkg/sandbox"
+)
+
+func TestMain(m *testing.M) {
+	sandbox.Init()
+	os.Exit(m.Run())
+}
+
+func TestJobSandbox(t *testing.T) {
+	jobCtx := build.JobContext{
+		SourceDir: "/w/src",
+		OutputDir: "/w/out",
+		Deps:      map[build.ID]string{{'a'}: "/w/a"},
+	}
+
+	c := jobSandbox(&sandbox.Config{Network: true}, jobCtx, "/tmp/job", []string{"/opt/go/bin"})
+	require.True(t, c.Network)
+	require.Subset(t, c.ReadOnly, []string{"/opt/go/bin", "/w/src", "/w/a", "/usr"})
+	require.NotContains(t, c.ReadOnly, "/w/out")
+	require.Equal(t, []string{"/w/out", "/tmp/job"}, c.Writable)
+}
+
+func TestCommand(t *testing.T) {
+	cmd := &build.Cmd{Exec: []string{"echo", "foo"}, WorkingDirectory: "/w/src"}
+	env := []string{"PATH=/usr/bin:/bin", "A=b"}
+
+	c, err := command(context.Background(), cmd, env, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"echo", "foo"}, c.Args)
+	require.Contains(t, []string{"/usr/bin/echo", "/bin/echo"}, c.Path)
+	require.Equal(t, env, c.Env)
+	require.Equal(t, "/w/src", c.Dir)
+
+	_, err = command(context.Background(), cmd, []string{"PATH=" + t.TempDir()}, nil)
+	require.ErrorIs(t, err, exec.ErrNotFound)
+
+	_, err = command(context.Background(), &build.Cmd{}, env, nil)
+	require.Error(t, err)
+}
+
+// TestSandboxToolchain проверяет SetEnv вместе с SetSandbox: команда из тулчейна вне системных директорий
+// находится и запускается в песочнице.
+func TestSandboxToolchain(t *testing.T) {
+	toolchain := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(toolchain, "cc"), []byte("#!/bin/sh\necho compiled\n"), 0755))
+
+	env := &EnvConfig{Toolchain: []string{toolchain, "/usr/bin", "/bin"}, ScratchDir: t.TempDir()}
+	scratch, err := env.scratch(build.ID{'a'})
+	require.NoError(t, err)
+
+	jobCtx := build.JobContext{SourceDir: t.TempDir(), OutputDir: t.TempDir()}
+	sb := jobSandbox(&sandbox.Config{}, jobCtx, scratch, env.toolchain())
+
+	cmd := &build.Cmd{Exec: []string{"cc"}, WorkingDirectory: jobCtx.SourceDir}
+	probe, err := command(context.Background(), &build.Cmd{Exec: []string{"true"}}, env.environ(cmd, scratch, nil), sb)
+	require.NoError(t, err)
+	if out, err := probe.CombinedOutput(); err != nil {
+		t.Skipf("namespaces are not available: %v %s", err, out)
+	}
+
+	c, err := command(context.Background(), cmd, env.environ(cmd, scratch, nil), sb)
+	require.NoError(t, err)
+
+	var stdout bytes.Buffer
+	c.Stdout, c.Stderr = &stdout, os.Stderr
+	require.NoError(t, c.Run())
+	require.Equal(t, "compiled\n", stdout.String())
+}
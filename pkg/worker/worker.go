@@ -5,12 +5,15 @@ package worker
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"go.uber.org/zap"
 
 	"distributed_build/pkg/api"
 	"distributed_build/pkg/artifact"
 	"distributed_build/pkg/filecache"
+	"distributed_build/pkg/mtls"
+	"distributed_build/pkg/sandbox"
 )
 
 type Worker struct {
@@ -30,6 +33,46 @@ func (w *Worker) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 	panic("implement me")
 }
 
+// Run ходит с heartbeat-ами к координатору и выполняет джобы, пока не отменят ctx. Если воркер выводится
+// из кластера через Drain, Run возвращает nil после последнего heartbeat-а с Leaving.
 func (w *Worker) Run(ctx context.Context) error {
 	panic("implement me")
 }
+
+// Drain выводит воркера из кластера: воркер сообщает координатору Draining, перестаёт брать новые джобы,
+// доделывает бегущие и раздаёт артефакты, пока координатор не ответит Drained или не пройдёт timeout.
+// После этого воркер присылает heartbeat с Leaving, и Run завершается.
+//
+// Для POST /admin/drain, доступного роли auth.RoleAdmin, реализация ServeHTTP должна зарегистрировать
+// drainHandler с Drain в качестве drain.
+func (w *Worker) Drain(timeout time.Duration) {
+	panic("implement me")
+}
+
+// SetSandbox включает песочницу: команды джобов выполняются в Linux namespace-ах с доступом только
+// к исходникам и артефактам зависимостей на чтение и к выходной директории на запись, см. пакет sandbox.
+// cfg задаёт общие настройки, например Network. Вызывается до Run.
+//
+// Бинарник воркера с песочницей должен вызывать sandbox.Init в начале main.
+func (w *Worker) SetSandbox(cfg *sandbox.Config) {
+	panic("implement me")
+}
+
+// SetEnv задаёт окружение команд джобов: директории тулчейна для PATH и директорию для временных
+// директорий джобов. Без SetEnv используются DefaultToolchain и os.TempDir(). Вызывается до Run.
+func (w *Worker) SetEnv(cfg *EnvConfig) {
+	panic("implement me")
+}
+
+// SetOutputLimit задаёт, сколько байт stdout и сколько байт stderr каждого джоба попадает в JobResult.
+// Полный вывод джоба, не поместившегося в лимит, сохраняется в лог-артефакт. Без SetOutputLimit используется
+// DefaultOutputLimit, ноль отключает лимит. Вызывается до Run.
+func (w *Worker) SetOutputLimit(limit int) {
+	panic("implement me")
+}
+
+// SetTLS включает mTLS: воркер ходит к координатору и другим воркерам с сертификатом из cfg.
+// Сертификат должен быть выпущен на WorkerID воркера. Вызывается до Run.
+func (w *Worker) SetTLS(cfg *mtls.Config) {
+	panic("implement me")
+}